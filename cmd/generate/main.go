@@ -4,8 +4,11 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 
+	"go-fake/internal/dialect"
 	"go-fake/internal/generator"
 	"go-fake/internal/parser"
 	"go-fake/internal/schema"
@@ -17,7 +20,8 @@ const version = "v1.3.0"
 func main() {
 	schemaFile := flag.String("schema", "", "Path to the schema file (JSON or SQL)")
 	outputFile := flag.String("output", "output.csv", "Output directory for multi-table schemas or file path for single-table schemas")
-	numRows := flag.Int("rows", 100, "Number of rows to generate")
+	numRows := &rowsFlag{total: 100}
+	flag.Var(numRows, "rows", "Number of rows to generate, optionally per table (e.g. 1000 or 500,departments=10,employees=2000)")
 	showVersion := flag.Bool("version", false, "Show version information")
 	enableAI := flag.Bool("ai", false, "Enable OpenAI-powered field inference (requires OPENAI_API_KEY)")
 	outputFormat := flag.String("format", "", "Override output format (json or csv). If not specified, format is auto-detected from schema type")
@@ -25,6 +29,12 @@ func main() {
 	enablePerf := flag.Bool("perf", false, "Enable performance optimizations (parallel generation, caching)")
 	workers := flag.Int("workers", 0, "Number of parallel workers (0 = auto-detect CPU cores)")
 	batchSize := flag.Int("batch", 1000, "Batch size for row generation (higher = more memory, faster generation)")
+	seed := flag.Int64("seed", 0, "Seed for reproducible output (0 = random seed)")
+	nullRatio := flag.Float64("null-ratio", 0, "Share of null values in columns that are not required, overrides the schema null_ratio")
+	nullToken := flag.String("null-token", "", "Text written to CSV files for null values (e.g. NULL or \\N)")
+	defaults := &defaultsFlag{}
+	flag.Var(defaults, "defaults", "Column DEFAULT policy: always, ignore or the share of rows given the default (e.g. 0.3), overrides the schema default_ratio")
+	sqlDialect := flag.String("dialect", dialect.Auto, "SQL dialect of SQL schemas: auto, postgres, mysql, sqlite or sqlserver")
 	
 	flag.Usage = func() {
 		fmt.Fprintf(flag.CommandLine.Output(), "go-fake v%s - AI-Enhanced Fake Data Generator\n\n", version)
@@ -45,6 +55,19 @@ func main() {
 		fmt.Fprintf(flag.CommandLine.Output(), "  Use -perf flag to enable parallel generation and caching optimizations\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  -workers N: Set number of parallel workers (0 = auto-detect CPU cores)\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  -batch N: Set batch size for row generation (higher = faster, more memory)\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Reproducibility:\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  -seed N: Produce identical output for the same seed, schema and row count\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  Seeded runs generate dates relative to 2025-01-01 instead of the current time\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Null Values:\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  -null-ratio R: Make a share R (0-1) of the values in nullable columns null\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  -null-token T: Write nulls to CSV files as T (default: empty cell)\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Column Defaults:\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  -defaults always: Give every row the DEFAULT of its columns (default)\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  -defaults P: Give a share P (0-1) of the rows the default, generate the others\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  -defaults ignore: Generate every value as if the columns had no default\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "SQL Dialects:\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  -dialect D: Map the column types of SQL schemas as postgres, mysql, sqlite or sqlserver does\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  The default, auto, detects the dialect from the file\n\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "Supported field types:\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  Basic: string, int, float, bool, date, datetime\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  Identity: email, name, firstname, lastname, username, uuid\n")
@@ -55,6 +78,7 @@ func main() {
 		fmt.Fprintf(flag.CommandLine.Output(), "  Content: text, hashtag, color, product, brand, skill\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  Measurements: age, height, weight, temperature, longitude, latitude\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  System: status, priority, duration, gender\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  Database: time, interval, json, point, binary, arrays such as int[] or string[]\n")
 	}
 	flag.Parse()
 
@@ -100,12 +124,31 @@ func main() {
 		}
 	} else {
 		logger.Debug("Detected SQL schema format")
-		schemaData, err = parser.ParseSQLSchema(*schemaFile)
+		d, err := dialect.Parse(*sqlDialect)
+		if err != nil {
+			logger.Fatal("Invalid -dialect value: %v", err)
+		}
+		schemaData, err = parser.ParseSQLSchemaWithDialect(*schemaFile, d)
 		if err != nil {
 			logger.Fatal("Error parsing SQL schema: %v", err)
 		}
 	}
 
+	if flagWasSet("null-ratio") {
+		schemaData.NullRatio = nullRatio
+	}
+	if defaults.ratio != nil {
+		schemaData.DefaultRatio = defaults.ratio
+	}
+
+	if err := schema.ValidateSchema(schemaData); err != nil {
+		logger.Fatal("Invalid schema: %v", err)
+	}
+
+	if err := numRows.apply(&schemaData); err != nil {
+		logger.Fatal("Invalid -rows value: %v", err)
+	}
+
 	logger.Info("Schema parsed successfully: %d table(s) found", len(schemaData.Tables))
 	for _, table := range schemaData.Tables {
 		logger.Debug("Table '%s': %d fields", table.Name, len(table.Fields))
@@ -139,6 +182,8 @@ func main() {
 			CacheFieldInference: false,
 		}
 	}
+	performanceConfig.Seed = *seed
+	performanceConfig.NullToken = *nullToken
 	
 	if *enableAI {
 		aiStatus := getAIStatus()
@@ -148,22 +193,22 @@ func main() {
 		}
 		
 		logger.Time("AI-enhanced data generation", func() {
-			generatedFiles, err = generator.GenerateWithAIAndFormat(&schemaData, *numRows, *outputFile, *outputFormat)
+			generatedFiles, err = generator.GenerateWithAIAndConfig(&schemaData, numRows.total, *outputFile, *outputFormat, performanceConfig)
 		})
 	} else {
 		logger.Debug("Using standard field inference")
 		if *enablePerf {
 			logger.Time("Optimized data generation", func() {
-				generatedFiles, err = generator.GenerateDataFilesOptimized(schemaData, *numRows, *outputFile, 
+				generatedFiles, err = generator.GenerateDataFilesOptimized(schemaData, numRows.total, *outputFile, 
 					generator.FormatCSV, performanceConfig)
 				if *outputFormat == "json" {
-					generatedFiles, err = generator.GenerateDataFilesOptimized(schemaData, *numRows, *outputFile, 
+					generatedFiles, err = generator.GenerateDataFilesOptimized(schemaData, numRows.total, *outputFile, 
 						generator.FormatJSON, performanceConfig)
 				}
 			})
 		} else {
 			logger.Time("Standard data generation", func() {
-				generatedFiles, err = generator.GenerateWithFormat(&schemaData, *numRows, *outputFile, *outputFormat)
+				generatedFiles, err = generator.GenerateWithConfig(&schemaData, numRows.total, *outputFile, *outputFormat, performanceConfig)
 			})
 		}
 	}
@@ -196,3 +241,118 @@ func getAIStatus() string {
 	}
 	return "Not configured (set OPENAI_API_KEY)"
 }
+
+// rowsFlag holds the -rows value: a row count for every table, per-table
+// counts written as table=N, or both separated by commas
+type rowsFlag struct {
+	total    int
+	perTable map[string]int
+}
+
+func (f *rowsFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	parts := []string{strconv.Itoa(f.total)}
+	names := make([]string, 0, len(f.perTable))
+	for name := range f.perTable {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s=%d", name, f.perTable[name]))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f *rowsFlag) Set(value string) error {
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, count, perTable := strings.Cut(part, "=")
+		if !perTable {
+			count = name
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid row count %q", part)
+		}
+		if !perTable {
+			f.total = n
+			continue
+		}
+		if f.perTable == nil {
+			f.perTable = make(map[string]int)
+		}
+		f.perTable[strings.TrimSpace(name)] = n
+	}
+	return nil
+}
+
+// apply sets the per-table row counts on the schema
+func (f *rowsFlag) apply(s *schema.Schema) error {
+	for name, n := range f.perTable {
+		found := false
+		for i := range s.Tables {
+			if s.Tables[i].Name == name {
+				count := n
+				s.Tables[i].Rows = &count
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown table %q", name)
+		}
+	}
+	return nil
+}
+
+// defaultsFlag is the -defaults policy, stored as the share of rows given
+// the column defaults
+type defaultsFlag struct {
+	ratio *float64
+}
+
+func (f *defaultsFlag) String() string {
+	if f == nil || f.ratio == nil {
+		return ""
+	}
+	switch *f.ratio {
+	case 1:
+		return "always"
+	case 0:
+		return "ignore"
+	}
+	return strconv.FormatFloat(*f.ratio, 'g', -1, 64)
+}
+
+func (f *defaultsFlag) Set(value string) error {
+	var ratio float64
+	switch value = strings.ToLower(strings.TrimSpace(value)); value {
+	case "always":
+		ratio = 1
+	case "ignore", "never":
+		ratio = 0
+	default:
+		r, err := strconv.ParseFloat(value, 64)
+		if err != nil || r < 0 || r > 1 {
+			return fmt.Errorf("use always, ignore or a share between 0 and 1, got %q", value)
+		}
+		ratio = r
+	}
+	f.ratio = &ratio
+	return nil
+}
+
+// flagWasSet reports whether a flag was given on the command line
+func flagWasSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
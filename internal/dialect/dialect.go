@@ -0,0 +1,85 @@
+// Package dialect maps the column types of SQL dialects (PostgreSQL, MySQL,
+// SQLite and SQL Server) onto the internal field types, and detects the
+// dialect of a DDL file.
+package dialect
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Dialect is an SQL dialect. Generic accepts the types of every dialect.
+type Dialect string
+
+const (
+	Generic   Dialect = ""
+	Postgres  Dialect = "postgres"
+	MySQL     Dialect = "mysql"
+	SQLite    Dialect = "sqlite"
+	SQLServer Dialect = "sqlserver"
+)
+
+// Auto is the -dialect value that detects the dialect from the file
+const Auto = "auto"
+
+// Dialects lists the supported dialects
+var Dialects = []Dialect{Postgres, MySQL, SQLite, SQLServer}
+
+// aliases are the other names accepted for each dialect
+var aliases = map[string]Dialect{
+	"postgres": Postgres, "postgresql": Postgres, "pg": Postgres,
+	"mysql": MySQL, "mariadb": MySQL,
+	"sqlite": SQLite, "sqlite3": SQLite,
+	"sqlserver": SQLServer, "mssql": SQLServer, "tsql": SQLServer,
+}
+
+// Parse returns the dialect with a name or alias, or Generic for "" and auto
+func Parse(name string) (Dialect, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == Auto {
+		return Generic, nil
+	}
+	if d, ok := aliases[name]; ok {
+		return d, nil
+	}
+	return Generic, fmt.Errorf("unknown SQL dialect %q; use auto, postgres, mysql, sqlite or sqlserver", name)
+}
+
+func (d Dialect) String() string {
+	if d == Generic {
+		return "generic"
+	}
+	return string(d)
+}
+
+// markers are the syntax only found in one dialect, used to detect it. SQL
+// Server identifiers in brackets must start with a letter and be followed by
+// a space, dot or parenthesis, unlike Postgres arrays (INT[3]) and the
+// character classes of LIKE patterns ('[a-c]%').
+var markers = map[Dialect]*regexp.Regexp{
+	Postgres:  regexp.MustCompile(`(?i)\b(?:BIGSERIAL|SMALLSERIAL|SERIAL|JSONB|BYTEA|TIMESTAMPTZ|INET|CIDR|MACADDR|CITEXT|TSVECTOR|nextval)\b|::|\$\$|\w\[\d*\]|\bCREATE\s+TYPE\b|\bOWNER\s+TO\b|\bCHARACTER\s+VARYING\b`),
+	MySQL:     regexp.MustCompile("(?i)`|\\b(?:AUTO_INCREMENT|UNSIGNED|TINYINT|MEDIUMINT|MEDIUMTEXT|LONGTEXT|TINYTEXT|LONGBLOB|MEDIUMBLOB|ZEROFILL)\\b|\\bENGINE\\s*=|\\bCHARSET\\b"),
+	SQLite:    regexp.MustCompile(`(?i)\bAUTOINCREMENT\b|\bWITHOUT\s+ROWID\b|\bPRAGMA\b|\)\s*STRICT\b`),
+	SQLServer: regexp.MustCompile(`(?i)\[[a-z_][^\]]*\][\s.(]|\b(?:NVARCHAR|NCHAR|NTEXT|DATETIME2|DATETIMEOFFSET|SMALLDATETIME|UNIQUEIDENTIFIER|SMALLMONEY)\b|\bIDENTITY\s*\(|\bdbo\.|(?m)^\s*GO\s*$`),
+}
+
+// Detect guesses the dialect of SQL source from the syntax only one dialect
+// uses, such as backquotes or JSONB. It returns Generic when no dialect
+// stands out.
+func Detect(src string) Dialect {
+	best, bestCount, tie := Generic, 0, false
+	for _, d := range Dialects {
+		count := len(markers[d].FindAllStringIndex(src, -1))
+		switch {
+		case count > bestCount:
+			best, bestCount, tie = d, count, false
+		case count == bestCount && count > 0:
+			tie = true
+		}
+	}
+	if tie {
+		return Generic
+	}
+	return best
+}
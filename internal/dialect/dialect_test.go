@@ -0,0 +1,162 @@
+package dialect
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		want Dialect
+	}{
+		{"", Generic},
+		{"auto", Generic},
+		{" AUTO ", Generic},
+		{"postgres", Postgres},
+		{"PostgreSQL", Postgres},
+		{"pg", Postgres},
+		{"mysql", MySQL},
+		{"mariadb", MySQL},
+		{"sqlite", SQLite},
+		{"sqlite3", SQLite},
+		{"sqlserver", SQLServer},
+		{"mssql", SQLServer},
+		{"tsql", SQLServer},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.name)
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+
+	if _, err := Parse("oracle"); err == nil || !strings.Contains(err.Error(), "unknown SQL dialect") {
+		t.Errorf("Parse(oracle) error = %v, want an unknown dialect", err)
+	}
+	if Generic.String() != "generic" || Postgres.String() != "postgres" {
+		t.Errorf("String() = %q, %q", Generic.String(), Postgres.String())
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want Dialect
+	}{
+		{"postgres serial", "CREATE TABLE t (id SERIAL PRIMARY KEY, doc JSONB);", Postgres},
+		{"postgres cast", "ALTER TABLE t ALTER COLUMN id SET DEFAULT nextval('t_id_seq'::regclass);", Postgres},
+		{"postgres array", "CREATE TABLE t (tags text[]);", Postgres},
+		{"postgres sized array", "CREATE TABLE t (grid INT[3][3], code TEXT CHECK (code LIKE '[A-Z]%'));", Postgres},
+		{"like class", "CREATE TABLE t (code VARCHAR(5) CHECK (code LIKE '[a-c]%'));", Generic},
+		{"mysql backquotes", "CREATE TABLE `t` (`id` INT AUTO_INCREMENT) ENGINE=InnoDB;", MySQL},
+		{"sqlite", "CREATE TABLE t (id INTEGER PRIMARY KEY AUTOINCREMENT) WITHOUT ROWID;", SQLite},
+		{"sqlserver", "CREATE TABLE [dbo].[t] ([id] INT IDENTITY(1,1), [name] NVARCHAR(50));\nGO", SQLServer},
+		{"sqlserver brackets", "CREATE TABLE [orders] ([id] INT, [total] DECIMAL(10, 2));", SQLServer},
+		{"plain ansi", "CREATE TABLE t (id INT PRIMARY KEY, name VARCHAR(50));", Generic},
+		{"tie", "CREATE TABLE t (id SERIAL, n TINYINT);", Generic},
+		{"majority wins", "CREATE TABLE t (id SERIAL, doc JSONB, n TINYINT);", Postgres},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.src); got != tt.want {
+			t.Errorf("%s: Detect() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseType(t *testing.T) {
+	tests := []struct {
+		sqlType string
+		want    Type
+	}{
+		{"int", Type{Name: "INT"}},
+		{"NUMERIC(10, 2)", Type{Name: "NUMERIC", Args: []string{"10", "2"}}},
+		{"nvarchar(max)", Type{Name: "NVARCHAR", Args: []string{"MAX"}}},
+		{"INT UNSIGNED ZEROFILL", Type{Name: "INT", Unsigned: true}},
+		{"character varying(255)", Type{Name: "CHARACTER VARYING", Args: []string{"255"}}},
+		{"text[]", Type{Name: "TEXT", Array: true}},
+		{"INT[3][3]", Type{Name: "INT", Array: true}},
+		{"integer ARRAY", Type{Name: "INTEGER", Array: true}},
+		{"pg_catalog.int4", Type{Name: "INT4"}},
+		{"[datetime2]", Type{Name: "DATETIME2"}},
+	}
+	for _, tt := range tests {
+		if got := ParseType(tt.sqlType); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseType(%q) = %+v, want %+v", tt.sqlType, got, tt.want)
+		}
+	}
+}
+
+func TestMapType(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		sqlType string
+		want    string
+	}{
+		// Types every dialect shares
+		{Postgres, "INTEGER", "int"},
+		{MySQL, "DECIMAL(10,2)", "float"},
+		{SQLite, "VARCHAR(20)", "string"},
+		{SQLServer, "DATE", "date"},
+		{Postgres, "TIMESTAMP WITH TIME ZONE", "timestamp"},
+		{MySQL, "TIME", Time},
+		{Postgres, "INTERVAL", Interval},
+		{MySQL, "JSON", JSON},
+		{SQLServer, "VARBINARY(MAX)", Binary},
+
+		// Postgres
+		{Postgres, "BIGSERIAL", "int"},
+		{Postgres, "JSONB", JSON},
+		{Postgres, "BYTEA", Binary},
+		{Postgres, "MONEY", "price"},
+		{Postgres, "INET", "ipaddress"},
+		{Postgres, "MACADDR", "macaddress"},
+		{Postgres, "POINT", Point},
+		{Postgres, "TIMESTAMPTZ", "timestamp"},
+		{Postgres, "TEXT[]", "string[]"},
+		{Postgres, "INT4[]", "int[]"},
+		{Postgres, "TINYINT", ""},
+
+		// MySQL
+		{MySQL, "TINYINT(1)", "boolean"},
+		{MySQL, "TINYINT(4)", "int"},
+		{MySQL, "BIT", "boolean"},
+		{MySQL, "BIT(8)", "int"},
+		{MySQL, "INT UNSIGNED", "int"},
+		{MySQL, "LONGTEXT", "string"},
+		{MySQL, "MEDIUMBLOB", Binary},
+		{MySQL, "JSONB", ""},
+
+		// SQLite, with type affinity for names it does not list
+		{SQLite, "UNSIGNED BIG INT", "int"},
+		{SQLite, "NVARCHAR(100)", "string"},
+		{SQLite, "BIGINT UNSIGNED", "int"},
+		{SQLite, "VARCHAR2(10)", "string"},
+		{SQLite, "LONGBLOB", Binary},
+		{SQLite, "DOUBLE FLOAT", "float"},
+		{SQLite, "MONEY", "float"},
+
+		// SQL Server
+		{SQLServer, "BIT", "boolean"},
+		{SQLServer, "NVARCHAR(MAX)", "string"},
+		{SQLServer, "DATETIME2", "timestamp"},
+		{SQLServer, "UNIQUEIDENTIFIER", "uuid"},
+		{SQLServer, "SMALLMONEY", "price"},
+		{SQLServer, "ROWVERSION", Binary},
+		{SQLServer, "SERIAL", ""},
+
+		// Generic accepts the types of every dialect
+		{Generic, "JSONB", JSON},
+		{Generic, "TINYINT(1)", "boolean"},
+		{Generic, "DATETIMEOFFSET", "timestamp"},
+		{Generic, "NVARCHAR(50)", "string"},
+		{Generic, "GEOGRAPHY", ""},
+	}
+	for _, tt := range tests {
+		if got := tt.dialect.MapType(tt.sqlType); got != tt.want {
+			t.Errorf("%v.MapType(%q) = %q, want %q", tt.dialect, tt.sqlType, got, tt.want)
+		}
+	}
+}
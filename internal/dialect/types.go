@@ -0,0 +1,174 @@
+package dialect
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Type is a parsed column type, e.g. NUMERIC(10,2), INT UNSIGNED or TEXT[]
+type Type struct {
+	Name     string   // upper-case name without arguments, e.g. CHARACTER VARYING
+	Args     []string // arguments, e.g. [10 2] for NUMERIC(10,2) or [MAX]
+	Array    bool     // Postgres array: TEXT[], INT[3] or INT ARRAY
+	Unsigned bool     // MySQL UNSIGNED
+}
+
+var (
+	typeArgsRe    = regexp.MustCompile(`\(([^)]*)\)`)
+	arraySuffixRe = regexp.MustCompile(`(?:\s*\[\s*\d*\s*\])+$|\s+ARRAY$`)
+)
+
+// ParseType parses a column type as written in DDL
+func ParseType(sqlType string) Type {
+	s := strings.ToUpper(strings.TrimSpace(sqlType))
+	var t Type
+	if loc := arraySuffixRe.FindStringIndex(s); loc != nil {
+		t.Array = true
+		s = s[:loc[0]]
+	}
+	if m := typeArgsRe.FindStringSubmatchIndex(s); m != nil {
+		for _, arg := range strings.Split(s[m[2]:m[3]], ",") {
+			t.Args = append(t.Args, strings.TrimSpace(arg))
+		}
+		s = s[:m[0]] + " " + s[m[1]:]
+	}
+
+	var words []string
+	for _, word := range strings.Fields(strings.NewReplacer(`"`, "", "`", "", "[", "", "]", "").Replace(s)) {
+		switch word {
+		case "UNSIGNED":
+			t.Unsigned = true
+		case "SIGNED", "ZEROFILL":
+		default:
+			words = append(words, word)
+		}
+	}
+	t.Name = strings.Join(words, " ")
+	// Schema-qualified types such as pg_catalog.int4
+	if i := strings.LastIndex(t.Name, "."); i >= 0 {
+		t.Name = t.Name[i+1:]
+	}
+	return t
+}
+
+// Internal field types of SQL types beyond the basic int, float, string,
+// boolean, date and timestamp
+const (
+	JSON     = "json"
+	Binary   = "binary"
+	Point    = "point"
+	Interval = "interval"
+	Time     = "time"
+)
+
+// common maps the types every dialect shares, with ANSI names
+var common = map[string]string{
+	"INT": "int", "INTEGER": "int", "SMALLINT": "int", "BIGINT": "int",
+	"DECIMAL": "float", "NUMERIC": "float", "DEC": "float", "FLOAT": "float", "REAL": "float",
+	"DOUBLE": "float", "DOUBLE PRECISION": "float",
+	"CHAR": "string", "CHARACTER": "string", "VARCHAR": "string", "CHARACTER VARYING": "string",
+	"NATIONAL CHARACTER": "string", "NATIONAL CHAR": "string", "TEXT": "string", "CLOB": "string",
+	"BOOLEAN": "boolean", "BOOL": "boolean",
+	"DATE":      "date",
+	"TIMESTAMP": "timestamp", "DATETIME": "timestamp", "TIMESTAMP WITH TIME ZONE": "timestamp",
+	"TIMESTAMP WITHOUT TIME ZONE": "timestamp",
+	"TIME":                        Time, "TIME WITH TIME ZONE": Time, "TIME WITHOUT TIME ZONE": Time,
+	"INTERVAL": Interval,
+	"UUID":     "uuid",
+	"JSON":     JSON,
+	"BINARY":   Binary, "VARBINARY": Binary, "BLOB": Binary, "BINARY VARYING": Binary,
+	"XML": "string",
+}
+
+// types maps the types of each dialect that are not in common
+var types = map[Dialect]map[string]string{
+	Postgres: {
+		"SERIAL": "int", "SMALLSERIAL": "int", "BIGSERIAL": "int", "SERIAL2": "int", "SERIAL4": "int", "SERIAL8": "int",
+		"INT2": "int", "INT4": "int", "INT8": "int", "FLOAT4": "float", "FLOAT8": "float",
+		"MONEY": "price", "BPCHAR": "string", "CITEXT": "string", "NAME": "string", "TSVECTOR": "string",
+		"TIMESTAMPTZ": "timestamp", "TIMETZ": Time,
+		"JSONB": JSON, "BYTEA": Binary,
+		"INET": "ipaddress", "CIDR": "ipaddress", "MACADDR": "macaddress", "MACADDR8": "macaddress",
+		"POINT": Point,
+	},
+	MySQL: {
+		"TINYINT": "int", "MEDIUMINT": "int", "YEAR": "int", "BIT": "int",
+		"TINYTEXT": "string", "MEDIUMTEXT": "string", "LONGTEXT": "string", "SET": "string",
+		"TINYBLOB": Binary, "MEDIUMBLOB": Binary, "LONGBLOB": Binary,
+		"POINT": Point,
+	},
+	SQLite: {
+		"INT2": "int", "INT8": "int", "TINYINT": "int", "MEDIUMINT": "int", "UNSIGNED BIG INT": "int",
+		"NCHAR": "string", "NVARCHAR": "string", "VARYING CHARACTER": "string", "NATIVE CHARACTER": "string",
+	},
+	SQLServer: {
+		"TINYINT": "int", "BIT": "boolean",
+		"NCHAR": "string", "NVARCHAR": "string", "NTEXT": "string", "SYSNAME": "string",
+		"DATETIME2": "timestamp", "SMALLDATETIME": "timestamp", "DATETIMEOFFSET": "timestamp",
+		"UNIQUEIDENTIFIER": "uuid", "MONEY": "price", "SMALLMONEY": "price", "IMAGE": Binary,
+		"ROWVERSION": Binary,
+	},
+}
+
+// MapType returns the internal field type of an SQL column type, or "" when
+// the dialect does not know it. Arrays map to their element type followed by
+// [], e.g. string[].
+func (d Dialect) MapType(sqlType string) string {
+	return d.Map(ParseType(sqlType))
+}
+
+// Map returns the internal field type of a parsed column type, or ""
+func (d Dialect) Map(t Type) string {
+	internal := d.lookup(t)
+	if internal != "" && t.Array {
+		return internal + "[]"
+	}
+	return internal
+}
+
+func (d Dialect) lookup(t Type) string {
+	// MySQL stores booleans as TINYINT(1) and BIT, which is BIT(1)
+	if d == MySQL || d == Generic {
+		one := len(t.Args) == 1 && t.Args[0] == "1"
+		if (t.Name == "TINYINT" && one) || (t.Name == "BIT" && (one || len(t.Args) == 0)) {
+			return "boolean"
+		}
+	}
+
+	if internal, ok := common[t.Name]; ok {
+		return internal
+	}
+	if d == Generic {
+		for _, other := range Dialects {
+			if internal, ok := types[other][t.Name]; ok {
+				return internal
+			}
+		}
+		return ""
+	}
+	if internal, ok := types[d][t.Name]; ok {
+		return internal
+	}
+	if d == SQLite {
+		return sqliteAffinity(t.Name)
+	}
+	return ""
+}
+
+// sqliteAffinity applies the rules SQLite uses to give any declared type an
+// affinity, e.g. BIGINT UNSIGNED is an integer and VARCHAR2 text
+func sqliteAffinity(name string) string {
+	switch {
+	case name == "":
+		return ""
+	case strings.Contains(name, "INT"):
+		return "int"
+	case strings.Contains(name, "CHAR"), strings.Contains(name, "CLOB"), strings.Contains(name, "TEXT"):
+		return "string"
+	case strings.Contains(name, "BLOB"):
+		return Binary
+	case strings.Contains(name, "REAL"), strings.Contains(name, "FLOA"), strings.Contains(name, "DOUB"):
+		return "float"
+	}
+	return "float" // NUMERIC affinity
+}
@@ -0,0 +1,491 @@
+package expr
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Env supplies the values an expression reads: the columns of the row and
+// the aggregates over child rows. Aggregate may be nil when the expression
+// has none.
+type Env struct {
+	Row       map[string]interface{}
+	Aggregate func(Aggregate) interface{}
+}
+
+// Eval evaluates the expression. Evaluation never fails: null inputs give a
+// null result, as in SQL, and so do divisions by zero.
+func (e *Expr) Eval(env Env) interface{} {
+	return e.root.eval(env)
+}
+
+type node interface {
+	eval(env Env) interface{}
+}
+
+type literalNode struct{ value interface{} }
+
+func (n *literalNode) eval(Env) interface{} { return n.value }
+
+type columnNode struct{ name string }
+
+func (n *columnNode) eval(env Env) interface{} { return env.Row[n.name] }
+
+type aggregateNode struct{ aggregate Aggregate }
+
+func (n *aggregateNode) eval(env Env) interface{} {
+	if env.Aggregate == nil {
+		return nil
+	}
+	return env.Aggregate(n.aggregate)
+}
+
+type notNode struct{ operand node }
+
+func (n *notNode) eval(env Env) interface{} { return !Truthy(n.operand.eval(env)) }
+
+type isNullNode struct{ operand node }
+
+func (n *isNullNode) eval(env Env) interface{} { return n.operand.eval(env) == nil }
+
+// inNode tests whether a value is one of a list; a null value is in no list
+type inNode struct {
+	operand node
+	values  []node
+}
+
+func (n *inNode) eval(env Env) interface{} {
+	value := n.operand.eval(env)
+	if value == nil {
+		return false
+	}
+	for _, v := range n.values {
+		if other := v.eval(env); other != nil && compare(value, other) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type logicalNode struct {
+	or          bool
+	left, right node
+}
+
+func (n *logicalNode) eval(env Env) interface{} {
+	left := Truthy(n.left.eval(env))
+	if n.or {
+		return left || Truthy(n.right.eval(env))
+	}
+	return left && Truthy(n.right.eval(env))
+}
+
+type caseNode struct {
+	operand      node // nil for CASE WHEN condition THEN ...
+	whens, thens []node
+	otherwise    node
+}
+
+func (n *caseNode) eval(env Env) interface{} {
+	var operand interface{}
+	if n.operand != nil {
+		operand = n.operand.eval(env)
+	}
+	for i, when := range n.whens {
+		value := when.eval(env)
+		matched := Truthy(value)
+		if n.operand != nil {
+			matched = operand != nil && value != nil && compare(operand, value) == 0
+		}
+		if matched {
+			return n.thens[i].eval(env)
+		}
+	}
+	if n.otherwise != nil {
+		return n.otherwise.eval(env)
+	}
+	return nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n *binaryNode) eval(env Env) interface{} {
+	left, right := n.left.eval(env), n.right.eval(env)
+	if left == nil || right == nil {
+		return nil
+	}
+
+	switch n.op {
+	case "||":
+		return ToString(left) + ToString(right)
+	case "=", "==":
+		return compare(left, right) == 0
+	case "!=", "<>":
+		return compare(left, right) != 0
+	case "<":
+		return compare(left, right) < 0
+	case "<=":
+		return compare(left, right) <= 0
+	case ">":
+		return compare(left, right) > 0
+	case ">=":
+		return compare(left, right) >= 0
+	}
+
+	a, aok := ToNumber(left)
+	b, bok := ToNumber(right)
+	if !aok || !bok {
+		// + joins values that are not both numbers, e.g. first_name + " " + last_name
+		if n.op == "+" {
+			return ToString(left) + ToString(right)
+		}
+		return nil
+	}
+	ai, aint := a.(int)
+	bi, bint := b.(int)
+	if aint && bint {
+		switch n.op {
+		case "+":
+			return ai + bi
+		case "-":
+			return ai - bi
+		case "*":
+			return ai * bi
+		case "%":
+			if bi == 0 {
+				return nil
+			}
+			return ai % bi
+		}
+	}
+
+	x, y := toFloat(a), toFloat(b)
+	switch n.op {
+	case "+":
+		return x + y
+	case "-":
+		return x - y
+	case "*":
+		return x * y
+	case "/":
+		if y == 0 {
+			return nil
+		}
+		return x / y
+	case "%":
+		if y == 0 {
+			return nil
+		}
+		return math.Mod(x, y)
+	}
+	return nil
+}
+
+type callNode struct {
+	fn   func(args []interface{}) interface{}
+	args []node
+}
+
+func (n *callNode) eval(env Env) interface{} {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		args[i] = arg.eval(env)
+	}
+	return n.fn(args)
+}
+
+// Truthy reports whether a value counts as true in a condition: true,
+// non-zero numbers and non-empty strings
+func Truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != "" && !strings.EqualFold(v, "false")
+	}
+	if n, ok := ToNumber(value); ok {
+		return toFloat(n) != 0
+	}
+	return true
+}
+
+// ToNumber converts numbers and numeric strings to an int or a float64
+func ToNumber(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		n, err := parseNumber(string(v))
+		return n, err == nil
+	case string:
+		n, err := parseNumber(strings.TrimSpace(v))
+		return n, err == nil
+	}
+	return nil, false
+}
+
+// parseNumber reads an integer or a decimal number
+func parseNumber(s string) (interface{}, error) {
+	if i, err := strconv.Atoi(s); err == nil {
+		return i, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("not a number: %q", s)
+	}
+	return f, nil
+}
+
+func toFloat(n interface{}) float64 {
+	if i, ok := n.(int); ok {
+		return float64(i)
+	}
+	return n.(float64)
+}
+
+// ToString renders a value as text, the way it is written to CSV
+func ToString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// compare orders two values: numerically when both are numbers, as text otherwise
+func compare(a, b interface{}) int {
+	x, xok := ToNumber(a)
+	y, yok := ToNumber(b)
+	if xok && yok {
+		switch fx, fy := toFloat(x), toFloat(y); {
+		case fx < fy:
+			return -1
+		case fx > fy:
+			return 1
+		}
+		return 0
+	}
+	if ab, ok := a.(bool); ok {
+		return compare(boolNumber(ab), boolNumber(Truthy(b)))
+	}
+	return strings.Compare(ToString(a), ToString(b))
+}
+
+func boolNumber(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// aggregateFuncs take a table.column argument to aggregate child rows
+var aggregateFuncs = map[string]bool{"sum": true, "avg": true, "count": true, "min": true, "max": true}
+
+// function describes a scalar function and how many arguments it takes
+type function struct {
+	minArgs, maxArgs int // maxArgs is -1 for any number
+	fn               func(args []interface{}) interface{}
+}
+
+func (f function) arity() string {
+	switch {
+	case f.maxArgs < 0:
+		return fmt.Sprintf("at least %d arguments", f.minArgs)
+	case f.minArgs == f.maxArgs:
+		return fmt.Sprintf("%d arguments", f.minArgs)
+	}
+	return fmt.Sprintf("%d to %d arguments", f.minArgs, f.maxArgs)
+}
+
+// strict wraps a function so that a null argument gives a null result
+func strict(fn func(args []interface{}) interface{}) func(args []interface{}) interface{} {
+	return func(args []interface{}) interface{} {
+		for _, arg := range args {
+			if arg == nil {
+				return nil
+			}
+		}
+		return fn(args)
+	}
+}
+
+// numeric wraps a one-argument math function
+func numeric(fn func(float64) float64) func(args []interface{}) interface{} {
+	return strict(func(args []interface{}) interface{} {
+		n, ok := ToNumber(args[0])
+		if !ok {
+			return nil
+		}
+		if i, isInt := n.(int); isInt {
+			return int(fn(float64(i)))
+		}
+		return fn(n.(float64))
+	})
+}
+
+// functions are the scalar functions expressions can call
+var functions = map[string]function{
+	"lower": {1, 1, strict(func(a []interface{}) interface{} { return strings.ToLower(ToString(a[0])) })},
+	"upper": {1, 1, strict(func(a []interface{}) interface{} { return strings.ToUpper(ToString(a[0])) })},
+	"trim":  {1, 1, strict(func(a []interface{}) interface{} { return strings.TrimSpace(ToString(a[0])) })},
+	"length": {1, 1, strict(func(a []interface{}) interface{} {
+		return len([]rune(ToString(a[0])))
+	})},
+	"substr": {2, 3, strict(substr)},
+	"replace": {3, 3, strict(func(a []interface{}) interface{} {
+		return strings.ReplaceAll(ToString(a[0]), ToString(a[1]), ToString(a[2]))
+	})},
+	"slug": {1, 1, strict(slug)},
+	"concat": {1, -1, func(a []interface{}) interface{} {
+		var b strings.Builder
+		for _, arg := range a {
+			b.WriteString(ToString(arg))
+		}
+		return b.String()
+	}},
+	"format":   {1, -1, format},
+	"round":    {1, 2, strict(round)},
+	"floor":    {1, 1, numeric(math.Floor)},
+	"ceil":     {1, 1, numeric(math.Ceil)},
+	"abs":      {1, 1, numeric(math.Abs)},
+	"coalesce": {1, -1, coalesce},
+	"if": {3, 3, func(a []interface{}) interface{} {
+		if Truthy(a[0]) {
+			return a[1]
+		}
+		return a[2]
+	}},
+	"min": {2, -1, strict(func(a []interface{}) interface{} { return extreme(a, -1) })},
+	"max": {2, -1, strict(func(a []interface{}) interface{} { return extreme(a, 1) })},
+}
+
+// substr returns length characters from a 1-based start, or the rest of the string
+func substr(a []interface{}) interface{} {
+	s := []rune(ToString(a[0]))
+	start, ok := ToNumber(a[1])
+	if !ok {
+		return nil
+	}
+	from := int(toFloat(start)) - 1
+	if from < 0 {
+		from = 0
+	}
+	if from > len(s) {
+		from = len(s)
+	}
+	to := len(s)
+	if len(a) == 3 {
+		n, ok := ToNumber(a[2])
+		if !ok {
+			return nil
+		}
+		if end := from + int(toFloat(n)); end < to {
+			to = end
+		}
+	}
+	if to < from {
+		to = from
+	}
+	return string(s[from:to])
+}
+
+var nonSlugRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slug turns text into a lower-case, dash-separated identifier
+func slug(a []interface{}) interface{} {
+	return strings.Trim(nonSlugRe.ReplaceAllString(strings.ToLower(ToString(a[0])), "-"), "-")
+}
+
+// round rounds to a number of decimals, 0 by default
+func round(a []interface{}) interface{} {
+	n, ok := ToNumber(a[0])
+	if !ok {
+		return nil
+	}
+	places := 0
+	if len(a) == 2 {
+		p, ok := ToNumber(a[1])
+		if !ok {
+			return nil
+		}
+		places = int(toFloat(p))
+	}
+	if i, isInt := n.(int); isInt && places >= 0 {
+		return i
+	}
+	unit := math.Pow10(places)
+	return math.Round(toFloat(n)*unit) / unit
+}
+
+func coalesce(a []interface{}) interface{} {
+	for _, arg := range a {
+		if arg != nil {
+			return arg
+		}
+	}
+	return nil
+}
+
+// extreme returns the smallest (sign -1) or largest (sign 1) argument
+func extreme(a []interface{}, sign int) interface{} {
+	best := a[0]
+	for _, arg := range a[1:] {
+		if compare(arg, best)*sign > 0 {
+			best = arg
+		}
+	}
+	return best
+}
+
+var verbRe = regexp.MustCompile(`%[-+# 0]*\d*(?:\.\d+)?([a-zA-Z%])`)
+
+// format formats values with Go fmt verbs, converting each value to the kind
+// its verb expects: integers for %d, %x, %o, %b and %c, floats for %e, %f and
+// %g, text for the others
+func format(a []interface{}) interface{} {
+	if a[0] == nil {
+		return nil
+	}
+	layout := ToString(a[0])
+	var args []interface{}
+	next := 1
+	for _, m := range verbRe.FindAllStringSubmatch(layout, -1) {
+		verb := m[1]
+		if verb == "%" {
+			continue
+		}
+		var arg interface{}
+		if next < len(a) {
+			arg = a[next]
+		}
+		next++
+		n, numericArg := ToNumber(arg)
+		switch {
+		case strings.Contains("dxXobc", verb) && numericArg:
+			arg = int(math.Round(toFloat(n)))
+		case strings.Contains("eEfFgG", verb) && numericArg:
+			arg = toFloat(n)
+		case strings.Contains("sqv", verb):
+			arg = ToString(arg)
+		}
+		args = append(args, arg)
+	}
+	return fmt.Sprintf(layout, args...)
+}
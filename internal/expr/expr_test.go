@@ -0,0 +1,204 @@
+package expr
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	row := map[string]interface{}{
+		"quantity":   3,
+		"unit_price": 2.5,
+		"first_name": "Ada",
+		"last_name":  "Lovelace",
+		"status":     "shipped",
+		"zip":        "123",
+		"suffix":     "456",
+		"amount":     json.Number("10.25"),
+		"missing":    nil,
+	}
+
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		// Precedence: * / % before + - ||, comparisons before NOT, AND before OR
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 - 4 - 3", 3},
+		{"7 % 4 + 1", 4},
+		{"-2 * 3", -6},
+		{"7 / 2", 3.5},
+		{"quantity * unit_price", 7.5},
+		{"amount * 2", 20.5},
+		{"1 + 2 = 3", true},
+		{"1 = 1 OR 1 = 2 AND 1 = 2", true},
+		{"NOT 1 = 2 AND 2 <> 3", true},
+		{"quantity >= 3 AND status = 'shipped'", true},
+		{"'b' > 'a'", true},
+		{"'10' > 9", true},
+		{"first_name || ' ' || last_name", "Ada Lovelace"},
+		{"'#' || (1 + 2)", "#3"},
+		{"'#' || 1 + 2", "#12"}, // + - || share a level and apply left to right
+
+		// + adds values that are both numbers, numeric-looking strings included,
+		// and joins the others; || always joins
+		{"zip + suffix", 579},
+		{"zip || suffix", "123456"},
+		{"first_name + ' ' + last_name", "Ada Lovelace"},
+		{"zip + '-' + suffix", "123-456"},
+
+		// CASE, with and without an operand
+		{"CASE WHEN quantity >= 3 THEN 'bulk' ELSE 'single' END", "bulk"},
+		{"CASE WHEN quantity > 5 THEN 'bulk' END", nil},
+		{"CASE status WHEN 'pending' THEN 1 WHEN 'shipped' THEN 2 ELSE 0 END", 2},
+		{"CASE missing WHEN 'x' THEN 1 ELSE 0 END", 0},
+
+		// IN and IS NULL
+		{"status IN ('shipped', 'delivered')", true},
+		{"status NOT IN ('shipped', 'delivered')", false},
+		{"quantity IN (1, 2, '3')", true},
+		{"missing IN ('x')", false},
+		{"missing IS NULL", true},
+		{"status IS NOT NULL", true},
+
+		// Functions
+		{"lower(first_name)", "ada"},
+		{"upper(last_name)", "LOVELACE"},
+		{"trim('  x  ')", "x"},
+		{"length(last_name)", 8},
+		{"substr(last_name, 1, 4)", "Love"},
+		{"substr(last_name, 5)", "lace"},
+		{"replace(status, 'shipp', 'wrapp')", "wrapped"},
+		{"slug('Hello, World!')", "hello-world"},
+		{"concat(first_name, missing, '!')", "Ada!"},
+		{"format('%s x%d (%.1f)', first_name, quantity, unit_price)", "Ada x3 (2.5)"},
+		{"round(2.345, 2)", 2.35},
+		{"round(unit_price)", 3.0},
+		{"round(quantity)", 3},
+		{"floor(2.7)", 2.0},
+		{"ceil(quantity)", 3},
+		{"abs(-4)", 4},
+		{"coalesce(missing, 'n/a')", "n/a"},
+		{"if(quantity > 2, 'many', 'few')", "many"},
+		{"min(3, 1, 2)", 1},
+		{"max(first_name, last_name)", "Lovelace"},
+
+		// Null inputs give null, as does division by zero
+		{"missing + 1", nil},
+		{"missing || 'x'", nil},
+		{"missing = missing", nil},
+		{"lower(missing)", nil},
+		{"min(1, missing)", nil},
+		{"quantity / 0", nil},
+		{"quantity % 0", nil},
+		{"unknown_column * 2", nil},
+		{"NULL", nil},
+	}
+
+	for _, tt := range tests {
+		e, err := Parse(tt.src)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.src, err)
+			continue
+		}
+		if got := e.Eval(Env{Row: row}); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %#v, want %#v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestParseColumnsAndAggregates(t *testing.T) {
+	e, err := Parse("coalesce(sum(order_items.total), 0) + count(order_items.id) * fee + fee - avg(refunds.amount) + min(a, b) + max(lines.qty)")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if want := []string{"fee", "a", "b"}; !reflect.DeepEqual(e.Columns(), want) {
+		t.Errorf("Columns() = %v, want %v", e.Columns(), want)
+	}
+	want := []Aggregate{
+		{Func: "sum", Table: "order_items", Column: "total"},
+		{Func: "count", Table: "order_items", Column: "id"},
+		{Func: "avg", Table: "refunds", Column: "amount"},
+		{Func: "max", Table: "lines", Column: "qty"},
+	}
+	if !reflect.DeepEqual(e.Aggregates(), want) {
+		t.Errorf("Aggregates() = %v, want %v", e.Aggregates(), want)
+	}
+
+	values := map[string]interface{}{"sum": 10, "count": 2, "avg": nil, "max": 4}
+	aggregate := func(a Aggregate) interface{} { return values[a.Func] }
+	total, err := Parse("sum(order_items.total) + count(order_items.id) * max(lines.qty)")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := total.Eval(Env{Aggregate: aggregate}); got != 18 {
+		t.Errorf("Eval() with aggregates = %v, want 18", got)
+	}
+	if got := total.Eval(Env{}); got != nil {
+		t.Errorf("Eval() without aggregates = %v, want nil", got)
+	}
+	average, _ := Parse("coalesce(avg(refunds.amount), 0)")
+	if got := average.Eval(Env{Aggregate: aggregate}); got != 0 {
+		t.Errorf("coalesce of a null aggregate = %v, want 0", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"1 +", "unexpected"},
+		{"(1 + 2", `expected ")"`},
+		{"quantity quantity", "unexpected"},
+		{"nope(1)", "unknown function nope"},
+		{"lower()", "lower takes 1 arguments"},
+		{"substr('a')", "substr takes 2 to 3 arguments"},
+		{"coalesce()", "coalesce takes at least 1 arguments"},
+		{"order_items.total * 2", "can only be used inside an aggregate"},
+		{"sum(order_items.total, 1)", "single table.column argument"},
+		{"CASE END", "CASE needs at least one WHEN"},
+		{"CASE WHEN 1 THEN 2", "expected END"},
+		{"x IS 1", "expected NULL"},
+		{"x IN 1", `expected "("`},
+		{"'open", "unterminated"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestTruthyAndToNumber(t *testing.T) {
+	for value, want := range map[interface{}]bool{nil: false, true: true, false: false, "": false, "false": false, "x": true, 0: false, 2: true, 0.0: false, "0": true} {
+		if got := Truthy(value); got != want {
+			t.Errorf("Truthy(%#v) = %v, want %v", value, got, want)
+		}
+	}
+
+	tests := []struct {
+		value interface{}
+		want  interface{}
+		ok    bool
+	}{
+		{3, 3, true},
+		{int64(4), 4, true},
+		{1.5, 1.5, true},
+		{json.Number("12"), 12, true},
+		{" 2.5 ", 2.5, true},
+		{"abc", nil, false},
+		{"NaN", nil, false},
+		{true, nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := ToNumber(tt.value)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("ToNumber(%#v) = %#v, %v, want %#v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
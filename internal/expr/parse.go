@@ -0,0 +1,523 @@
+// Package expr parses and evaluates the expressions of computed fields and
+// the conditions of when rules, such as quantity * unit_price, lower(title),
+// sum(order_items.total) or status IN ('shipped', 'delivered').
+//
+// The syntax follows SQL: arithmetic (+ - * / %), string concatenation (||,
+// or + between values that are not both numbers), comparisons (= != <> < <=
+// > >=), [NOT] IN (...), AND/OR/NOT, IS [NOT] NULL, CASE WHEN ... THEN ...
+// ELSE ... END and function calls. Strings are quoted with ' or ", identifiers name columns of
+// the row, and table.column inside sum, avg, count, min or max aggregates the
+// rows of a child table.
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Aggregate is an aggregate over the child rows of a table, e.g. sum(order_items.total)
+type Aggregate struct {
+	Func   string // sum, avg, count, min or max
+	Table  string // child table
+	Column string // column of the child table
+}
+
+// Expr is a parsed expression
+type Expr struct {
+	src        string
+	root       node
+	columns    []string
+	aggregates []Aggregate
+}
+
+// Columns returns the columns of the row the expression reads, in order of appearance
+func (e *Expr) Columns() []string {
+	return e.columns
+}
+
+// Aggregates returns the child-table aggregates the expression reads
+func (e *Expr) Aggregates() []Aggregate {
+	return e.aggregates
+}
+
+// String returns the source of the expression
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Parse parses an expression. Errors give the column of the problem.
+func Parse(src string) (*Expr, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: src, tokens: tokens, seen: make(map[string]bool)}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorf(tok, "unexpected %s", tok)
+	}
+	return &Expr{src: src, root: root, columns: p.columns, aggregates: p.aggregates}, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int // byte offset in the source
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return fmt.Sprintf("string %q", t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// twoCharOps are the operators made of two characters
+var twoCharOps = []string{"||", "<=", ">=", "<>", "!=", "=="}
+
+// lex splits an expression into tokens
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])):
+			start := i
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, src[start:i], start})
+		case c == '\'' || c == '"':
+			start := i
+			var b strings.Builder
+			for i++; ; i++ {
+				if i >= len(src) {
+					return nil, fmt.Errorf("expression %q: unterminated string at column %d", src, start+1)
+				}
+				if src[i] == c {
+					// A doubled quote is an escaped quote, as in SQL
+					if i+1 < len(src) && src[i+1] == c {
+						b.WriteByte(c)
+						i++
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteByte(src[i])
+			}
+			tokens = append(tokens, token{tokString, b.String(), start})
+		case isIdentStart(c) || c == '`':
+			start := i
+			name, next, err := lexName(src, i)
+			if err != nil {
+				return nil, err
+			}
+			// Qualified names: table.column
+			for next+1 < len(src) && src[next] == '.' && (isIdentStart(src[next+1]) || src[next+1] == '`') {
+				var part string
+				if part, next, err = lexName(src, next+1); err != nil {
+					return nil, err
+				}
+				name += "." + part
+			}
+			i = next
+			tokens = append(tokens, token{tokIdent, name, start})
+		default:
+			op := string(c)
+			for _, two := range twoCharOps {
+				if strings.HasPrefix(src[i:], two) {
+					op = two
+				}
+			}
+			if !strings.Contains("+-*/%(),=<>", op) && len(op) == 1 {
+				return nil, fmt.Errorf("expression %q: unexpected character %q at column %d", src, c, i+1)
+			}
+			tokens = append(tokens, token{tokOp, op, i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(src)}), nil
+}
+
+// lexName reads a plain or `quoted` identifier starting at i
+func lexName(src string, i int) (string, int, error) {
+	if src[i] == '`' {
+		end := strings.IndexByte(src[i+1:], '`')
+		if end < 0 {
+			return "", 0, fmt.Errorf("expression %q: unterminated identifier at column %d", src, i+1)
+		}
+		return src[i+1 : i+1+end], i + end + 2, nil
+	}
+	start := i
+	for i < len(src) && (isIdentStart(src[i]) || isDigit(src[i])) {
+		i++
+	}
+	return src[start:i], i, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// parser is a recursive-descent parser over the tokens, lowest precedence first:
+// OR, AND, NOT, comparisons, IS NULL and IN, + - ||, * / %, unary minus
+type parser struct {
+	src    string
+	tokens []token
+	pos    int
+
+	columns    []string
+	aggregates []Aggregate
+	seen       map[string]bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword reports whether the next token is the keyword, consuming it if so
+func (p *parser) keyword(word string) bool {
+	if tok := p.peek(); tok.kind == tokIdent && strings.EqualFold(tok.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// op reports whether the next token is one of the operators, consuming it if so
+func (p *parser) op(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(op string) error {
+	if _, ok := p.op(op); !ok {
+		return p.errorf(p.peek(), "expected %q, found %s", op, p.peek())
+	}
+	return nil
+}
+
+func (p *parser) expectKeyword(word string) error {
+	if !p.keyword(word) {
+		return p.errorf(p.peek(), "expected %s, found %s", word, p.peek())
+	}
+	return nil
+}
+
+func (p *parser) errorf(tok token, format string, args ...interface{}) error {
+	return fmt.Errorf("expression %q: %s at column %d", p.src, fmt.Sprintf(format, args...), tok.pos+1)
+}
+
+func (p *parser) parseExpr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{or: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.keyword("NOT") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if p.keyword("IS") {
+		negate := p.keyword("NOT")
+		if err := p.expectKeyword("NULL"); err != nil {
+			return nil, err
+		}
+		var n node = &isNullNode{operand: left}
+		if negate {
+			n = &notNode{operand: n}
+		}
+		return n, nil
+	}
+	start := p.pos
+	negate := p.keyword("NOT")
+	if p.keyword("IN") {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		in := &inNode{operand: left}
+		for {
+			value, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			in.values = append(in.values, value)
+			if _, ok := p.op(","); !ok {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		if negate {
+			return &notNode{operand: in}, nil
+		}
+		return in, nil
+	}
+	p.pos = start
+	if op, ok := p.op("=", "==", "!=", "<>", "<", "<=", ">", ">="); ok {
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.op("+", "-", "||")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.op("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if _, ok := p.op("-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{op: "-", left: &literalNode{value: 0}, right: operand}, nil
+	}
+	if _, ok := p.op("+"); ok {
+		return p.parseUnary()
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		value, err := parseNumber(tok.text)
+		if err != nil {
+			return nil, p.errorf(tok, "invalid number %q", tok.text)
+		}
+		return &literalNode{value: value}, nil
+	case tokString:
+		return &literalNode{value: tok.text}, nil
+	case tokOp:
+		if tok.text == "(" {
+			inner, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		}
+	case tokIdent:
+		switch strings.ToUpper(tok.text) {
+		case "TRUE":
+			return &literalNode{value: true}, nil
+		case "FALSE":
+			return &literalNode{value: false}, nil
+		case "NULL":
+			return &literalNode{value: nil}, nil
+		case "CASE":
+			return p.parseCase()
+		}
+		if _, ok := p.op("("); ok {
+			return p.parseCall(tok)
+		}
+		if strings.Contains(tok.text, ".") {
+			return nil, p.errorf(tok, "%s can only be used inside an aggregate such as sum(%s)", tok.text, tok.text)
+		}
+		if !p.seen[tok.text] {
+			p.seen[tok.text] = true
+			p.columns = append(p.columns, tok.text)
+		}
+		return &columnNode{name: tok.text}, nil
+	}
+	return nil, p.errorf(tok, "unexpected %s", tok)
+}
+
+// parseCase parses CASE [operand] WHEN ... THEN ... [ELSE ...] END
+func (p *parser) parseCase() (node, error) {
+	n := &caseNode{}
+	if tok := p.peek(); !(tok.kind == tokIdent && strings.EqualFold(tok.text, "WHEN")) {
+		operand, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		n.operand = operand
+	}
+	for p.keyword("WHEN") {
+		when, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("THEN"); err != nil {
+			return nil, err
+		}
+		then, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		n.whens = append(n.whens, when)
+		n.thens = append(n.thens, then)
+	}
+	if len(n.whens) == 0 {
+		return nil, p.errorf(p.peek(), "CASE needs at least one WHEN")
+	}
+	if p.keyword("ELSE") {
+		otherwise, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		n.otherwise = otherwise
+	}
+	return n, p.expectKeyword("END")
+}
+
+// parseCall parses the arguments of a function call; aggregates take a single table.column
+func (p *parser) parseCall(name token) (node, error) {
+	fn := strings.ToLower(name.text)
+	var args []node
+	var qualified []string
+	if _, ok := p.op(")"); !ok {
+		for {
+			if tok := p.peek(); tok.kind == tokIdent && strings.Contains(tok.text, ".") && aggregateFuncs[fn] {
+				p.next()
+				qualified = append(qualified, tok.text)
+				args = append(args, nil)
+			} else {
+				arg, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, arg)
+			}
+			if _, ok := p.op(","); ok {
+				continue
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	if len(qualified) > 0 {
+		if len(args) != 1 {
+			return nil, p.errorf(name, "%s over a child table takes a single table.column argument", fn)
+		}
+		dot := strings.LastIndex(qualified[0], ".")
+		agg := Aggregate{Func: fn, Table: qualified[0][:dot], Column: qualified[0][dot+1:]}
+		p.aggregates = append(p.aggregates, agg)
+		return &aggregateNode{aggregate: agg}, nil
+	}
+
+	spec, ok := functions[fn]
+	if !ok {
+		return nil, p.errorf(name, "unknown function %s", name.text)
+	}
+	if len(args) < spec.minArgs || (spec.maxArgs >= 0 && len(args) > spec.maxArgs) {
+		return nil, p.errorf(name, "%s takes %s", fn, spec.arity())
+	}
+	return &callNode{fn: spec.fn, args: args}, nil
+}
@@ -0,0 +1,106 @@
+package generator
+
+import (
+	"go-fake/internal/schema"
+	"go-fake/pkg/logger"
+	"strings"
+)
+
+// parentAssignment fixes which parent row each generated child row references
+type parentAssignment struct {
+	field   string // foreign key field in the child table
+	parents []int  // parent row index for each child row
+}
+
+// isOneToOne reports whether a relationship gives every parent exactly one child
+func isOneToOne(rel schema.Relationship) bool {
+	return rel.Cardinality == "1:1" || strings.EqualFold(rel.Type, "one_to_one")
+}
+
+// drivesRowCount reports whether a relationship determines the row count of its from_table
+func drivesRowCount(rel schema.Relationship) bool {
+	return isOneToOne(rel) || rel.MinCount != nil || rel.MaxCount != nil
+}
+
+// planRowCount decides how many rows a table gets. A table's own row count
+// overrides the global one; a relationship with a 1:1 cardinality or a
+// min/max count per parent derives the count from the generated parent rows
+// and also fixes which parent each row belongs to. The junction table of a
+// many_to_many relationship gets one row per linked pair.
+func planRowCount(seed int64, table schema.Table, defaultRows int, relationships []schema.Relationship, relData *RelationshipData) (int, []*parentAssignment, error) {
+	numRows := defaultRows
+	if table.Rows != nil {
+		numRows = *table.Rows
+	}
+
+	for _, rel := range relationships {
+		if name, fromColumn, toColumn := junctionTable(rel); isManyToMany(rel) && name == table.Name {
+			return planJunctionRows(seed, table, rel, fromColumn, toColumn, relData)
+		}
+	}
+
+	for _, rel := range relationships {
+		if rel.FromTable != table.Name || rel.ToTable == table.Name || isManyToMany(rel) || !drivesRowCount(rel) {
+			continue
+		}
+		parents := len(relData.TableData[rel.ToTable])
+		if parents == 0 {
+			logger.Debug("Table %s: no %s rows to derive the row count from", table.Name, rel.ToTable)
+			continue
+		}
+		if table.Rows != nil {
+			logger.Debug("Table %s: row count comes from its relationship with %s, ignoring rows=%d", table.Name, rel.ToTable, *table.Rows)
+		}
+
+		r := deriveRand(seed, table.Name+"."+rel.FromField+":cardinality", 0)
+		assignment := &parentAssignment{field: rel.FromField}
+
+		if isOneToOne(rel) {
+			assignment.parents = r.Perm(parents)
+		} else {
+			minCount, maxCount := 0, 0
+			if rel.MinCount != nil {
+				minCount = *rel.MinCount
+			}
+			if rel.MaxCount != nil {
+				maxCount = *rel.MaxCount
+			}
+			if maxCount < minCount {
+				maxCount = minCount
+			}
+			for parent := 0; parent < parents; parent++ {
+				children := minCount + r.IntN(maxCount-minCount+1)
+				for i := 0; i < children; i++ {
+					assignment.parents = append(assignment.parents, parent)
+				}
+			}
+		}
+
+		logger.Debug("Table %s: %d rows derived from %d %s rows", table.Name, len(assignment.parents), parents, rel.ToTable)
+		return len(assignment.parents), []*parentAssignment{assignment}, nil
+	}
+
+	return numRows, nil, nil
+}
+
+// planJunctionRows links the rows of both sides of a many_to_many relationship
+func planJunctionRows(seed int64, table schema.Table, rel schema.Relationship, fromColumn, toColumn string, relData *RelationshipData) (int, []*parentAssignment, error) {
+	if table.Rows != nil {
+		logger.Debug("Table %s: row count comes from the %s <-> %s links, ignoring rows=%d", table.Name, rel.FromTable, rel.ToTable, *table.Rows)
+	}
+
+	r := deriveRand(seed, table.Name+":links", 0)
+	pairs, err := planLinks(r, rel, len(relData.TableData[rel.FromTable]), len(relData.TableData[rel.ToTable]))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	from := &parentAssignment{field: fromColumn, parents: make([]int, len(pairs))}
+	to := &parentAssignment{field: toColumn, parents: make([]int, len(pairs))}
+	for i, pair := range pairs {
+		from.parents[i], to.parents[i] = pair[0], pair[1]
+	}
+
+	logger.Debug("Table %s: %d links between %s and %s", table.Name, len(pairs), rel.FromTable, rel.ToTable)
+	return len(pairs), []*parentAssignment{from, to}, nil
+}
@@ -0,0 +1,183 @@
+package generator
+
+import (
+	"go-fake/internal/expr"
+	"go-fake/internal/schema"
+	"go-fake/pkg/logger"
+	"math"
+	"strings"
+)
+
+// computedField evaluates the expression of a computed field
+type computedField struct {
+	expr       *expr.Expr
+	aggregated bool           // reads child rows, so it is filled once every table is generated
+	integer    bool           // integer column: results are rounded to whole numbers
+	decimal    *decimalFormat // precision and scale of numeric results
+}
+
+// newComputedField compiles the expression of a field, or returns nil when it
+// has none. Invalid expressions are ignored here; schema validation reports them.
+func newComputedField(field schema.Field) *computedField {
+	if field.Expression == "" {
+		return nil
+	}
+	e, err := expr.Parse(field.Expression)
+	if err != nil {
+		logger.Debug("Field %s: ignoring expression: %v", field.Name, err)
+		return nil
+	}
+	return &computedField{
+		expr:       e,
+		aggregated: len(e.Aggregates()) > 0,
+		integer:    numericTypes[strings.ToLower(field.Type)],
+		decimal:    newDecimalFormat(field),
+	}
+}
+
+// value evaluates the expression for a row and fits the result to the column
+func (c *computedField) value(row map[string]interface{}, aggregate func(expr.Aggregate) interface{}) interface{} {
+	value := c.expr.Eval(expr.Env{Row: row, Aggregate: aggregate})
+	if value == nil {
+		return nil
+	}
+	if c.decimal != nil {
+		return c.decimal.apply(value)
+	}
+	if c.integer {
+		if n, ok := expr.ToNumber(value); ok {
+			if f, isFloat := n.(float64); isFloat {
+				return int(math.Round(f))
+			}
+			return n
+		}
+	}
+	return value
+}
+
+// fillAggregates computes the fields that aggregate child rows, such as
+// sum(order_items.total), once every table has been generated, together with
+// the fields of the same table that read them, in dependency order. Tables are
+// filled children first, so aggregates of aggregates see final values.
+func fillAggregates(seed int64, inference *FieldTypeInference, plan *generationPlan, relData *RelationshipData) {
+	for level := len(plan.levels) - 1; level >= 0; level-- {
+		for _, table := range plan.levels[level] {
+			var pending []*fieldGenerator
+			for _, fg := range compileTable(seed, inference, nil, table, plan).order {
+				if fg.afterAggregates {
+					pending = append(pending, fg)
+				}
+			}
+			if len(pending) == 0 {
+				continue
+			}
+
+			children := make(map[string]map[interface{}][]map[string]interface{})
+			aggregate := func(row map[string]interface{}) func(expr.Aggregate) interface{} {
+				return func(a expr.Aggregate) interface{} {
+					fk, key := plan.childForeignKey(a.Table, table.Name)
+					if _, done := children[a.Table]; !done && fk != "" {
+						children[a.Table] = groupRows(relData.TableData[a.Table], fk)
+					}
+					groups := children[a.Table]
+					if groups == nil {
+						return nil
+					}
+					return aggregateRows(a, groups[row[key]])
+				}
+			}
+
+			r := deriveRand(seed, table.Name+":aggregates", 0)
+			for i, row := range relData.TableData[table.Name] {
+				for _, fg := range pending {
+					row[fg.field.Name] = fg.produce(r, i, row, relData, aggregate(row))
+				}
+			}
+			for _, fg := range pending {
+				logger.Debug("Computed field %s.%s from aggregates of child rows", table.Name, fg.field.Name)
+			}
+		}
+	}
+}
+
+// childForeignKey returns the column of a child table that references the
+// parent table, and the referenced column of the parent
+func (p *generationPlan) childForeignKey(child, parent string) (string, string) {
+	for _, table := range p.tables {
+		if table.Name != child {
+			continue
+		}
+		for _, field := range table.Fields {
+			if c := field.Constraints; c != nil && c.References != nil && c.References.Table == parent {
+				return field.Name, c.References.Field
+			}
+		}
+	}
+	return "", ""
+}
+
+// groupRows groups rows by the value of a column, skipping nulls
+func groupRows(rows []map[string]interface{}, column string) map[interface{}][]map[string]interface{} {
+	groups := make(map[interface{}][]map[string]interface{})
+	for _, row := range rows {
+		if key := row[column]; key != nil {
+			groups[key] = append(groups[key], row)
+		}
+	}
+	return groups
+}
+
+// aggregateRows applies an aggregate to a column of some child rows. Nulls
+// are skipped; sum and count of no rows are 0, avg, min and max are null.
+func aggregateRows(a expr.Aggregate, rows []map[string]interface{}) interface{} {
+	count, intSum := 0, 0
+	floatSum, allInts := 0.0, true
+	var min, max interface{}
+	var minValue, maxValue float64
+	for _, row := range rows {
+		value := row[a.Column]
+		if value == nil {
+			continue
+		}
+		count++
+		n, ok := expr.ToNumber(value)
+		if !ok {
+			continue
+		}
+		f := 0.0
+		if i, isInt := n.(int); isInt {
+			intSum += i
+			f = float64(i)
+		} else {
+			allInts = false
+			f = n.(float64)
+		}
+		floatSum += f
+		if min == nil || f < minValue {
+			min, minValue = n, f
+		}
+		if max == nil || f > maxValue {
+			max, maxValue = n, f
+		}
+	}
+
+	switch a.Func {
+	case "count":
+		return count
+	case "sum":
+		if allInts {
+			return intSum
+		}
+		return floatSum
+	case "avg":
+		if count == 0 {
+			return nil
+		}
+		return floatSum / float64(count)
+	case "min":
+		return min
+	case "max":
+		return max
+	}
+	return nil
+}
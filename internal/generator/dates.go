@@ -0,0 +1,90 @@
+package generator
+
+import (
+	"go-fake/internal/schema"
+	"go-fake/pkg/faker"
+	"go-fake/pkg/logger"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// timeRange generates the dates or timestamps of a field within its
+// min_date/max_date bounds, in its time zone and output format
+type timeRange struct {
+	min, max time.Time
+	date     bool // whole days rather than instants
+	layout   string
+	loc      *time.Location
+}
+
+// newTimeRange compiles the date bounds, format and time zone of a field. It
+// returns nil for fields that set none of them, which keep the default
+// generators, and for invalid settings, which schema validation reports.
+func newTimeRange(field schema.Field, inferredType string) *timeRange {
+	c := field.Constraints
+	bounded := c != nil && (c.MinDate != "" || c.MaxDate != "")
+	if !bounded && field.Format == "" && field.Timezone == "" {
+		return nil
+	}
+
+	tr := &timeRange{date: inferredType == "date", layout: field.Format, loc: time.UTC}
+	if inferredType != "date" && inferredType != "datetime" {
+		if !bounded {
+			return nil
+		}
+		tr.date = strings.EqualFold(field.Type, "date")
+	}
+	if tr.layout == "" && tr.date {
+		tr.layout = "date"
+	}
+
+	if field.Timezone != "" {
+		loc, err := time.LoadLocation(field.Timezone)
+		if err != nil {
+			logger.Debug("Field %s: ignoring date settings: %v", field.Name, err)
+			return nil
+		}
+		tr.loc = loc
+	}
+
+	ref := faker.ReferenceTime()
+	tr.min, tr.max = faker.DefaultDateTimeStart, ref
+	if tr.date {
+		tr.min = faker.DefaultDateStart
+	}
+	var minSet, maxSet bool
+	if c != nil && c.MinDate != "" {
+		t, err := faker.ParseTimeBound(c.MinDate, ref, tr.loc)
+		if err != nil {
+			logger.Debug("Field %s: ignoring date settings: %v", field.Name, err)
+			return nil
+		}
+		tr.min, minSet = t, true
+	}
+	if c != nil && c.MaxDate != "" {
+		t, err := faker.ParseTimeBound(c.MaxDate, ref, tr.loc)
+		if err != nil {
+			logger.Debug("Field %s: ignoring date settings: %v", field.Name, err)
+			return nil
+		}
+		tr.max, maxSet = t, true
+	}
+
+	// A single bound beyond the default window gets a one-year window
+	if minSet && !maxSet && tr.min.After(tr.max) {
+		tr.max = tr.min.AddDate(1, 0, 0)
+	}
+	if maxSet && !minSet && tr.max.Before(tr.min) {
+		tr.min = tr.max.AddDate(-1, 0, 0)
+	}
+	return tr
+}
+
+// generate returns a random date or timestamp within the range, formatted
+func (tr *timeRange) generate(r *rand.Rand) interface{} {
+	if tr.date {
+		return faker.FormatTime(faker.GenerateDateBetween(r, tr.min.In(tr.loc), tr.max), tr.layout)
+	}
+	return faker.FormatTime(faker.GenerateTimeBetween(r, tr.min, tr.max).In(tr.loc), tr.layout)
+}
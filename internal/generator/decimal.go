@@ -0,0 +1,85 @@
+package generator
+
+import (
+	"encoding/json"
+	"go-fake/internal/schema"
+	"math"
+	"strconv"
+)
+
+// decimalFormat fits numeric values to the precision and scale of a field,
+// e.g. DECIMAL(10,2): at most 10 digits, 2 of them after the decimal point
+type decimalFormat struct {
+	scale    int      // digits after the decimal point
+	limit    float64  // largest absolute value the precision allows, 0 when unbounded
+	min, max *float64 // min_value and max_value
+}
+
+// newDecimalFormat compiles the precision and scale of a field, or returns nil
+// when it sets neither. As in SQL, a precision without a scale means scale 0.
+func newDecimalFormat(field schema.Field) *decimalFormat {
+	if field.Precision == nil && field.Scale == nil {
+		return nil
+	}
+	f := &decimalFormat{}
+	if field.Scale != nil {
+		f.scale = *field.Scale
+	}
+	if field.Precision != nil {
+		f.limit = math.Pow10(*field.Precision-f.scale) - math.Pow10(-f.scale)
+	}
+	f.min, f.max = numericBounds(field)
+	return f
+}
+
+// apply rounds a numeric value to the scale and clamps it to the precision
+// and the min/max bounds. Values with a scale are returned as json.Number so
+// both JSON and CSV keep their trailing zeros (12.50). Non-numeric values are
+// returned unchanged.
+func (f *decimalFormat) apply(value interface{}) interface{} {
+	v, ok := toFloat(value)
+	if !ok {
+		return value
+	}
+
+	if f.limit > 0 {
+		v = math.Max(-f.limit, math.Min(f.limit, v))
+	}
+	if f.min != nil && v < *f.min {
+		v = *f.min
+	}
+	if f.max != nil && v > *f.max {
+		v = *f.max
+	}
+
+	unit := math.Pow10(f.scale)
+	rounded := math.Round(v*unit) / unit
+	// Rounding must not step outside the bounds: 99.996 with max 99.99 is 99.99
+	if f.max != nil && rounded > *f.max {
+		rounded = math.Floor(*f.max*unit) / unit
+	}
+	if f.min != nil && rounded < *f.min {
+		rounded = math.Ceil(*f.min*unit) / unit
+	}
+	return json.Number(strconv.FormatFloat(rounded, 'f', f.scale, 64))
+}
+
+// toFloat converts a generated numeric value, including numeric strings such
+// as formatted prices, to a float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
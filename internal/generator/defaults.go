@@ -0,0 +1,148 @@
+package generator
+
+import (
+	"fmt"
+	"go-fake/internal/schema"
+	"go-fake/pkg/logger"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"unicode/utf8"
+)
+
+// columnDefault is the DEFAULT of a column, which a share of the rows take
+// instead of a generated value
+type columnDefault struct {
+	ratio    float64
+	value    interface{}     // fixed default value
+	function *fieldGenerator // values of a function default such as now()
+}
+
+// defaultRatio returns the share of rows given a field's default: all of them
+// unless the field or schema sets default_ratio. Keys, foreign keys and
+// computed fields keep their own values.
+func defaultRatio(field schema.Field) float64 {
+	if field.Default == nil || field.PrimaryKey || field.Unique || field.AutoIncrement || field.Expression != "" {
+		return 0
+	}
+	if field.Constraints != nil && field.Constraints.References != nil {
+		return 0
+	}
+	if field.DefaultRatio == nil {
+		return 1
+	}
+	return *field.DefaultRatio
+}
+
+// newColumnDefault compiles the default of a field, or returns nil when no
+// row takes it. Function defaults generate values like a field of their own:
+// now() timestamps up to the current time, CURRENT_DATE dates up to today and
+// gen_random_uuid() UUIDs.
+func newColumnDefault(seed int64, inference *FieldTypeInference, tableName string, field schema.Field, inferredType string, plan *generationPlan) *columnDefault {
+	ratio := defaultRatio(field)
+	if ratio <= 0 {
+		return nil
+	}
+	d := &columnDefault{ratio: ratio, value: field.Default.Value}
+	if field.Default.Function == "" {
+		return d
+	}
+
+	generated := schema.Field{Name: field.Name, Type: field.Type, Format: field.Format, Timezone: field.Timezone}
+	switch field.Default.Function {
+	case "uuid":
+		generated.Type = "uuid"
+	case "now", "today":
+		generated.Constraints = &schema.Constraint{MaxDate: field.Default.Function}
+		if field.Constraints != nil {
+			generated.Constraints.MinDate = field.Constraints.MinDate
+		}
+		if field.Default.Function == "today" {
+			generated.Type = "date"
+		} else if inferredType != "date" && inferredType != "datetime" {
+			generated.Type = "timestamp"
+		}
+	}
+	d.function = compileField(seed, inference, nil, tableName, generated, false, plan)
+	return d
+}
+
+// generate returns the default value of the column for one row
+func (d *columnDefault) generate(r *rand.Rand) interface{} {
+	if d.function != nil {
+		return d.function.generateValue(r)
+	}
+	return d.value
+}
+
+// withCheckedDefaults returns the schema without the fixed column defaults that
+// break the constraints of their field, such as DEFAULT 0 with CHECK (qty > 0),
+// logging a warning for each. The input is not modified.
+func withCheckedDefaults(s schema.Schema) schema.Schema {
+	check := func(prefix string, fields []schema.Field) []schema.Field {
+		var result []schema.Field
+		for i, field := range fields {
+			if field.Default == nil || field.Default.Function != "" {
+				continue
+			}
+			if err := checkDefault(field); err != nil {
+				logger.Warn("Ignoring DEFAULT %v of column %s%s: %v", field.Default.Value, prefix, field.Name, err)
+				if result == nil {
+					result = append([]schema.Field(nil), fields...)
+				}
+				result[i].Default = nil
+			}
+		}
+		if result == nil {
+			return fields
+		}
+		return result
+	}
+
+	tables := make([]schema.Table, len(s.Tables))
+	for i, table := range s.Tables {
+		tables[i] = table
+		tables[i].Fields = check(table.Name+".", table.Fields)
+	}
+	s.Tables = tables
+	s.Fields = check("", s.Fields)
+	return s
+}
+
+// checkDefault reports why the fixed default of a field breaks its min/max
+// value, enum, pattern or length constraints, or returns nil
+func checkDefault(field schema.Field) error {
+	value := field.Default.Value
+	text, isString := value.(string)
+	if isString {
+		n := utf8.RuneCountInString(text)
+		if field.MinLength != nil && n < *field.MinLength {
+			return fmt.Errorf("shorter than min_length %d", *field.MinLength)
+		}
+		if field.MaxLength != nil && n > *field.MaxLength {
+			return fmt.Errorf("longer than max_length %d", *field.MaxLength)
+		}
+	}
+
+	c := field.Constraints
+	if c == nil {
+		return nil
+	}
+	if n, ok := toFloat(value); ok {
+		if c.MinValue != nil && n < *c.MinValue {
+			return fmt.Errorf("below min_value %g", *c.MinValue)
+		}
+		if c.MaxValue != nil && n > *c.MaxValue {
+			return fmt.Errorf("above max_value %g", *c.MaxValue)
+		}
+	}
+	if c.Enum != nil && !slices.ContainsFunc(c.Enum.Values, func(v interface{}) bool { return fmt.Sprint(v) == fmt.Sprint(value) }) {
+		return fmt.Errorf("not one of the enum values %v", c.Enum.Values)
+	}
+	if c.Pattern != "" && isString {
+		if re, err := regexp.Compile(`^(?:` + c.Pattern + `)$`); err == nil && !re.MatchString(text) {
+			return fmt.Errorf("does not match pattern %s", c.Pattern)
+		}
+	}
+	return nil
+}
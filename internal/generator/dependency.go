@@ -0,0 +1,475 @@
+package generator
+
+import (
+	"fmt"
+	"go-fake/internal/schema"
+	"go-fake/pkg/logger"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+)
+
+// defaultRootFraction is the share of rows without a parent in a self-referencing table
+const defaultRootFraction = 0.1
+
+// generationPlan describes the order in which tables are generated and which
+// foreign key columns have to wait until every table exists.
+type generationPlan struct {
+	tables        []schema.Table // tables with relationship foreign keys applied to their fields
+	relationships []schema.Relationship
+	levels        [][]schema.Table
+	deferred      map[string]map[string]bool // table -> nullable FK fields filled in a second pass
+}
+
+// isDeferred reports whether a field is left empty during generation and filled afterwards
+func (p *generationPlan) isDeferred(tableName, fieldName string) bool {
+	return p != nil && p.deferred[tableName][fieldName]
+}
+
+// dependencyGraph maps each table to the tables it must be generated after,
+// remembering which fields introduced each edge.
+type dependencyGraph struct {
+	edges map[string][]string
+	via   map[string][]string // "from->to" -> fields of the from table
+}
+
+// buildDependencyGraph collects the edges from field references and from foreign
+// key relationships declared on the schema. References to unknown tables are ignored.
+func buildDependencyGraph(tables []schema.Table, relationships []schema.Relationship) *dependencyGraph {
+	known := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		known[table.Name] = true
+	}
+
+	graph := &dependencyGraph{
+		edges: make(map[string][]string, len(tables)),
+		via:   make(map[string][]string),
+	}
+	addEdge := func(from, to, field string) {
+		if !known[to] {
+			logger.Debug("Table %s references unknown table %s, ignoring dependency", from, to)
+			return
+		}
+		// Self-references do not constrain the order between tables
+		if from == to {
+			return
+		}
+		key := from + "->" + to
+		if _, exists := graph.via[key]; !exists {
+			graph.edges[from] = append(graph.edges[from], to)
+		}
+		for _, existing := range graph.via[key] {
+			if existing == field {
+				return
+			}
+		}
+		graph.via[key] = append(graph.via[key], field)
+	}
+
+	for _, table := range tables {
+		graph.edges[table.Name] = nil
+		for _, field := range table.Fields {
+			if field.Constraints != nil && field.Constraints.References != nil {
+				addEdge(table.Name, field.Constraints.References.Table, field.Name)
+			}
+		}
+		for _, fk := range table.ForeignKeys {
+			for _, field := range fk.Fields {
+				addEdge(table.Name, fk.ToTable, field)
+			}
+		}
+	}
+
+	for _, rel := range relationships {
+		if isManyToMany(rel) || !known[rel.FromTable] {
+			continue
+		}
+		addEdge(rel.FromTable, rel.ToTable, rel.FromField)
+	}
+
+	return graph
+}
+
+// applyRelationshipReferences returns the tables with foreign key relationships
+// declared on the schema copied onto their fields as references, so that
+// generation only has to look at field constraints. The input is not modified.
+func applyRelationshipReferences(tables []schema.Table, relationships []schema.Relationship) []schema.Table {
+	if len(relationships) == 0 {
+		return tables
+	}
+
+	result := make([]schema.Table, len(tables))
+	for i, table := range tables {
+		result[i] = table
+		result[i].Fields = append([]schema.Field(nil), table.Fields...)
+		for j, field := range result[i].Fields {
+			if field.Constraints != nil && field.Constraints.References != nil {
+				continue
+			}
+			for _, rel := range relationships {
+				if isManyToMany(rel) || rel.FromTable != table.Name || rel.FromField != field.Name {
+					continue
+				}
+				constraints := schema.Constraint{}
+				if field.Constraints != nil {
+					constraints = *field.Constraints
+				}
+				constraints.References = &schema.Reference{Table: rel.ToTable, Field: rel.ToField}
+				result[i].Fields[j].Constraints = &constraints
+				break
+			}
+		}
+	}
+	return result
+}
+
+// removeEdge drops the dependency of one table on another
+func (g *dependencyGraph) removeEdge(from, to string) {
+	deps := g.edges[from]
+	for i, dep := range deps {
+		if dep == to {
+			g.edges[from] = append(deps[:i:i], deps[i+1:]...)
+			break
+		}
+	}
+	delete(g.via, from+"->"+to)
+}
+
+// planGeneration orders tables into dependency levels. Cycles are broken by
+// deferring nullable foreign key columns to a fill pass that runs once every
+// table exists; a cycle made only of required foreign keys is an error.
+func planGeneration(tables []schema.Table, relationships []schema.Relationship) (*generationPlan, error) {
+	tables = applyRelationshipReferences(withJunctionTables(tables, relationships), relationships)
+	graph := buildDependencyGraph(tables, relationships)
+	plan := &generationPlan{tables: tables, relationships: relationships, deferred: make(map[string]map[string]bool)}
+
+	fieldsByTable := make(map[string]map[string]schema.Field, len(tables))
+	for _, table := range tables {
+		fieldsByTable[table.Name] = make(map[string]schema.Field, len(table.Fields))
+		for _, field := range table.Fields {
+			fieldsByTable[table.Name][field.Name] = field
+		}
+	}
+
+	for {
+		levels, cycle := topologicalLevels(tables, graph)
+		if cycle == nil {
+			plan.levels = levels
+			return plan, nil
+		}
+
+		broken := false
+		for i := 0; i+1 < len(cycle) && !broken; i++ {
+			from, to := cycle[i], cycle[i+1]
+			fields := graph.via[from+"->"+to]
+			nullable := true
+			for _, name := range fields {
+				if field, exists := fieldsByTable[from][name]; !exists || field.Required {
+					nullable = false
+					break
+				}
+			}
+			if !nullable {
+				continue
+			}
+
+			logger.Debug("Breaking dependency cycle %s by filling %s.%s after all tables are generated",
+				strings.Join(cycle, " -> "), from, strings.Join(fields, ", "))
+			if plan.deferred[from] == nil {
+				plan.deferred[from] = make(map[string]bool)
+			}
+			for _, name := range fields {
+				plan.deferred[from][name] = true
+			}
+			graph.removeEdge(from, to)
+			broken = true
+		}
+
+		if !broken {
+			return nil, fmt.Errorf("foreign key dependency cycle between tables: %s (make one of these foreign keys nullable to break it)", strings.Join(cycle, " -> "))
+		}
+	}
+}
+
+// topologicalLevels groups tables into levels so that every table comes after
+// all the tables it references. Tables within a level keep their schema order
+// and do not depend on each other, so a level can be generated in parallel.
+// When the graph has a cycle, one cycle is returned instead.
+func topologicalLevels(tables []schema.Table, graph *dependencyGraph) ([][]schema.Table, []string) {
+	done := make(map[string]bool, len(tables))
+	var levels [][]schema.Table
+
+	for len(done) < len(tables) {
+		var level []schema.Table
+		for _, table := range tables {
+			if done[table.Name] {
+				continue
+			}
+			ready := true
+			for _, dep := range graph.edges[table.Name] {
+				if !done[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				level = append(level, table)
+			}
+		}
+
+		if len(level) == 0 {
+			return nil, findCycle(tables, graph.edges, done)
+		}
+
+		for _, table := range level {
+			done[table.Name] = true
+		}
+		levels = append(levels, level)
+	}
+
+	return levels, nil
+}
+
+// findCycle returns one dependency cycle among the tables not yet generated,
+// with the first table repeated at the end (e.g. a -> b -> a).
+func findCycle(tables []schema.Table, graph map[string][]string, done map[string]bool) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var path []string
+	var cycle []string
+
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range graph[name] {
+			if done[dep] {
+				continue
+			}
+			switch state[dep] {
+			case visiting:
+				for i, step := range path {
+					if step == dep {
+						cycle = append(append([]string{}, path[i:]...), dep)
+						return true
+					}
+				}
+			case unvisited:
+				if visit(dep) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return false
+	}
+
+	for _, table := range tables {
+		if !done[table.Name] && state[table.Name] == unvisited && visit(table.Name) {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// isSelfReference reports whether a field is a foreign key to its own table
+func isSelfReference(tableName string, field schema.Field) bool {
+	return field.Constraints != nil && field.Constraints.References != nil && field.Constraints.References.Table == tableName
+}
+
+// isSelfForeignKey reports whether a composite foreign key references its own table
+func isSelfForeignKey(tableName string, fk schema.ForeignKey) bool {
+	return fk.ToTable == tableName
+}
+
+// fillSelfReferences turns self-referencing foreign keys into a tree. Rows only
+// point at earlier rows, so the result never contains a cycle. Roots get a null
+// parent, or reference themselves when the column is required.
+func fillSelfReferences(seed int64, table schema.Table, rows []map[string]interface{}) {
+	for _, field := range table.Fields {
+		if !isSelfReference(table.Name, field) {
+			continue
+		}
+		r := deriveRand(seed, table.Name+"."+field.Name, 0)
+		fillTree(r, rows, []string{field.Name}, []string{field.Constraints.References.Field}, field.Required, field.Constraints.Hierarchy)
+		logger.Debug("Generated hierarchy for %s.%s", table.Name, field.Name)
+	}
+
+	for _, fk := range table.ForeignKeys {
+		if !isSelfForeignKey(table.Name, fk) || len(fk.Fields) != len(fk.ToFields) {
+			continue
+		}
+		required := true
+		for _, field := range table.Fields {
+			for _, name := range fk.Fields {
+				if field.Name == name && !field.Required {
+					required = false
+				}
+			}
+		}
+		key := strings.Join(fk.Fields, ",")
+		fillTree(deriveRand(seed, table.Name+"."+key, 0), rows, fk.Fields, fk.ToFields, required, nil)
+		logger.Debug("Generated hierarchy for %s.(%s)", table.Name, key)
+	}
+}
+
+// fillTree copies the toFields of an earlier parent row into the fields of
+// every row, shaping the rows into a tree as described by the hierarchy
+func fillTree(r *rand.Rand, rows []map[string]interface{}, fields, toFields []string, required bool, hierarchy *schema.Hierarchy) {
+	rootFraction := defaultRootFraction
+	maxDepth := 0
+	if hierarchy != nil {
+		if hierarchy.RootFraction > 0 {
+			rootFraction = math.Min(hierarchy.RootFraction, 1)
+		}
+		maxDepth = hierarchy.MaxDepth
+	}
+
+	depths := make([]int, len(rows))
+	var candidates []int // earlier rows that can still take children
+	for i, row := range rows {
+		isRoot := i == 0 || r.Float64() < rootFraction || len(candidates) == 0
+		if isRoot {
+			for j, name := range fields {
+				if required {
+					row[name] = row[toFields[j]]
+				} else {
+					row[name] = nil
+				}
+			}
+			depths[i] = 1
+		} else {
+			parent := candidates[r.IntN(len(candidates))]
+			for j, name := range fields {
+				row[name] = rows[parent][toFields[j]]
+			}
+			depths[i] = depths[parent] + 1
+		}
+		if maxDepth <= 0 || depths[i] < maxDepth {
+			candidates = append(candidates, i)
+		}
+	}
+}
+
+// fillDeferredReferences fills the foreign key columns that were deferred to
+// break dependency cycles, now that every referenced table has been generated.
+// Unique and 1:1 foreign keys take distinct parent values, drawn from a
+// permutation; there must be at least as many parents as rows to fill.
+func fillDeferredReferences(seed int64, plan *generationPlan, relData *RelationshipData) error {
+	for _, table := range plan.tables {
+		if len(plan.deferred[table.Name]) == 0 {
+			continue
+		}
+		rows := relData.TableData[table.Name]
+		for _, field := range table.Fields {
+			if !plan.isDeferred(table.Name, field.Name) {
+				continue
+			}
+			if field.Constraints == nil || field.Constraints.References == nil {
+				continue
+			}
+			ref := field.Constraints.References
+			values := relData.References[ref.Table+"."+ref.Field]
+			if len(values) == 0 {
+				continue
+			}
+			r := deriveRand(seed, table.Name+"."+field.Name, 0)
+			ratio := nullRatio(field)
+			var perm []int
+			if isUnique(field) || plan.isOneToOne(table.Name, field.Name) {
+				perm = r.Perm(len(values))
+			}
+			next := 0
+			for _, row := range rows {
+				if ratio > 0 && r.Float64() < ratio {
+					row[field.Name] = nil
+					continue
+				}
+				if perm == nil {
+					row[field.Name] = values[r.IntN(len(values))]
+					continue
+				}
+				if next == len(perm) {
+					return fmt.Errorf("table %s: unique foreign key %s needs more distinct %s.%s values than the %d available",
+						table.Name, field.Name, ref.Table, ref.Field, len(values))
+				}
+				row[field.Name] = values[perm[next]]
+				next++
+			}
+			logger.Debug("Filled deferred foreign key %s.%s", table.Name, field.Name)
+		}
+		for _, fk := range table.ForeignKeys {
+			parents := relData.TableData[fk.ToTable]
+			if len(fk.Fields) == 0 || !plan.isDeferred(table.Name, fk.Fields[0]) || len(parents) == 0 {
+				continue
+			}
+			key := strings.Join(fk.Fields, ",")
+			r := deriveRand(seed, table.Name+"."+key, 0)
+			ratio := keyNullRatio(table, fk.Fields)
+			var perm []int
+			if isUniqueKey(table, fk.Fields) {
+				perm = r.Perm(len(parents))
+			}
+			next := 0
+			for _, row := range rows {
+				if ratio > 0 && r.Float64() < ratio {
+					continue
+				}
+				if perm == nil {
+					copyParentKey(row, parents[r.IntN(len(parents))], fk)
+					continue
+				}
+				if next == len(perm) {
+					return fmt.Errorf("table %s: unique foreign key (%s) needs more distinct %s rows than the %d available",
+						table.Name, strings.Join(fk.Fields, ", "), fk.ToTable, len(parents))
+				}
+				copyParentKey(row, parents[perm[next]], fk)
+				next++
+			}
+			logger.Debug("Filled deferred foreign key %s.(%s)", table.Name, key)
+		}
+		populateReferences(table.Name, table.Fields, rows, relData)
+	}
+	return nil
+}
+
+// isOneToOne reports whether a foreign key field belongs to a 1:1 relationship
+func (p *generationPlan) isOneToOne(tableName, fieldName string) bool {
+	for _, rel := range p.relationships {
+		if rel.FromTable == tableName && rel.FromField == fieldName && isOneToOne(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// isUniqueKey reports whether a set of columns is the primary key or a unique
+// key of a table, in any order
+func isUniqueKey(table schema.Table, fields []string) bool {
+	sameColumns := func(key []string) bool {
+		if len(key) != len(fields) {
+			return false
+		}
+		for _, name := range key {
+			if !slices.Contains(fields, name) {
+				return false
+			}
+		}
+		return true
+	}
+	if sameColumns(table.PrimaryKey) {
+		return true
+	}
+	for _, key := range table.UniqueKeys {
+		if sameColumns(key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,292 @@
+package generator
+
+import (
+	"go-fake/internal/schema"
+	"go-fake/pkg/faker"
+	"go-fake/pkg/logger"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default distances of a depends_on value from the column it depends on
+const (
+	defaultMaxTimeOffset   = "30d"
+	defaultMaxNumberOffset = "100"
+)
+
+// fieldDependency derives a value from another column of the same row, or of
+// the parent row a foreign key points to, at a random distance after or before it
+type fieldDependency struct {
+	base                 *fieldGenerator // column of the row, the foreign key for parent dependencies
+	parent               *parentLookup   // nil for columns of the same row
+	back                 bool            // before rather than after the base value
+	kind                 string          // date, datetime or number
+	minOffset, maxOffset string
+}
+
+// parentLookup finds the row of a referenced table a foreign key value points to
+type parentLookup struct {
+	table, key string          // referenced table and column
+	column     *fieldGenerator // column of the parent row the value depends on, for its format
+
+	once sync.Once
+	rows map[interface{}]map[string]interface{}
+}
+
+// row returns the parent row with the key value, indexing the referenced
+// table on first use. The referenced table is complete by then, since
+// tables are generated after the tables they reference.
+func (p *parentLookup) row(key interface{}, relData *RelationshipData) map[string]interface{} {
+	if relData == nil {
+		return nil
+	}
+	p.once.Do(func() {
+		parents := relData.TableData[p.table]
+		p.rows = make(map[interface{}]map[string]interface{}, len(parents))
+		for _, parent := range parents {
+			if value := parent[p.key]; value != nil {
+				if _, exists := p.rows[value]; !exists {
+					p.rows[value] = parent
+				}
+			}
+		}
+	})
+	return p.rows[key]
+}
+
+// findField returns a column of a table of the generation plan
+func (p *generationPlan) findField(tableName, fieldName string) (schema.Field, bool) {
+	if p != nil {
+		for _, table := range p.tables {
+			if table.Name != tableName {
+				continue
+			}
+			for _, field := range table.Fields {
+				if field.Name == fieldName {
+					return field, true
+				}
+			}
+		}
+	}
+	return schema.Field{}, false
+}
+
+// dependencyKind returns how a field's values are offset, or "" when its type cannot be
+func dependencyKind(inferredType string) string {
+	if inferredType == "date" || inferredType == "datetime" {
+		return inferredType
+	}
+	if _, numeric := numericTypes[inferredType]; numeric {
+		return "number"
+	}
+	return ""
+}
+
+// compileDependency resolves the depends_on constraint of a field against the
+// other columns of its table. Invalid dependencies are ignored here; schema
+// validation reports them.
+func (tg *tableGenerator) compileDependency(fg *fieldGenerator, plan *generationPlan) {
+	if fg.field.Constraints == nil || fg.field.Constraints.DependsOn == nil {
+		return
+	}
+	dep := fg.field.Constraints.DependsOn
+	local := dep.Field
+	if dep.Parent != "" {
+		local = dep.Parent
+	}
+	base := tg.field(local)
+	kind := dependencyKind(fg.inferredType)
+	if base == nil || base == fg || kind == "" {
+		logger.Debug("Field %s: ignoring depends_on %q", fg.field.Name, local)
+		return
+	}
+
+	d := &fieldDependency{base: base, kind: kind}
+	if dep.Parent != "" {
+		parentField, ok := plan.findField(base.refTable, dep.Field)
+		if base.refTable == "" || base.composite || !ok {
+			logger.Debug("Field %s: ignoring depends_on %s.%s: %s is not a foreign key to a table with that column",
+				fg.field.Name, base.refTable, dep.Field, dep.Parent)
+			return
+		}
+		column := &fieldGenerator{field: parentField, inferredType: fg.inference.InferFieldType(parentField)}
+		column.timeRange = newTimeRange(parentField, column.inferredType)
+		d.parent = &parentLookup{table: base.refTable, key: base.refField, column: column}
+	}
+	strict := true
+	switch strings.ToLower(dep.Operator) {
+	case "", "after", ">":
+	case "on_or_after", ">=":
+		strict = false
+	case "before", "<":
+		d.back = true
+	case "on_or_before", "<=":
+		d.back, strict = true, false
+	default:
+		logger.Debug("Field %s: ignoring depends_on with unknown operator %q", fg.field.Name, dep.Operator)
+		return
+	}
+
+	d.minOffset, d.maxOffset = string(dep.MinOffset), string(dep.MaxOffset)
+	if d.minOffset == "" {
+		d.minOffset = "0"
+		if strict {
+			d.minOffset = map[string]string{"date": "1d", "datetime": "1s", "number": "1"}[kind]
+		}
+	}
+	if d.maxOffset == "" {
+		d.maxOffset = defaultMaxTimeOffset
+		if kind == "number" {
+			d.maxOffset = defaultMaxNumberOffset
+		}
+	}
+	fg.dependency = d
+	// The dependency decides the value instead of the column default
+	fg.defaults = nil
+}
+
+// orderByDependencies returns the fields with every field after the columns
+// it reads, otherwise keeping the schema order. Cycles, which schema validation rejects, are broken where
+// they are found.
+func orderByDependencies(fields []*fieldGenerator) []*fieldGenerator {
+	byName := make(map[string]*fieldGenerator, len(fields))
+	for _, fg := range fields {
+		byName[fg.field.Name] = fg
+	}
+	order := make([]*fieldGenerator, 0, len(fields))
+	state := make(map[*fieldGenerator]int) // 1 visiting, 2 done
+	var visit func(fg *fieldGenerator)
+	visit = func(fg *fieldGenerator) {
+		if fg == nil || state[fg] != 0 {
+			return
+		}
+		state[fg] = 1
+		for _, name := range fg.inputs() {
+			visit(byName[name])
+		}
+		state[fg] = 2
+		order = append(order, fg)
+	}
+	for _, fg := range fields {
+		visit(fg)
+	}
+	return order
+}
+
+// inputs returns the columns of the row a field reads: the column it depends
+// on, and those read by its expression and its when rules
+func (fg *fieldGenerator) inputs() []string {
+	var names []string
+	if fg.dependency != nil {
+		names = append(names, fg.dependency.base.field.Name)
+	}
+	if fg.computed != nil {
+		names = append(names, fg.computed.expr.Columns()...)
+	}
+	for _, rule := range fg.rules {
+		if rule.cond != nil {
+			names = append(names, rule.cond.Columns()...)
+		}
+		if rule.computed != nil {
+			names = append(names, rule.computed.expr.Columns()...)
+		}
+		if rule.override != nil {
+			names = append(names, rule.override.inputs()...)
+		}
+	}
+	return names
+}
+
+// timeFormat returns the layout and time zone the values of a date field are written in
+func (fg *fieldGenerator) timeFormat() (string, *time.Location) {
+	if fg.timeRange != nil {
+		return fg.timeRange.layout, fg.timeRange.loc
+	}
+	if fg.inferredType == "date" {
+		return "date", time.UTC
+	}
+	return "datetime", time.UTC
+}
+
+// derive computes a value from the row's base column. It reports false when
+// the base value is null or cannot be read, in which case the field is
+// generated on its own.
+func (d *fieldDependency) derive(r *rand.Rand, fg *fieldGenerator, row map[string]interface{}, relData *RelationshipData) (interface{}, bool) {
+	value := row[d.base.field.Name]
+	column := d.base
+	if d.parent != nil && value != nil {
+		parent := d.parent.row(value, relData)
+		if parent == nil {
+			return nil, false
+		}
+		value, column = parent[d.parent.column.field.Name], d.parent.column
+	}
+	if value == nil {
+		return nil, false
+	}
+	if d.kind == "number" {
+		return d.deriveNumber(r, fg, value)
+	}
+
+	layout, loc := column.timeFormat()
+	base, ok := faker.ParseTime(value, layout, loc)
+	if !ok {
+		return nil, false
+	}
+	lo, err := faker.ShiftTime(base, d.minOffset, d.back)
+	if err != nil {
+		return nil, false
+	}
+	hi, err := faker.ShiftTime(base, d.maxOffset, d.back)
+	if err != nil {
+		return nil, false
+	}
+	if d.back {
+		lo, hi = hi, lo
+	}
+
+	layout, loc = fg.timeFormat()
+	if d.kind == "date" {
+		return faker.FormatTime(faker.GenerateDateBetween(r, lo.In(loc), hi), layout), true
+	}
+	return faker.FormatTime(faker.GenerateTimeBetween(r, lo, hi).In(loc), layout), true
+}
+
+// deriveNumber adds a random offset to a numeric base value
+func (d *fieldDependency) deriveNumber(r *rand.Rand, fg *fieldGenerator, value interface{}) (interface{}, bool) {
+	base, ok := toFloat(value)
+	if !ok {
+		return nil, false
+	}
+	lo, err := strconv.ParseFloat(d.minOffset, 64)
+	if err != nil {
+		return nil, false
+	}
+	hi, err := strconv.ParseFloat(d.maxOffset, 64)
+	if err != nil {
+		return nil, false
+	}
+
+	sign := 1.0
+	if d.back {
+		sign = -1
+	}
+	if numericTypes[fg.inferredType] {
+		lo, hi = math.Ceil(lo), math.Floor(hi)
+		offset := lo
+		if hi > lo {
+			offset += float64(r.IntN(int(hi-lo) + 1))
+		}
+		return int(math.Round(base + sign*offset)), true
+	}
+
+	result := base + sign*(lo+r.Float64()*(hi-lo))
+	if fg.decimal != nil {
+		return fg.decimal.apply(result), true
+	}
+	return result, true
+}
@@ -0,0 +1,220 @@
+package generator
+
+import (
+	"go-fake/internal/schema"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxDistributionDraws bounds the redraws spent landing inside min/max before clamping
+	maxDistributionDraws = 16
+	// defaultZipfRange is the number of ranks of a zipf distribution without max_value
+	defaultZipfRange = 1000
+	// maxZipfRange caps the ranks precomputed for a zipf distribution
+	maxZipfRange = 1000000
+)
+
+// numericTypes lists the inferred types a distribution applies to, and
+// whether their values are integers
+var numericTypes = map[string]bool{
+	"int":         true,
+	"integer":     true,
+	"age":         true,
+	"float":       false,
+	"price":       false,
+	"temperature": false,
+	"weight":      false,
+	"height":      false,
+	"longitude":   false,
+	"latitude":    false,
+}
+
+// numericDistribution draws the values of a numeric field from a distribution
+type numericDistribution struct {
+	kind     string
+	params   schema.Distribution
+	min, max *float64
+	integer  bool
+	cents    bool // prices are rounded to two decimals
+
+	zipf    []float64       // cumulative weights of the zipf ranks
+	buckets *weightedChoice // histogram bucket indexes
+}
+
+// numericBounds returns the min_value/max_value bounds of a field
+func numericBounds(field schema.Field) (*float64, *float64) {
+	if field.Constraints == nil {
+		return nil, nil
+	}
+	return field.Constraints.MinValue, field.Constraints.MaxValue
+}
+
+// valueRange returns the range uniform numeric values are drawn from: the
+// min_value and max_value bounds, with the default range standing in for a
+// missing one. A single bound beyond the default range gets a range of the
+// default width next to it, so CHECK (qty > 5000) still has values to draw.
+func valueRange(minValue, maxValue *float64, defaultMin, defaultMax float64) (float64, float64) {
+	min, max := defaultMin, defaultMax
+	if minValue != nil {
+		min = *minValue
+	}
+	if maxValue != nil {
+		max = *maxValue
+	}
+	if minValue != nil && maxValue == nil && min >= max {
+		max = min + (defaultMax - defaultMin)
+	}
+	if maxValue != nil && minValue == nil && max <= min {
+		min = max - (defaultMax - defaultMin)
+	}
+	return min, max
+}
+
+// newNumericDistribution compiles the distribution of a field, or returns nil
+// when the field has none or its type is not numeric
+func newNumericDistribution(field schema.Field, inferredType string) *numericDistribution {
+	if field.Constraints == nil || field.Constraints.Distribution == nil {
+		return nil
+	}
+	integer, numeric := numericTypes[inferredType]
+	if !numeric {
+		return nil
+	}
+
+	params := *field.Constraints.Distribution
+	d := &numericDistribution{
+		kind:    strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(params.Type)),
+		params:  params,
+		integer: integer,
+		cents:   inferredType == "price",
+	}
+	d.min, d.max = numericBounds(field)
+
+	switch d.kind {
+	case "zipf":
+		d.zipf = zipfWeights(d.zipfRange(), params.S)
+	case "histogram":
+		enum := schema.Enum{}
+		for i, bucket := range params.Buckets {
+			enum.Values = append(enum.Values, i)
+			enum.Weights = append(enum.Weights, bucket.Weight)
+		}
+		d.buckets = newWeightedChoice(enum)
+	}
+	return d
+}
+
+// zipfRange returns the number of ranks of a zipf distribution
+func (d *numericDistribution) zipfRange() int {
+	n := defaultZipfRange
+	if d.min != nil && d.max != nil {
+		n = int(*d.max-*d.min) + 1
+	}
+	if n < 1 {
+		n = 1
+	}
+	if n > maxZipfRange {
+		n = maxZipfRange
+	}
+	return n
+}
+
+// zipfWeights returns the cumulative weights 1/k^s of ranks 1..n
+func zipfWeights(n int, s float64) []float64 {
+	if s == 0 {
+		s = 1.1
+	}
+	cumulative := make([]float64, n)
+	total := 0.0
+	for k := 1; k <= n; k++ {
+		total += 1 / math.Pow(float64(k), s)
+		cumulative[k-1] = total
+	}
+	return cumulative
+}
+
+// generate draws a value, redrawing a few times to stay within the bounds
+// and clamping when that fails
+func (d *numericDistribution) generate(r *rand.Rand) interface{} {
+	var value float64
+	for attempt := 0; attempt < maxDistributionDraws; attempt++ {
+		value = d.draw(r)
+		if d.integer {
+			value = math.Round(value)
+		}
+		if d.inBounds(value) {
+			break
+		}
+	}
+	if d.min != nil && value < *d.min {
+		value = *d.min
+	}
+	if d.max != nil && value > *d.max {
+		value = *d.max
+	}
+
+	if d.integer {
+		return int(value)
+	}
+	if d.cents {
+		return math.Round(value*100) / 100
+	}
+	return value
+}
+
+// inBounds reports whether a value lies within min_value and max_value
+func (d *numericDistribution) inBounds(value float64) bool {
+	return (d.min == nil || value >= *d.min) && (d.max == nil || value <= *d.max)
+}
+
+// draw samples the distribution once, ignoring the bounds
+func (d *numericDistribution) draw(r *rand.Rand) float64 {
+	p := d.params
+	switch d.kind {
+	case "normal":
+		return p.Mean + r.NormFloat64()*p.StdDev
+	case "lognormal":
+		return math.Exp(p.Mean + r.NormFloat64()*p.StdDev)
+	case "exponential":
+		return r.ExpFloat64() * p.Mean
+	case "poisson":
+		return float64(poisson(r, p.Mean))
+	case "zipf":
+		target := r.Float64() * d.zipf[len(d.zipf)-1]
+		rank := sort.Search(len(d.zipf), func(i int) bool { return d.zipf[i] > target })
+		offset := 1.0
+		if d.min != nil {
+			offset = *d.min
+		}
+		return offset + float64(rank)
+	case "histogram":
+		bucket := p.Buckets[d.buckets.pick(r).(int)]
+		if d.integer {
+			lo, hi := math.Ceil(bucket.Min), math.Floor(bucket.Max)
+			if hi < lo {
+				return lo
+			}
+			return lo + float64(r.IntN(int(hi-lo)+1))
+		}
+		return bucket.Min + r.Float64()*(bucket.Max-bucket.Min)
+	}
+	return 0
+}
+
+// poisson samples a Poisson distribution: Knuth's method for small means and
+// a normal approximation for large ones
+func poisson(r *rand.Rand, mean float64) int {
+	if mean > 30 {
+		return int(math.Max(0, math.Round(mean+r.NormFloat64()*math.Sqrt(mean))))
+	}
+	limit := math.Exp(-mean)
+	k, p := 0, r.Float64()
+	for p > limit {
+		k++
+		p *= r.Float64()
+	}
+	return k
+}
@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"go-fake/internal/schema"
+	"math/rand/v2"
+	"sort"
+)
+
+// weightedChoice picks one of a list of values with probability proportional to its weight
+type weightedChoice struct {
+	values     []interface{}
+	cumulative []float64 // running total of the weights
+}
+
+// newWeightedChoice compiles an enum constraint. Missing or invalid weights
+// give every value the same weight.
+func newWeightedChoice(enum schema.Enum) *weightedChoice {
+	if len(enum.Values) == 0 {
+		return nil
+	}
+
+	c := &weightedChoice{
+		values:     enum.Values,
+		cumulative: make([]float64, len(enum.Values)),
+	}
+	weighted := len(enum.Weights) == len(enum.Values)
+	total := 0.0
+	for i := range enum.Values {
+		weight := 1.0
+		if weighted {
+			weight = enum.Weights[i]
+		}
+		if weight > 0 {
+			total += weight
+		}
+		c.cumulative[i] = total
+	}
+	if total == 0 {
+		for i := range c.cumulative {
+			c.cumulative[i] = float64(i + 1)
+		}
+	}
+	return c
+}
+
+// pick returns a random value
+func (c *weightedChoice) pick(r *rand.Rand) interface{} {
+	target := r.Float64() * c.cumulative[len(c.cumulative)-1]
+	i := sort.Search(len(c.cumulative), func(i int) bool { return c.cumulative[i] > target })
+	if i == len(c.values) {
+		i = len(c.values) - 1
+	}
+	return c.values[i]
+}
+
+// size returns the number of values that can be picked
+func (c *weightedChoice) size() uint64 {
+	n, previous := uint64(0), 0.0
+	for _, total := range c.cumulative {
+		if total > previous {
+			n++
+		}
+		previous = total
+	}
+	return n
+}
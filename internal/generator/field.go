@@ -0,0 +1,411 @@
+package generator
+
+import (
+	"fmt"
+	"go-fake/internal/expr"
+	"go-fake/internal/schema"
+	"go-fake/pkg/faker"
+	"go-fake/pkg/logger"
+	"math/rand/v2"
+	"strings"
+)
+
+// maxUniqueAttemptsPerValue bounds the retries spent looking for distinct values
+const maxUniqueAttemptsPerValue = 100
+
+// fieldGenerator is the compiled form of a schema field: its inferred type,
+// its constraints and where its foreign key values come from. Sequential,
+// parallel and single-table generation all produce values through it, so
+// the generation strategy never changes the semantics of the data.
+type fieldGenerator struct {
+	field        schema.Field
+	inferredType string
+	inference    *FieldTypeInference
+
+	refTable  string // referenced table for foreign keys, empty otherwise
+	refField  string
+	delayed   bool    // self-reference or deferred foreign key, filled after generation
+	composite bool    // part of a composite foreign key, set from the parent row
+	nullRatio float64 // share of null values, 0 for required columns
+	invalid   error   // constraints that leave no value to generate, reported before generation
+
+	parentRows []int // fixed parent row per row index, for relationship-driven row counts
+
+	afterAggregates bool // reads an aggregate of child rows, filled once every table exists
+
+	uniqueValues []interface{}        // fixed value set for unique_count
+	pattern      *faker.Pattern       // values generated from the field's regex pattern
+	enum         *weightedChoice      // allowed values of an enum constraint
+	distribution *numericDistribution // shape of numeric values, nil for uniform
+	decimal      *decimalFormat       // precision and scale of numeric values
+	length       *lengthLimits        // min/max length of generated strings
+	timeRange    *timeRange           // bounds, zone and format of dates and timestamps
+	dependency   *fieldDependency     // value derived from another column of the row
+	computed     *computedField       // value computed by an expression over the row
+	rules        []*fieldRule         // when rules: the first one matching the row applies
+	defaults     *columnDefault       // column DEFAULT taken by a share of the rows
+
+	sequential  bool         // auto-increment ids: one per row, starting at min_value or 1
+	permutation *permutation // distinct integers for unique columns with a value range
+}
+
+// tableGenerator holds the compiled generators for every field of a table
+type tableGenerator struct {
+	name   string
+	fields []*fieldGenerator
+	order  []*fieldGenerator // fields in generation order, each after the column it depends on
+
+	foreignKeys []compositeForeignKey // composite foreign keys picked as whole parent rows
+	uniqueKeys  [][]string            // column sets whose combined values must be distinct
+}
+
+// compositeForeignKey is a multi-column foreign key, null as a whole at nullRatio
+// when none of its columns is required
+type compositeForeignKey struct {
+	schema.ForeignKey
+	nullRatio float64
+}
+
+// compositeMembers maps every column of a composite foreign key to true
+func compositeMembers(table schema.Table) map[string]bool {
+	members := make(map[string]bool)
+	for _, fk := range table.ForeignKeys {
+		for _, name := range fk.Fields {
+			members[name] = true
+		}
+	}
+	return members
+}
+
+// compileTable compiles every field of a table. The cache may be nil; when set,
+// inferred types are shared between tables with the same field name and type.
+// Value sets that must be identical for every row (unique_count) are drawn
+// here from a stream derived from the seed, so batches can be generated in any order.
+func compileTable(seed int64, inference *FieldTypeInference, cache *FieldInferenceCache, table schema.Table, plan *generationPlan) *tableGenerator {
+	tg := &tableGenerator{
+		name:   table.Name,
+		fields: make([]*fieldGenerator, 0, len(table.Fields)),
+	}
+
+	members := compositeMembers(table)
+	for _, field := range table.Fields {
+		fg := compileField(seed, inference, cache, table.Name, field, members[field.Name], plan)
+
+		// Unique columns of composite foreign keys are checked like a one-column key
+		if fg.composite && isUnique(field) {
+			tg.uniqueKeys = append(tg.uniqueKeys, []string{field.Name})
+		}
+
+		tg.fields = append(tg.fields, fg)
+	}
+
+	for _, fg := range tg.fields {
+		tg.compileDependency(fg, plan)
+		tg.compileRules(seed, cache, fg, plan)
+	}
+	tg.order = orderByDependencies(tg.fields)
+	for _, fg := range tg.order {
+		fg.afterAggregates = fg.computed != nil && fg.computed.aggregated
+		for _, name := range fg.inputs() {
+			if input := tg.field(name); input != nil && input.afterAggregates {
+				fg.afterAggregates = true
+			}
+		}
+	}
+
+	for _, fk := range table.ForeignKeys {
+		if len(fk.Fields) == 0 || len(fk.Fields) != len(fk.ToFields) {
+			logger.Debug("Table %s: ignoring foreign key (%v) with mismatched column lists", table.Name, fk.Fields)
+			continue
+		}
+		// Self-references and deferred keys are filled after generation
+		if isSelfForeignKey(table.Name, fk) || plan.isDeferred(table.Name, fk.Fields[0]) {
+			continue
+		}
+		tg.foreignKeys = append(tg.foreignKeys, compositeForeignKey{ForeignKey: fk, nullRatio: keyNullRatio(table, fk.Fields)})
+	}
+
+	if len(table.PrimaryKey) > 0 {
+		tg.uniqueKeys = append(tg.uniqueKeys, table.PrimaryKey)
+	}
+	tg.uniqueKeys = append(tg.uniqueKeys, table.UniqueKeys...)
+
+	return tg
+}
+
+// compileField compiles one field of a table, without the parts that depend on
+// the other fields of the table
+func compileField(seed int64, inference *FieldTypeInference, cache *FieldInferenceCache, tableName string, field schema.Field, composite bool, plan *generationPlan) *fieldGenerator {
+	fg := &fieldGenerator{
+		field:     field,
+		inference: inference,
+		composite: composite,
+		nullRatio: nullRatio(field),
+	}
+
+	cacheKey := field.Name + ":" + field.Type
+	if strings.EqualFold(field.Type, "computed") {
+		fg.inferredType = "computed"
+	} else if cachedType, exists := cache.Get(cacheKey); exists {
+		fg.inferredType = cachedType
+	} else {
+		fg.inferredType = inference.InferFieldType(field)
+		cache.Set(cacheKey, fg.inferredType)
+	}
+
+	if field.Constraints != nil && field.Constraints.References != nil {
+		fg.refTable = field.Constraints.References.Table
+		fg.refField = field.Constraints.References.Field
+		fg.delayed = isSelfReference(tableName, field) || plan.isDeferred(tableName, field.Name)
+	}
+
+	if field.Constraints != nil && field.Constraints.Enum != nil {
+		fg.enum = newWeightedChoice(*field.Constraints.Enum)
+	}
+	fg.distribution = newNumericDistribution(field, fg.inferredType)
+	fg.decimal = newDecimalFormat(field)
+	fg.length = newLengthLimits(field)
+	fg.timeRange = newTimeRange(field, fg.inferredType)
+	fg.computed = newComputedField(field)
+	fg.defaults = newColumnDefault(seed, inference, tableName, field, fg.inferredType, plan)
+	if field.Constraints != nil && field.Constraints.Pattern != "" {
+		pattern, err := faker.CompilePattern(field.Constraints.Pattern)
+		if err != nil {
+			logger.Debug("Field %s: ignoring pattern: %v", field.Name, err)
+		}
+		fg.pattern = pattern
+	}
+
+	if min, max, ok := integerRange(field, fg.inferredType); ok && max < min {
+		fg.invalid = fmt.Errorf("field %s has no whole number between min_value and max_value (%d > %d after rounding)", field.Name, min, max)
+		return fg
+	}
+
+	if field.Constraints != nil && field.Constraints.UniqueCount != nil && fg.refTable == "" {
+		r := deriveRand(seed, tableName+"."+field.Name, 0)
+		fg.uniqueValues = fg.generateUniqueValues(r, *field.Constraints.UniqueCount)
+	}
+	fg.compileUnique(seed, tableName)
+	return fg
+}
+
+// assignParents fixes the parent row of every generated row for the foreign keys
+func (tg *tableGenerator) assignParents(assignments []*parentAssignment) {
+	for _, assignment := range assignments {
+		for _, fg := range tg.fields {
+			if fg.field.Name == assignment.field && fg.refTable != "" && !fg.delayed {
+				fg.parentRows = assignment.parents
+			}
+		}
+	}
+}
+
+// generateTable generates every row of a table with a single stream and makes
+// unique columns distinct
+func (tg *tableGenerator) generateTable(seed int64, numRows int, relData *RelationshipData) ([]map[string]interface{}, error) {
+	if err := tg.checkValueRanges(); err != nil {
+		return nil, err
+	}
+	if err := tg.checkRowCount(numRows); err != nil {
+		return nil, err
+	}
+	rows := tg.generateRows(deriveRand(seed, tg.name, 0), 0, numRows, relData)
+	if err := tg.enforceUniqueness(seed, rows, relData); err != nil {
+		return nil, err
+	}
+	if err := tg.enforceKeyUniqueness(seed, rows, relData); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// checkValueRanges fails early when a field, or one of its when rules, has
+// bounds that no value can satisfy
+func (tg *tableGenerator) checkValueRanges() error {
+	for _, fg := range tg.fields {
+		if fg.invalid != nil {
+			return fmt.Errorf("table %s: %v", tg.name, fg.invalid)
+		}
+		for _, rule := range fg.rules {
+			if rule.override != nil && rule.override.invalid != nil {
+				return fmt.Errorf("table %s: when rule of %v", tg.name, rule.override.invalid)
+			}
+		}
+	}
+	return nil
+}
+
+// generateRows generates the rows with indexes [startRow, endRow)
+func (tg *tableGenerator) generateRows(r *rand.Rand, startRow, endRow int, relData *RelationshipData) []map[string]interface{} {
+	rows := make([]map[string]interface{}, 0, endRow-startRow)
+	for i := startRow; i < endRow; i++ {
+		row := make(map[string]interface{}, len(tg.fields))
+		for _, fg := range tg.order {
+			row[fg.field.Name] = fg.generate(r, i, row, relData)
+		}
+		for _, fk := range tg.foreignKeys {
+			if fk.nullRatio > 0 && r.Float64() < fk.nullRatio {
+				continue
+			}
+			tg.pickParentKey(r, row, fk.ForeignKey, relData)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// pickParentKey copies the referenced columns of one random parent row into a
+// composite foreign key, so the combined value always exists in the parent table
+func (tg *tableGenerator) pickParentKey(r *rand.Rand, row map[string]interface{}, fk schema.ForeignKey, relData *RelationshipData) {
+	if relData == nil {
+		return
+	}
+	if parents := relData.TableData[fk.ToTable]; len(parents) > 0 {
+		copyParentKey(row, parents[r.IntN(len(parents))], fk)
+	}
+}
+
+// copyParentKey sets the columns of a composite foreign key from a parent row
+func copyParentKey(row, parent map[string]interface{}, fk schema.ForeignKey) {
+	for j, name := range fk.Fields {
+		row[name] = parent[fk.ToFields[j]]
+	}
+}
+
+// field returns the compiled generator of a column, or nil
+func (tg *tableGenerator) field(name string) *fieldGenerator {
+	for _, fg := range tg.fields {
+		if fg.field.Name == name {
+			return fg
+		}
+	}
+	return nil
+}
+
+// generate produces the value of the field for one row
+func (fg *fieldGenerator) generate(r *rand.Rand, rowIndex int, row map[string]interface{}, relData *RelationshipData) interface{} {
+	// Self-references and deferred foreign keys are filled once the referenced
+	// rows exist, and so are aggregates of child rows and the fields reading
+	// them; composite foreign keys are set from a whole parent row
+	if fg.delayed || fg.composite || fg.afterAggregates {
+		return nil
+	}
+	return fg.produce(r, rowIndex, row, relData, nil)
+}
+
+// produce computes the value of the field for one row. Aggregates of child
+// rows read nil until aggregate is set, once every table is generated.
+func (fg *fieldGenerator) produce(r *rand.Rand, rowIndex int, row map[string]interface{}, relData *RelationshipData, aggregate func(expr.Aggregate) interface{}) interface{} {
+
+	// When rules override the field in the rows matching their condition
+	if rule := fg.matchRule(row); rule != nil {
+		if value, ok := rule.generate(r, rowIndex, row, relData); ok {
+			return value
+		}
+	}
+
+	// Computed fields are evaluated from the columns generated before them
+	if fg.computed != nil {
+		return fg.computed.value(row, aggregate)
+	}
+
+	// Foreign keys pick a value from an existing row of the referenced table
+	if fg.parentRows != nil && relData != nil {
+		parents := relData.TableData[fg.refTable]
+		return parents[fg.parentRows[rowIndex]][fg.refField]
+	}
+
+	// Rows that leave the column to its DEFAULT, which is never null
+	if fg.defaults != nil && (fg.defaults.ratio >= 1 || r.Float64() < fg.defaults.ratio) {
+		return fg.defaults.generate(r)
+	}
+
+	// Nullable columns, foreign keys included, are null at their null ratio
+	if fg.nullRatio > 0 && r.Float64() < fg.nullRatio {
+		return nil
+	}
+	if fg.refTable != "" && relData != nil {
+		if values := relData.References[fg.refTable+"."+fg.refField]; len(values) > 0 {
+			return values[r.IntN(len(values))]
+		}
+	}
+
+	if fg.sequential {
+		return fg.sequenceStart() + rowIndex
+	}
+	if fg.permutation != nil {
+		return fg.permutation.value(rowIndex)
+	}
+
+	if len(fg.uniqueValues) > 0 {
+		return fg.uniqueValues[rowIndex%len(fg.uniqueValues)]
+	}
+
+	// Dependent values are offset from a column generated earlier in the row,
+	// or from a column of the parent row
+	if fg.dependency != nil {
+		if value, ok := fg.dependency.derive(r, fg, row, relData); ok {
+			return value
+		}
+	}
+
+	return fg.generateValue(r)
+}
+
+// generateValue produces a value from the inferred type and the field constraints
+func (fg *fieldGenerator) generateValue(r *rand.Rand) interface{} {
+	if fg.enum != nil || fg.pattern != nil {
+		value := fg.chooseValue(r)
+		if fg.length != nil {
+			value = fg.length.fit(r, value, fg.chooseValue)
+		}
+		return value
+	}
+	var value interface{}
+	if fg.distribution != nil {
+		value = fg.distribution.generate(r)
+	} else if fg.timeRange != nil {
+		value = fg.timeRange.generate(r)
+	} else {
+		value = fg.inferValue(r)
+	}
+	if fg.length != nil {
+		value = fg.length.fit(r, value, fg.inferValue)
+	}
+	if fg.decimal != nil {
+		value = fg.decimal.apply(value)
+	}
+	return value
+}
+
+// chooseValue picks a value of the enum, or generates one from the pattern
+func (fg *fieldGenerator) chooseValue(r *rand.Rand) interface{} {
+	if fg.enum != nil {
+		return fg.enum.pick(r)
+	}
+	return fg.pattern.Generate(r)
+}
+
+// inferValue produces a value from the inferred type alone
+func (fg *fieldGenerator) inferValue(r *rand.Rand) interface{} {
+	return fg.inference.generateValue(r, fg.field, fg.inferredType)
+}
+
+// generateUniqueValues draws up to count distinct values for the field
+func (fg *fieldGenerator) generateUniqueValues(r *rand.Rand, count int) []interface{} {
+	seen := make(map[interface{}]bool)
+	values := make([]interface{}, 0, count)
+
+	for attempts := 0; len(values) < count && attempts < count*maxUniqueAttemptsPerValue; attempts++ {
+		value := fg.generateValue(r)
+		if !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+
+	if len(values) < count {
+		logger.Debug("Field %s: only found %d of %d unique values", fg.field.Name, len(values), count)
+	}
+	return values
+}
@@ -6,12 +6,15 @@ import (
 	"fmt"
 	"go-fake/internal/schema"
 	"go-fake/pkg/csv"
+	"go-fake/pkg/faker"
 	"go-fake/pkg/logger"
+	"hash/fnv"
 	"math/rand/v2"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 )
 
 var ErrInvalidSchema = errors.New("invalid schema type")
@@ -19,6 +22,10 @@ var ErrInvalidSchema = errors.New("invalid schema type")
 // Global field inference instance
 var fieldInference = NewFieldTypeInference()
 
+// seededReferenceTime is the upper bound for generated dates in seeded runs, so
+// that the same seed produces the same output regardless of when it is run.
+var seededReferenceTime = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
 // OutputFormat represents the desired output format
 type OutputFormat int
 
@@ -50,6 +57,9 @@ func GenerateDataFilesOptimized(schemaInterface interface{}, numRows int, output
 		return nil, ErrInvalidSchema
 	}
 
+	seed := resolveSeed(config.Seed)
+	s = withCheckedDefaults(withFieldRatios(s))
+
 	// Initialize relationship data tracker
 	relData := &RelationshipData{
 		TableData: make(map[string][]map[string]interface{}),
@@ -75,17 +85,23 @@ func GenerateDataFilesOptimized(schemaInterface interface{}, numRows int, output
 			// Use parallel generation for multiple tables
 			logger.Debug("Using parallel table generation with %d workers", config.WorkerPoolSize)
 			ptg := NewParallelTableGenerator(config)
-			ptg.GenerateTablesParallel(s.Tables, numRows, relData, s.Relationships)
+			if err := ptg.GenerateTablesParallel(seed, s.Tables, numRows, relData, s.Relationships); err != nil {
+				return nil, err
+			}
 		} else {
 			// Use sequential generation (original approach)
 			logger.Debug("Using sequential table generation")
-			generateTablesSequential(s.Tables, numRows, relData, s.Relationships)
+			if err := generateTablesSequential(seed, s.Tables, numRows, relData, s.Relationships); err != nil {
+				return nil, err
+			}
 		}
 
-		// Write all generated data to files in the output directory
+		// Write all generated data to files in the output directory, including
+		// junction tables added for many_to_many relationships
 		logger.Debug("Writing data to files")
-		for i, table := range s.Tables {
-			logger.Progress(i+1, len(s.Tables), "Writing table files")
+		tables := withJunctionTables(s.Tables, s.Relationships)
+		for i, table := range tables {
+			logger.Progress(i+1, len(tables), "Writing table files")
 			var filename string
 			var err error
 			
@@ -93,7 +109,7 @@ func GenerateDataFilesOptimized(schemaInterface interface{}, numRows int, output
 				filename = filepath.Join(outputDir, table.Name+".json")
 				err = writeJSONFileArray(filename, relData.TableData[table.Name])
 			} else {
-				data := convertToStringSlicesWithHeaders(relData.TableData[table.Name], table.Fields)
+				data := convertToStringSlicesWithHeaders(relData.TableData[table.Name], table.Fields, config.NullToken)
 				filename = filepath.Join(outputDir, table.Name+".csv")
 				err = csv.WriteCSV(filename, data)
 			}
@@ -110,7 +126,10 @@ func GenerateDataFilesOptimized(schemaInterface interface{}, numRows int, output
 		var err error
 		
 		if format == FormatJSON {
-			data := generateTableDataAsJSON(s.Fields, numRows, "data")
+			data, genErr := generateTableDataAsJSON(seed, s.Fields, numRows, "data")
+			if genErr != nil {
+				return nil, genErr
+			}
 			filename = outputPath
 			if filename == "" || strings.HasSuffix(filename, ".csv") {
 				filename = strings.TrimSuffix(filename, ".csv") + ".json"
@@ -120,7 +139,10 @@ func GenerateDataFilesOptimized(schemaInterface interface{}, numRows int, output
 			}
 			err = writeJSONFile(filename, data)
 		} else {
-			data := generateTableData(s.Fields, numRows)
+			data, genErr := generateTableData(seed, s.Fields, numRows, config.NullToken)
+			if genErr != nil {
+				return nil, genErr
+			}
 			filename = outputPath
 			if filename == "" {
 				filename = "output.csv"
@@ -140,35 +162,38 @@ func GenerateDataFilesOptimized(schemaInterface interface{}, numRows int, output
 	return generatedFiles, nil
 }
 
-// generateTablesSequential generates tables sequentially (original approach)
-func generateTablesSequential(tables []schema.Table, numRows int, relData *RelationshipData, relationships []schema.Relationship) {
-	// First pass: Generate data for tables without dependencies
-	logger.Debug("First pass: generating data for independent tables")
-	independentCount := 0
-	for _, table := range tables {
-		if !hasReferences(table.Fields) {
-			logger.Debug("Generating data for independent table: %s", table.Name)
-			data := generateTableDataWithConstraints(table.Fields, numRows, table.Name, relData, relationships)
-			relData.TableData[table.Name] = data
-			populateReferences(table.Name, table.Fields, data, relData)
-			independentCount++
-		}
+// generateTablesSequential generates tables one at a time in dependency order
+func generateTablesSequential(seed int64, tables []schema.Table, numRows int, relData *RelationshipData, relationships []schema.Relationship) error {
+	plan, err := planGeneration(tables, relationships)
+	if err != nil {
+		return err
 	}
-	logger.Debug("Generated data for %d independent tables", independentCount)
-
-	// Second pass: Generate data for tables with dependencies
-	logger.Debug("Second pass: generating data for dependent tables")
-	dependentCount := 0
-	for _, table := range tables {
-		if hasReferences(table.Fields) {
-			logger.Debug("Generating data for dependent table: %s", table.Name)
-			data := generateTableDataWithConstraints(table.Fields, numRows, table.Name, relData, relationships)
+
+	for depth, level := range plan.levels {
+		logger.Debug("Generating dependency level %d (%d tables)", depth, len(level))
+		for _, table := range level {
+			logger.Debug("Generating data for table: %s", table.Name)
+			rows, assignments, err := planRowCount(seed, table, numRows, plan.relationships, relData)
+			if err != nil {
+				return err
+			}
+			tg := compileTable(seed, fieldInference, nil, table, plan)
+			tg.assignParents(assignments)
+			data, err := tg.generateTable(seed, rows, relData)
+			if err != nil {
+				return err
+			}
+			fillSelfReferences(seed, table, data)
 			relData.TableData[table.Name] = data
 			populateReferences(table.Name, table.Fields, data, relData)
-			dependentCount++
 		}
 	}
-	logger.Debug("Generated data for %d dependent tables", dependentCount)
+
+	if err := fillDeferredReferences(seed, plan, relData); err != nil {
+		return err
+	}
+	fillAggregates(seed, fieldInference, plan, relData)
+	return nil
 }
 
 // GenerateData generates fake data based on the provided schema (backward compatibility).
@@ -187,48 +212,57 @@ func GenerateData(schemaInterface interface{}, numRows int) ([][]string, error)
 		fields = s.Fields
 	}
 
-	return generateTableData(fields, numRows), nil
+	return generateTableData(resolveSeed(0), fields, numRows, "")
 }
 
-// generateTableData generates fake data for a specific table's fields
-func generateTableData(fields []schema.Field, numRows int) [][]string {
-	var data [][]string
-	headers := make([]string, len(fields))
-	for i, field := range fields {
-		headers[i] = field.Name
+// resolveSeed returns the seed for a generation run. A zero seed picks a random
+// one, which is logged so the run can be reproduced. Seeded runs also pin the
+// reference time used for dates and timestamps.
+func resolveSeed(seed int64) int64 {
+	if seed == 0 {
+		seed = rand.Int64()
+		logger.Debug("No seed specified, using random seed %d", seed)
+		faker.SetReferenceTime(time.Now())
+	} else {
+		logger.Debug("Using seed %d", seed)
+		faker.SetReferenceTime(seededReferenceTime)
 	}
-	data = append(data, headers)
+	return seed
+}
 
-	// Generate fake data for each row
-	for i := 0; i < numRows; i++ {
-		row := make([]string, len(fields))
-		for j, field := range fields {
-			row[j] = generateFakeValue(field)
-		}
-		data = append(data, row)
-	}
+// deriveRand creates the random source for one batch of a table. The stream
+// depends only on the run seed, the table name and the batch index, so output
+// is the same however the work is scheduled.
+func deriveRand(seed int64, tableName string, batch int) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(tableName))
+	return rand.New(rand.NewPCG(uint64(seed)^h.Sum64(), uint64(batch)))
+}
 
-	return data
+// generateFieldRows generates rows for a single-table schema defined by its fields
+func generateFieldRows(seed int64, fields []schema.Field, numRows int, tableName string) ([]map[string]interface{}, error) {
+	table := schema.Table{Name: tableName, Fields: fields}
+	tg := compileTable(seed, fieldInference, nil, table, nil)
+	return tg.generateTable(seed, numRows, nil)
 }
 
-// generateTableDataAsJSON generates fake data as JSON objects
-func generateTableDataAsJSON(fields []schema.Field, numRows int, tableName string) map[string]interface{} {
-	var records []map[string]interface{}
-
-	// Generate fake data for each row
-	for i := 0; i < numRows; i++ {
-		record := make(map[string]interface{})
-		for _, field := range fields {
-			// Use intelligent field type inference for JSON generation
-			value := fieldInference.GenerateIntelligentValue(field)
-			record[field.Name] = value
-		}
-		records = append(records, record)
+// generateTableData generates fake data for a specific table's fields, writing
+// null values as nullToken
+func generateTableData(seed int64, fields []schema.Field, numRows int, nullToken string) ([][]string, error) {
+	rows, err := generateFieldRows(seed, fields, numRows, "data")
+	if err != nil {
+		return nil, err
 	}
+	return convertToStringSlicesWithHeaders(rows, fields, nullToken), nil
+}
 
-	return map[string]interface{}{
-		tableName: records,
+// generateTableDataAsJSON generates fake data as JSON objects
+func generateTableDataAsJSON(seed int64, fields []schema.Field, numRows int, tableName string) (map[string]interface{}, error) {
+	rows, err := generateFieldRows(seed, fields, numRows, tableName)
+	if err != nil {
+		return nil, err
 	}
+	return map[string]interface{}{tableName: rows}, nil
 }
 
 // getOutputDirectory determines the output directory path
@@ -271,97 +305,6 @@ func writeJSONFile(filename string, data map[string]interface{}) error {
 	return encoder.Encode(data)
 }
 
-// generateFakeValue generates a fake value based on intelligent field type detection.
-func generateFakeValue(field schema.Field) string {
-	// Use intelligent field type inference
-	value := fieldInference.GenerateIntelligentValue(field)
-	
-	// Convert to string for CSV compatibility
-	return fmt.Sprintf("%v", value)
-}
-
-// hasReferences checks if any field in the table has reference constraints
-func hasReferences(fields []schema.Field) bool {
-	for _, field := range fields {
-		if field.Constraints != nil && field.Constraints.References != nil {
-			return true
-		}
-	}
-	return false
-}
-
-// generateTableDataWithConstraints generates table data while respecting relationship constraints
-func generateTableDataWithConstraints(fields []schema.Field, numRows int, tableName string, relData *RelationshipData, relationships []schema.Relationship) []map[string]interface{} {
-	var rows []map[string]interface{}
-	
-	for i := 0; i < numRows; i++ {
-		row := make(map[string]interface{})
-		
-		for _, field := range fields {
-			value := generateConstrainedValue(field, relData, tableName, i)
-			row[field.Name] = value
-		}
-		
-		rows = append(rows, row)
-	}
-	
-	return rows
-}
-
-// generateConstrainedValue generates a value for a field considering its constraints
-func generateConstrainedValue(field schema.Field, relData *RelationshipData, tableName string, rowIndex int) interface{} {
-	// Handle reference constraints (foreign keys)
-	if field.Constraints != nil && field.Constraints.References != nil {
-		refKey := field.Constraints.References.Table + "." + field.Constraints.References.Field
-		if values, exists := relData.References[refKey]; exists && len(values) > 0 {
-			// Pick a random value from the referenced table
-			return values[rand.IntN(len(values))]
-		}
-	}
-	
-	// Handle dependent fields
-	if field.Constraints != nil && field.Constraints.DependsOn != "" {
-		// This could be used for conditional generation based on other fields
-		// For now, we'll generate normally but this can be extended
-	}
-	
-	// Handle unique count constraints
-	if field.Constraints != nil && field.Constraints.UniqueCount != nil {
-		uniqueValues := generateUniqueValues(field, *field.Constraints.UniqueCount)
-		return uniqueValues[rowIndex % len(uniqueValues)]
-	}
-	
-	// Generate value based on type with min/max constraints
-	return generateConstrainedFakeValue(field)
-}
-
-// generateConstrainedFakeValue generates a fake value with min/max constraints
-func generateConstrainedFakeValue(field schema.Field) interface{} {
-	// Use intelligent field detection for better field type inference
-	if field.Constraints != nil {
-		return fieldInference.GenerateIntelligentValue(field)
-	}
-	
-	// For unconstrained fields, also use intelligent detection
-	return fieldInference.GenerateIntelligentValue(field)
-}
-
-// generateUniqueValues generates a set of unique values for a field
-func generateUniqueValues(field schema.Field, count int) []interface{} {
-	seen := make(map[interface{}]bool)
-	values := make([]interface{}, 0, count)
-	
-	for len(values) < count {
-		value := generateConstrainedFakeValue(field)
-		if !seen[value] {
-			seen[value] = true
-			values = append(values, value)
-		}
-	}
-	
-	return values
-}
-
 // GenerateWithAI generates fake data using AI-enhanced field inference when available
 func GenerateWithAI(s *schema.Schema, numRows int, outputPath string) ([]string, error) {
 	return GenerateWithAIAndFormat(s, numRows, outputPath, "")
@@ -369,6 +312,11 @@ func GenerateWithAI(s *schema.Schema, numRows int, outputPath string) ([]string,
 
 // GenerateWithAIAndFormat generates fake data using AI-enhanced intelligent field inference with format override
 func GenerateWithAIAndFormat(s *schema.Schema, numRows int, outputPath string, formatOverride string) ([]string, error) {
+	return GenerateWithAIAndConfig(s, numRows, outputPath, formatOverride, DefaultPerformanceConfig())
+}
+
+// GenerateWithAIAndConfig generates fake data using AI-enhanced field inference with format override and generation settings
+func GenerateWithAIAndConfig(s *schema.Schema, numRows int, outputPath string, formatOverride string, config PerformanceConfig) ([]string, error) {
 	logger.Debug("Enabling AI-enhanced field inference")
 	
 	// Enable AI mode on the global field inference instance
@@ -380,7 +328,7 @@ func GenerateWithAIAndFormat(s *schema.Schema, numRows int, outputPath string, f
 	}
 	
 	// Use the standard generation process with AI-enhanced inference
-	return GenerateWithFormat(s, numRows, outputPath, formatOverride)
+	return GenerateWithConfig(s, numRows, outputPath, formatOverride, config)
 }
 
 // Generate generates fake data using standard intelligent field inference
@@ -390,6 +338,11 @@ func Generate(s *schema.Schema, numRows int, outputPath string) ([]string, error
 
 // GenerateWithFormat generates fake data using standard intelligent field inference with format override
 func GenerateWithFormat(s *schema.Schema, numRows int, outputPath string, formatOverride string) ([]string, error) {
+	return GenerateWithConfig(s, numRows, outputPath, formatOverride, DefaultPerformanceConfig())
+}
+
+// GenerateWithConfig generates fake data with format override and explicit generation settings
+func GenerateWithConfig(s *schema.Schema, numRows int, outputPath string, formatOverride string, config PerformanceConfig) ([]string, error) {
 	logger.Debug("Starting data generation with %d rows", numRows)
 	
 	// Determine output format based on schema type or override
@@ -435,7 +388,7 @@ func GenerateWithFormat(s *schema.Schema, numRows int, outputPath string, format
 	}
 	logger.Debug("Selected output format: %s", formatName)
 	
-	return GenerateDataFiles(*s, numRows, outputPath, format)
+	return GenerateDataFilesOptimized(*s, numRows, outputPath, format, config)
 }
 
 // populateReferences stores generated values for use as foreign key references
@@ -453,7 +406,7 @@ func populateReferences(tableName string, fields []schema.Field, data []map[stri
 }
 
 // convertToStringSlicesWithHeaders converts map data to string slices for CSV output with headers
-func convertToStringSlicesWithHeaders(data []map[string]interface{}, fields []schema.Field) [][]string {
+func convertToStringSlicesWithHeaders(data []map[string]interface{}, fields []schema.Field, nullToken string) [][]string {
 	// Create result slice with space for header + data rows
 	result := make([][]string, len(data)+1)
 	
@@ -469,7 +422,7 @@ func convertToStringSlicesWithHeaders(data []map[string]interface{}, fields []sc
 		rowData := make([]string, len(fields))
 		for j, field := range fields {
 			if value, exists := row[field.Name]; exists {
-				rowData[j] = fmt.Sprintf("%v", value)
+				rowData[j] = formatCSVValue(value, nullToken)
 			}
 		}
 		result[i+1] = rowData
@@ -479,14 +432,14 @@ func convertToStringSlicesWithHeaders(data []map[string]interface{}, fields []sc
 }
 
 // convertToStringSlices converts map data to string slices for CSV output
-func convertToStringSlices(data []map[string]interface{}, fields []schema.Field) [][]string {
+func convertToStringSlices(data []map[string]interface{}, fields []schema.Field, nullToken string) [][]string {
 	result := make([][]string, len(data))
 	
 	for i, row := range data {
 		rowData := make([]string, len(fields))
 		for j, field := range fields {
 			if value, exists := row[field.Name]; exists {
-				rowData[j] = fmt.Sprintf("%v", value)
+				rowData[j] = formatCSVValue(value, nullToken)
 			}
 		}
 		result[i] = rowData
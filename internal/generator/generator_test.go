@@ -1,8 +1,18 @@
 package generator
 
 import (
+	"encoding/json"
+	"fmt"
 	"go-fake/internal/schema"
+	"go-fake/pkg/faker"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGenerateData(t *testing.T) {
@@ -74,4 +84,1564 @@ func TestGenerateData(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+// seedTestSchema is a small multi-table schema used by the reproducibility tests
+var seedTestSchema = schema.Schema{
+	Tables: []schema.Table{
+		{
+			Name: "users",
+			Fields: []schema.Field{
+				{Name: "id", Type: "int", Required: true},
+				{Name: "email", Type: "string", Required: true},
+				{Name: "created_at", Type: "timestamp", Required: true},
+			},
+		},
+		{
+			Name: "orders",
+			Fields: []schema.Field{
+				{Name: "id", Type: "int", Required: true},
+				{Name: "user_id", Type: "int", Required: true, Constraints: &schema.Constraint{
+					References: &schema.Reference{Table: "users", Field: "id"},
+				}},
+				{Name: "total", Type: "float", Required: true},
+			},
+		},
+	},
+}
+
+// generateFileContents runs a generation into a temp directory and returns file contents keyed by base name
+func generateFileContents(t *testing.T, s schema.Schema, numRows int, config PerformanceConfig) map[string]string {
+	t.Helper()
+	dir := t.TempDir()
+	files, err := GenerateDataFilesOptimized(s, numRows, dir, FormatJSON, config)
+	if err != nil {
+		t.Fatalf("GenerateDataFilesOptimized() unexpected error: %v", err)
+	}
+	contents := make(map[string]string)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("reading %s: %v", file, err)
+		}
+		contents[filepath.Base(file)] = string(data)
+	}
+	return contents
+}
+
+// assertSameContents fails the test if two generations differ
+func assertSameContents(t *testing.T, first, second map[string]string) {
+	t.Helper()
+	if len(first) != len(second) {
+		t.Fatalf("file count differs: %d vs %d", len(first), len(second))
+	}
+	for name, content := range first {
+		if second[name] != content {
+			t.Errorf("file %s differs between runs with the same seed", name)
+		}
+	}
+}
+
+func TestGenerateDataFilesSeedIsReproducible(t *testing.T) {
+	config := PerformanceConfig{WorkerPoolSize: 1, BatchSize: 100, Seed: 42}
+
+	first := generateFileContents(t, seedTestSchema, 50, config)
+	second := generateFileContents(t, seedTestSchema, 50, config)
+	if len(first) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(first))
+	}
+	assertSameContents(t, first, second)
+}
+
+func TestParallelGenerationIsIndependentOfWorkerCount(t *testing.T) {
+	for _, cache := range []bool{false, true} {
+		single := PerformanceConfig{EnableParallel: true, WorkerPoolSize: 1, BatchSize: 7, CacheFieldInference: cache, Seed: 99}
+		many := single
+		many.WorkerPoolSize = 8
+
+		assertSameContents(t, generateFileContents(t, seedTestSchema, 50, single), generateFileContents(t, seedTestSchema, 50, many))
+	}
+}
+
+func TestPlanGeneration(t *testing.T) {
+	ref := func(table string) *schema.Constraint {
+		return &schema.Constraint{References: &schema.Reference{Table: table, Field: "id"}}
+	}
+	tables := []schema.Table{
+		{Name: "projects", Fields: []schema.Field{{Name: "manager_id", Type: "int", Required: true, Constraints: ref("employees")}}},
+		{Name: "employees", Fields: []schema.Field{{Name: "user_id", Type: "int", Required: true, Constraints: ref("users")}}},
+		{Name: "users", Fields: []schema.Field{{Name: "id", Type: "int", Required: true}}},
+	}
+
+	plan, err := planGeneration(tables, nil)
+	if err != nil {
+		t.Fatalf("planGeneration() unexpected error: %v", err)
+	}
+	var order []string
+	for _, level := range plan.levels {
+		if len(level) != 1 {
+			t.Fatalf("expected one table per level, got %d", len(level))
+		}
+		order = append(order, level[0].Name)
+	}
+	if strings.Join(order, ",") != "users,employees,projects" {
+		t.Errorf("planGeneration() order = %v, want users,employees,projects", order)
+	}
+
+	// A cycle of required foreign keys cannot be generated
+	tables[2].Fields = append(tables[2].Fields, schema.Field{Name: "project_id", Type: "int", Required: true, Constraints: ref("projects")})
+	_, err = planGeneration(tables, nil)
+	if err == nil {
+		t.Fatal("planGeneration() expected cycle error but got none")
+	}
+	if !strings.Contains(err.Error(), "projects -> employees -> users -> projects") {
+		t.Errorf("cycle error does not list the cycle: %v", err)
+	}
+
+	// A nullable foreign key in the cycle is deferred instead
+	tables[2].Fields[1].Required = false
+	plan, err = planGeneration(tables, nil)
+	if err != nil {
+		t.Fatalf("planGeneration() unexpected error with nullable cycle: %v", err)
+	}
+	if !plan.isDeferred("users", "project_id") || len(plan.levels) != 3 {
+		t.Errorf("expected users.project_id to be deferred, got deferred=%v levels=%d", plan.deferred, len(plan.levels))
+	}
+}
+
+func TestSelfAndCyclicReferencesKeepIntegrity(t *testing.T) {
+	s := schema.Schema{
+		Tables: []schema.Table{
+			{
+				Name: "departments",
+				Fields: []schema.Field{
+					{Name: "id", Type: "uuid", Required: true},
+					{Name: "head_id", Type: "uuid", Constraints: &schema.Constraint{
+						References: &schema.Reference{Table: "employees", Field: "id"},
+					}},
+				},
+			},
+			{
+				Name: "employees",
+				Fields: []schema.Field{
+					{Name: "id", Type: "uuid", Required: true},
+					{Name: "department_id", Type: "uuid", Required: true, Constraints: &schema.Constraint{
+						References: &schema.Reference{Table: "departments", Field: "id"},
+					}},
+					{Name: "manager_id", Type: "uuid", Constraints: &schema.Constraint{
+						References: &schema.Reference{Table: "employees", Field: "id"},
+						Hierarchy:  &schema.Hierarchy{RootFraction: 0.2, MaxDepth: 3},
+					}},
+				},
+			},
+		},
+	}
+
+	for _, parallel := range []bool{false, true} {
+		config := PerformanceConfig{EnableParallel: parallel, WorkerPoolSize: 4, BatchSize: 10, Seed: 5}
+		relData := &RelationshipData{
+			TableData:  make(map[string][]map[string]interface{}),
+			References: make(map[string][]interface{}),
+		}
+		var err error
+		if parallel {
+			err = NewParallelTableGenerator(config).GenerateTablesParallel(config.Seed, s.Tables, 40, relData, nil)
+		} else {
+			err = generateTablesSequential(config.Seed, s.Tables, 40, relData, nil)
+		}
+		if err != nil {
+			t.Fatalf("generation (parallel=%v) unexpected error: %v", parallel, err)
+		}
+
+		ids := func(table string) map[interface{}]int {
+			index := make(map[interface{}]int)
+			for i, row := range relData.TableData[table] {
+				index[row["id"]] = i
+			}
+			return index
+		}
+		employeeIDs, departmentIDs := ids("employees"), ids("departments")
+
+		for _, row := range relData.TableData["departments"] {
+			if _, ok := employeeIDs[row["head_id"]]; !ok {
+				t.Errorf("departments.head_id %v does not reference an employee", row["head_id"])
+			}
+		}
+		employees := relData.TableData["employees"]
+		for i, row := range employees {
+			if _, ok := departmentIDs[row["department_id"]]; !ok {
+				t.Errorf("employees.department_id %v does not reference a department", row["department_id"])
+			}
+			depth := 1
+			for current := i; employees[current]["manager_id"] != nil; depth++ {
+				parent, ok := employeeIDs[employees[current]["manager_id"]]
+				if !ok || parent >= current {
+					t.Fatalf("employees.manager_id %v must reference an earlier employee", employees[current]["manager_id"])
+				}
+				current = parent
+			}
+			if depth > 3 {
+				t.Errorf("hierarchy depth %d exceeds max depth 3", depth)
+			}
+		}
+	}
+}
+
+func TestDeferredUniqueReferencesAreDistinct(t *testing.T) {
+	five := 5
+	s := schema.Schema{
+		Tables: []schema.Table{
+			{
+				Name: "users",
+				Fields: []schema.Field{
+					{Name: "id", Type: "int", PrimaryKey: true},
+					{Name: "profile_id", Type: "int", Unique: true, Constraints: &schema.Constraint{
+						References: &schema.Reference{Table: "profiles", Field: "id"},
+					}},
+				},
+			},
+			{
+				Name: "profiles",
+				Fields: []schema.Field{
+					{Name: "id", Type: "int", PrimaryKey: true},
+					{Name: "user_id", Type: "int", Required: true, Constraints: &schema.Constraint{
+						References: &schema.Reference{Table: "users", Field: "id"},
+					}},
+				},
+			},
+		},
+	}
+
+	for _, parallel := range []bool{false, true} {
+		relData := &RelationshipData{
+			TableData:  make(map[string][]map[string]interface{}),
+			References: make(map[string][]interface{}),
+		}
+		var err error
+		if parallel {
+			err = NewParallelTableGenerator(PerformanceConfig{EnableParallel: true, WorkerPoolSize: 2, BatchSize: 7}).GenerateTablesParallel(8, s.Tables, 30, relData, nil)
+		} else {
+			err = generateTablesSequential(8, s.Tables, 30, relData, nil)
+		}
+		if err != nil {
+			t.Fatalf("generation (parallel=%v) unexpected error: %v", parallel, err)
+		}
+		seen := make(map[interface{}]bool)
+		for _, row := range relData.TableData["users"] {
+			if seen[row["profile_id"]] {
+				t.Errorf("users.profile_id %v is not unique", row["profile_id"])
+			}
+			seen[row["profile_id"]] = true
+		}
+	}
+
+	// A 1:1 relationship makes the deferred key distinct as well
+	s.Tables[0].Fields[1].Unique = false
+	oneToOne := []schema.Relationship{{Type: "foreign_key", FromTable: "users", FromField: "profile_id", ToTable: "profiles", ToField: "id", Cardinality: "1:1"}}
+	relData := &RelationshipData{TableData: make(map[string][]map[string]interface{}), References: make(map[string][]interface{})}
+	s.Tables[1].Rows = &five
+	err := generateTablesSequential(8, s.Tables, 30, relData, oneToOne)
+	if err == nil || !strings.Contains(err.Error(), "profile_id") {
+		t.Errorf("generateTablesSequential() error = %v, want too few profiles for a 1:1 key", err)
+	}
+}
+
+func TestDeferredCompositeReferencesHonorNullRatio(t *testing.T) {
+	half := 0.5
+	s := schema.Schema{
+		Tables: []schema.Table{
+			{Name: "warehouses", Fields: []schema.Field{
+				{Name: "id", Type: "int", PrimaryKey: true},
+				{Name: "bin_aisle", Type: "int", NullRatio: &half},
+				{Name: "bin_shelf", Type: "int", NullRatio: &half},
+			}, ForeignKeys: []schema.ForeignKey{
+				{Fields: []string{"bin_aisle", "bin_shelf"}, ToTable: "bins", ToFields: []string{"aisle", "shelf"}},
+			}},
+			{Name: "bins", PrimaryKey: []string{"aisle", "shelf"}, Fields: []schema.Field{
+				{Name: "aisle", Type: "int", Required: true},
+				{Name: "shelf", Type: "int", Required: true},
+				{Name: "warehouse_id", Type: "int", Required: true, Constraints: &schema.Constraint{
+					References: &schema.Reference{Table: "warehouses", Field: "id"},
+				}},
+			}},
+		},
+	}
+	if plan, err := planGeneration(s.Tables, nil); err != nil || !plan.isDeferred("warehouses", "bin_aisle") {
+		t.Fatalf("expected warehouses.(bin_aisle, bin_shelf) to be deferred, got %v", err)
+	}
+
+	relData := &RelationshipData{
+		TableData:  make(map[string][]map[string]interface{}),
+		References: make(map[string][]interface{}),
+	}
+	if err := generateTablesSequential(6, s.Tables, 200, relData, nil); err != nil {
+		t.Fatalf("generateTablesSequential() error = %v", err)
+	}
+	bins := make(map[string]bool)
+	for _, row := range relData.TableData["bins"] {
+		bins[fmt.Sprint(row["aisle"], "/", row["shelf"])] = true
+	}
+	nulls := 0
+	for _, row := range relData.TableData["warehouses"] {
+		if row["bin_aisle"] == nil || row["bin_shelf"] == nil {
+			if row["bin_aisle"] != nil || row["bin_shelf"] != nil {
+				t.Errorf("composite key is partly null: %v", row)
+			}
+			nulls++
+			continue
+		}
+		if key := fmt.Sprint(row["bin_aisle"], "/", row["bin_shelf"]); !bins[key] {
+			t.Errorf("warehouse references missing bin %s", key)
+		}
+	}
+	if nulls < 60 || nulls > 140 {
+		t.Errorf("composite key is null in %d of 200 rows, want about half", nulls)
+	}
+}
+
+func TestPerfModeHonorsConstraints(t *testing.T) {
+	minAge, maxAge := 18.0, 30.0
+	unique := 3
+	s := seedTestSchema
+	s.Tables = append([]schema.Table{}, seedTestSchema.Tables...)
+	s.Tables[0].Fields = append([]schema.Field{}, seedTestSchema.Tables[0].Fields...)
+	s.Tables[0].Fields = append(s.Tables[0].Fields,
+		schema.Field{Name: "age", Type: "int", Constraints: &schema.Constraint{MinValue: &minAge, MaxValue: &maxAge}},
+		schema.Field{Name: "tier", Type: "string", Constraints: &schema.Constraint{UniqueCount: &unique}},
+	)
+
+	sequential := PerformanceConfig{WorkerPoolSize: 1, BatchSize: 100, Seed: 3}
+	perf := PerformanceConfig{EnableParallel: true, WorkerPoolSize: 4, BatchSize: 100, CacheFieldInference: true, Seed: 3}
+
+	// With a single batch per table both paths draw from the same streams
+	assertSameContents(t, generateFileContents(t, s, 60, sequential), generateFileContents(t, s, 60, perf))
+
+	relData := &RelationshipData{
+		TableData:  make(map[string][]map[string]interface{}),
+		References: make(map[string][]interface{}),
+	}
+	perf.BatchSize = 7
+	if err := NewParallelTableGenerator(perf).GenerateTablesParallel(perf.Seed, s.Tables, 60, relData, nil); err != nil {
+		t.Fatalf("GenerateTablesParallel() unexpected error: %v", err)
+	}
+
+	userIDs := make(map[interface{}]bool)
+	tiers := make(map[interface{}]bool)
+	for _, row := range relData.TableData["users"] {
+		userIDs[row["id"]] = true
+		tiers[row["tier"]] = true
+		if age := row["age"].(int); float64(age) < minAge || float64(age) > maxAge {
+			t.Errorf("age %d outside [%v, %v]", age, minAge, maxAge)
+		}
+	}
+	if len(tiers) != unique {
+		t.Errorf("expected %d distinct tiers, got %d", unique, len(tiers))
+	}
+	for _, row := range relData.TableData["orders"] {
+		if !userIDs[row["user_id"]] {
+			t.Errorf("orders.user_id %v does not reference a user", row["user_id"])
+		}
+	}
+}
+
+func TestRowCountsFromTablesAndRelationships(t *testing.T) {
+	ten, three, eight := 10, 3, 8
+	ref := &schema.Constraint{References: &schema.Reference{Table: "orders", Field: "id"}}
+	s := schema.Schema{
+		Tables: []schema.Table{
+			{Name: "orders", Rows: &ten, Fields: []schema.Field{{Name: "id", Type: "uuid", Required: true}}},
+			{Name: "order_items", Fields: []schema.Field{{Name: "order_id", Type: "uuid", Required: true, Constraints: ref}}},
+			{Name: "invoices", Fields: []schema.Field{{Name: "order_id", Type: "uuid", Required: true, Constraints: ref}}},
+			{Name: "notes", Fields: []schema.Field{{Name: "body", Type: "text"}}},
+		},
+		Relationships: []schema.Relationship{
+			{Type: "one_to_many", FromTable: "order_items", FromField: "order_id", ToTable: "orders", ToField: "id", Cardinality: "1:many", MinCount: &three, MaxCount: &eight},
+			{Type: "foreign_key", FromTable: "invoices", FromField: "order_id", ToTable: "orders", ToField: "id", Cardinality: "1:1"},
+		},
+	}
+
+	for _, parallel := range []bool{false, true} {
+		config := PerformanceConfig{EnableParallel: parallel, WorkerPoolSize: 4, BatchSize: 5, Seed: 11}
+		relData := &RelationshipData{
+			TableData:  make(map[string][]map[string]interface{}),
+			References: make(map[string][]interface{}),
+		}
+		var err error
+		if parallel {
+			err = NewParallelTableGenerator(config).GenerateTablesParallel(config.Seed, s.Tables, 50, relData, s.Relationships)
+		} else {
+			err = generateTablesSequential(config.Seed, s.Tables, 50, relData, s.Relationships)
+		}
+		if err != nil {
+			t.Fatalf("generation (parallel=%v) unexpected error: %v", parallel, err)
+		}
+
+		if got := len(relData.TableData["orders"]); got != 10 {
+			t.Errorf("orders rows = %d, want 10", got)
+		}
+		if got := len(relData.TableData["notes"]); got != 50 {
+			t.Errorf("notes rows = %d, want 50", got)
+		}
+
+		perOrder := make(map[interface{}]int)
+		for _, row := range relData.TableData["order_items"] {
+			perOrder[row["order_id"]]++
+		}
+		for _, order := range relData.TableData["orders"] {
+			if n := perOrder[order["id"]]; n < 3 || n > 8 {
+				t.Errorf("order %v has %d items, want 3-8", order["id"], n)
+			}
+		}
+
+		invoiced := make(map[interface{}]bool)
+		for _, row := range relData.TableData["invoices"] {
+			if invoiced[row["order_id"]] {
+				t.Errorf("order %v has more than one invoice", row["order_id"])
+			}
+			invoiced[row["order_id"]] = true
+		}
+		if len(invoiced) != 10 {
+			t.Errorf("expected one invoice per order, got %d", len(invoiced))
+		}
+	}
+}
+
+func TestUniqueColumns(t *testing.T) {
+	one, hundred := 1.0, 100.0
+	s := schema.Schema{
+		Tables: []schema.Table{
+			{Name: "users", Fields: []schema.Field{
+				{Name: "id", Type: "int", PrimaryKey: true, AutoIncrement: true, Required: true},
+				{Name: "email", Type: "email", Unique: true},
+				{Name: "username", Type: "string", Unique: true},
+				{Name: "badge", Type: "int", Unique: true, Constraints: &schema.Constraint{MinValue: &one, MaxValue: &hundred}},
+			}},
+			{Name: "profiles", Fields: []schema.Field{
+				{Name: "user_id", Type: "int", Unique: true, Required: true,
+					Constraints: &schema.Constraint{References: &schema.Reference{Table: "users", Field: "id"}}},
+			}},
+		},
+	}
+
+	for _, parallel := range []bool{false, true} {
+		config := PerformanceConfig{EnableParallel: parallel, WorkerPoolSize: 4, BatchSize: 7, Seed: 5}
+		relData := &RelationshipData{
+			TableData:  make(map[string][]map[string]interface{}),
+			References: make(map[string][]interface{}),
+		}
+		var err error
+		if parallel {
+			err = NewParallelTableGenerator(config).GenerateTablesParallel(config.Seed, s.Tables, 100, relData, nil)
+		} else {
+			err = generateTablesSequential(config.Seed, s.Tables, 100, relData, nil)
+		}
+		if err != nil {
+			t.Fatalf("generation (parallel=%v) unexpected error: %v", parallel, err)
+		}
+
+		for i, row := range relData.TableData["users"] {
+			if row["id"] != i+1 {
+				t.Errorf("users row %d id = %v, want %d", i, row["id"], i+1)
+			}
+		}
+		for _, column := range []string{"email", "username", "badge"} {
+			seen := make(map[interface{}]bool)
+			for _, row := range relData.TableData["users"] {
+				if seen[row[column]] {
+					t.Errorf("duplicate users.%s value %v", column, row[column])
+				}
+				seen[row[column]] = true
+			}
+		}
+		seen := make(map[interface{}]bool)
+		for _, row := range relData.TableData["profiles"] {
+			if seen[row["user_id"]] {
+				t.Errorf("duplicate profiles.user_id value %v", row["user_id"])
+			}
+			seen[row["user_id"]] = true
+		}
+	}
+
+	// A unique column whose value space is smaller than the row count is an error
+	small := []schema.Field{{Name: "active", Type: "boolean", Unique: true}}
+	if _, err := generateTableData(5, small, 3, ""); err == nil || !strings.Contains(err.Error(), "active") {
+		t.Errorf("expected an error naming the unique column, got %v", err)
+	}
+	codes := []schema.Field{{Name: "code", Type: "string", Unique: true, Constraints: &schema.Constraint{Pattern: "[A-C]{2}"}}}
+	if _, err := generateTableData(5, codes, 10, ""); err == nil {
+		t.Error("expected an error when unique pattern values run out")
+	}
+
+	// Other strings fall back to a numeric suffix that fits the max length
+	six, four := 6, 4
+	names := []schema.Field{
+		{Name: "country", Type: "country", Unique: true, MaxLength: &six},
+		{Name: "tag", Type: "word", Unique: true, MaxLength: &four, FixedLength: true},
+	}
+	data, err := generateTableData(5, names, 500, "")
+	if err != nil {
+		t.Fatalf("generateTableData() error = %v", err)
+	}
+	for column := range names {
+		seen := make(map[string]bool)
+		for _, record := range data[1:] {
+			value := record[column]
+			if seen[value] || len(value) > *names[column].MaxLength || (names[column].FixedLength && len(value) != four) {
+				t.Errorf("%s value %q is a duplicate or does not fit its length", names[column].Name, value)
+			}
+			seen[value] = true
+		}
+	}
+}
+
+func TestUniqueRegenerationRefreshesDependents(t *testing.T) {
+	fields := []schema.Field{
+		{Name: "slug", Type: "string", Expression: "lower(title) || '-' || length(title)"},
+		{Name: "title", Type: "string", Unique: true, Constraints: &schema.Constraint{Pattern: "[A-C]{2}"}},
+		{Name: "headline", Type: "string", Expression: "upper(slug)"},
+	}
+	rows, err := generateFieldRows(3, fields, 9, "data")
+	if err != nil {
+		t.Fatalf("generateTableData() error = %v", err)
+	}
+	for _, row := range rows {
+		title := fmt.Sprint(row["title"])
+		if want := strings.ToLower(title) + "-2"; row["slug"] != want || row["headline"] != strings.ToUpper(want) {
+			t.Errorf("title %s has stale dependents slug=%v headline=%v", title, row["slug"], row["headline"])
+		}
+	}
+}
+
+func TestCompositeKeys(t *testing.T) {
+	one, four := 1.0, 4.0
+	small := &schema.Constraint{MinValue: &one, MaxValue: &four}
+	s := schema.Schema{
+		Tables: []schema.Table{
+			{Name: "shipments", Fields: []schema.Field{
+				{Name: "id", Type: "int", PrimaryKey: true},
+				{Name: "order_id", Type: "int", Required: true},
+				{Name: "line_no", Type: "int", Required: true},
+			}, ForeignKeys: []schema.ForeignKey{
+				{Fields: []string{"order_id", "line_no"}, ToTable: "order_lines", ToFields: []string{"order_id", "line_no"}},
+			}},
+			{Name: "order_lines", PrimaryKey: []string{"order_id", "line_no"}, Fields: []schema.Field{
+				{Name: "order_id", Type: "int", Required: true, Constraints: small},
+				{Name: "line_no", Type: "int", Required: true, Constraints: small},
+			}},
+		},
+	}
+
+	for _, parallel := range []bool{false, true} {
+		config := PerformanceConfig{EnableParallel: parallel, WorkerPoolSize: 4, BatchSize: 3, Seed: 9}
+		relData := &RelationshipData{
+			TableData:  make(map[string][]map[string]interface{}),
+			References: make(map[string][]interface{}),
+		}
+		var err error
+		if parallel {
+			err = NewParallelTableGenerator(config).GenerateTablesParallel(config.Seed, s.Tables, 16, relData, nil)
+		} else {
+			err = generateTablesSequential(config.Seed, s.Tables, 16, relData, nil)
+		}
+		if err != nil {
+			t.Fatalf("generation (parallel=%v) unexpected error: %v", parallel, err)
+		}
+
+		lines := make(map[string]bool)
+		for _, row := range relData.TableData["order_lines"] {
+			key := fmt.Sprint(row["order_id"], "/", row["line_no"])
+			if lines[key] {
+				t.Errorf("duplicate order_lines primary key %s", key)
+			}
+			lines[key] = true
+		}
+		for _, row := range relData.TableData["shipments"] {
+			if key := fmt.Sprint(row["order_id"], "/", row["line_no"]); !lines[key] {
+				t.Errorf("shipment references missing order line %s", key)
+			}
+		}
+	}
+
+	// 4x4 combinations cannot hold 17 distinct primary keys
+	relData := &RelationshipData{
+		TableData:  make(map[string][]map[string]interface{}),
+		References: make(map[string][]interface{}),
+	}
+	if err := generateTablesSequential(9, s.Tables[1:], 17, relData, nil); err == nil {
+		t.Error("expected an error when the composite key runs out of values")
+	}
+}
+
+func TestManyToManyJunctionTables(t *testing.T) {
+	two, three, four := 2, 3, 4
+	ten, twenty := 10, 20
+	s := schema.Schema{
+		Tables: []schema.Table{
+			{Name: "students", Rows: &twenty, Fields: []schema.Field{{Name: "id", Type: "int", PrimaryKey: true}}},
+			{Name: "courses", Rows: &ten, Fields: []schema.Field{{Name: "id", Type: "int", PrimaryKey: true}}},
+			{Name: "enrollments", Fields: []schema.Field{
+				{Name: "student_id", Type: "int"},
+				{Name: "course_id", Type: "int"},
+				{Name: "enrolled_at", Type: "date"},
+			}},
+			{Name: "roles", Rows: &ten, Fields: []schema.Field{{Name: "id", Type: "int", PrimaryKey: true}}},
+		},
+		Relationships: []schema.Relationship{
+			{Type: "many_to_many", FromTable: "students", FromField: "id", ToTable: "courses", ToField: "id",
+				Through: "enrollments", MinPerFrom: &two, MaxPerFrom: &four, MinPerTo: &three},
+			{Type: "many_to_many", FromTable: "students", FromField: "id", ToTable: "roles", ToField: "id"},
+		},
+	}
+
+	dir := t.TempDir()
+	files, err := GenerateDataFilesOptimized(s, 5, filepath.Join(dir, "out"), FormatCSV, PerformanceConfig{Seed: 3, WorkerPoolSize: 1, BatchSize: 100})
+	if err != nil {
+		t.Fatalf("GenerateDataFilesOptimized() error = %v", err)
+	}
+	if len(files) != 5 || !strings.HasSuffix(files[4], "students_roles.csv") {
+		t.Errorf("expected a students_roles junction file, got %v", files)
+	}
+
+	relData := &RelationshipData{
+		TableData:  make(map[string][]map[string]interface{}),
+		References: make(map[string][]interface{}),
+	}
+	if err := generateTablesSequential(3, s.Tables, 5, relData, s.Relationships); err != nil {
+		t.Fatalf("generateTablesSequential() error = %v", err)
+	}
+
+	pairs := make(map[string]bool)
+	perStudent := make(map[interface{}]int)
+	perCourse := make(map[interface{}]int)
+	for _, row := range relData.TableData["enrollments"] {
+		pair := fmt.Sprint(row["student_id"], "/", row["course_id"])
+		if pairs[pair] {
+			t.Errorf("duplicate enrollment %s", pair)
+		}
+		pairs[pair] = true
+		perStudent[row["student_id"]]++
+		perCourse[row["course_id"]]++
+		if row["enrolled_at"] == nil {
+			t.Errorf("enrollment %s is missing its own columns", pair)
+		}
+	}
+	for _, student := range relData.TableData["students"] {
+		if n := perStudent[student["id"]]; n < 2 || n > 4 {
+			t.Errorf("student %v has %d courses, want 2-4", student["id"], n)
+		}
+	}
+	for _, course := range relData.TableData["courses"] {
+		if n := perCourse[course["id"]]; n < 3 {
+			t.Errorf("course %v has %d students, want at least 3", course["id"], n)
+		}
+	}
+	if len(perStudent) != 20 || len(perCourse) != 10 {
+		t.Errorf("links reference unknown rows: %d students, %d courses", len(perStudent), len(perCourse))
+	}
+
+	for _, row := range relData.TableData["students_roles"] {
+		if row["student_id"] == nil || row["role_id"] == nil {
+			t.Errorf("students_roles row missing a side: %v", row)
+		}
+	}
+
+	// Every course needs 3 students but 20 students can only take 1 course each
+	one := 1
+	s.Relationships[0].MinPerFrom, s.Relationships[0].MaxPerFrom, s.Relationships[0].MinPerTo = &one, &one, &three
+	if err := generateTablesSequential(3, s.Tables, 5, relData, s.Relationships); err == nil {
+		t.Error("expected an error for impossible link counts")
+	}
+}
+
+func TestNullRatio(t *testing.T) {
+	half, never := 0.5, 0.0
+	s := withFieldRatios(schema.Schema{
+		NullRatio: &half,
+		Tables: []schema.Table{
+			{Name: "users", Fields: []schema.Field{
+				{Name: "id", Type: "int", PrimaryKey: true},
+				{Name: "email", Type: "email", Required: true},
+				{Name: "bio", Type: "text"},
+				{Name: "nickname", Type: "username", NullRatio: &never},
+			}},
+			{Name: "posts", Fields: []schema.Field{
+				{Name: "author_id", Type: "int", Constraints: &schema.Constraint{References: &schema.Reference{Table: "users", Field: "id"}}},
+			}},
+		},
+	})
+
+	relData := &RelationshipData{
+		TableData:  make(map[string][]map[string]interface{}),
+		References: make(map[string][]interface{}),
+	}
+	if err := generateTablesSequential(4, s.Tables, 200, relData, nil); err != nil {
+		t.Fatalf("generateTablesSequential() error = %v", err)
+	}
+
+	nulls := make(map[string]int)
+	for _, table := range []string{"users", "posts"} {
+		for _, row := range relData.TableData[table] {
+			for column, value := range row {
+				if value == nil {
+					nulls[column]++
+				}
+			}
+		}
+	}
+	for _, column := range []string{"id", "email", "nickname"} {
+		if nulls[column] != 0 {
+			t.Errorf("column %s has %d nulls, want none", column, nulls[column])
+		}
+	}
+	for _, column := range []string{"bio", "author_id"} {
+		if nulls[column] < 60 || nulls[column] > 140 {
+			t.Errorf("column %s has %d nulls in 200 rows, want about half", column, nulls[column])
+		}
+	}
+
+	data := convertToStringSlicesWithHeaders(relData.TableData["posts"], s.Tables[1].Fields, `\N`)
+	for _, record := range data[1:] {
+		if record[0] == "<nil>" || record[0] == "" {
+			t.Errorf("null written as %q instead of the null token", record[0])
+		}
+	}
+}
+
+func TestPatternConstraint(t *testing.T) {
+	patterns := []string{
+		`^[A-Z]{3}-\d{5}$`,
+		`(INV|ORD)_[0-9a-f]{8}`,
+		`\w+@corp\.(com|io)`,
+		`[^,\s]{4,}`,
+		`(?i)id-x?\d*`,
+	}
+	var fields []schema.Field
+	for i, pattern := range patterns {
+		fields = append(fields, schema.Field{Name: fmt.Sprintf("code%d", i), Type: "string", Constraints: &schema.Constraint{Pattern: pattern}})
+	}
+
+	rows, err := generateFieldRows(8, fields, 200, "codes")
+	if err != nil {
+		t.Fatalf("generateFieldRows() error = %v", err)
+	}
+	for i, pattern := range patterns {
+		re := regexp.MustCompile(`^(?:` + pattern + `)$`)
+		for _, row := range rows {
+			value, ok := row[fields[i].Name].(string)
+			if !ok || !re.MatchString(value) {
+				t.Errorf("value %v does not match %s", row[fields[i].Name], pattern)
+				break
+			}
+		}
+	}
+
+	for _, pattern := range []string{`\bword\b`, `a^b`, `[`, `x$y`} {
+		s := schema.Schema{Fields: []schema.Field{{Name: "code", Type: "string", Constraints: &schema.Constraint{Pattern: pattern}}}}
+		if err := schema.ValidateSchema(s); err == nil {
+			t.Errorf("ValidateSchema() accepted pattern %s", pattern)
+		}
+	}
+}
+
+func TestEnumConstraint(t *testing.T) {
+	fields := []schema.Field{
+		{Name: "status", Type: "status", Constraints: &schema.Constraint{Enum: &schema.Enum{
+			Values:  []interface{}{"pending", "shipped", "delivered"},
+			Weights: []float64{0.2, 0.5, 0.3},
+		}}},
+		{Name: "rating", Type: "int", Constraints: &schema.Constraint{Enum: &schema.Enum{Values: []interface{}{1, 2, 3}}}},
+	}
+
+	rows, err := generateFieldRows(6, fields, 2000, "orders")
+	if err != nil {
+		t.Fatalf("generateFieldRows() error = %v", err)
+	}
+	counts := make(map[interface{}]int)
+	for _, row := range rows {
+		counts[row["status"]]++
+		if rating, ok := row["rating"].(int); !ok || rating < 1 || rating > 3 {
+			t.Errorf("rating %v is not one of the enum values", row["rating"])
+		}
+	}
+	if len(counts) != 3 {
+		t.Errorf("expected only the 3 enum values, got %v", counts)
+	}
+	if counts["shipped"] < 900 || counts["shipped"] > 1100 || counts["pending"] < 300 || counts["pending"] > 500 {
+		t.Errorf("status counts %v do not follow the 0.2/0.5/0.3 weights", counts)
+	}
+
+	unique := []schema.Field{{Name: "rating", Type: "int", Unique: true, Constraints: fields[1].Constraints}}
+	if _, err := generateTableData(6, unique, 4, ""); err == nil {
+		t.Error("expected an error for 4 unique rows from 3 enum values")
+	}
+}
+
+func TestDistributionConstraint(t *testing.T) {
+	zero, hundred := 0.0, 100.0
+	distribution := func(name, fieldType string, d schema.Distribution, bounded bool) schema.Field {
+		c := &schema.Constraint{Distribution: &d}
+		if bounded {
+			c.MinValue, c.MaxValue = &zero, &hundred
+		}
+		return schema.Field{Name: name, Type: fieldType, Constraints: c}
+	}
+	fields := []schema.Field{
+		distribution("score", "float", schema.Distribution{Type: "normal", Mean: 50, StdDev: 10}, true),
+		distribution("clipped", "int", schema.Distribution{Type: "normal", Mean: 95, StdDev: 20}, true),
+		distribution("latency", "float", schema.Distribution{Type: "lognormal", Mean: 3, StdDev: 0.5}, false),
+		distribution("wait", "float", schema.Distribution{Type: "exponential", Mean: 20}, false),
+		distribution("visits", "int", schema.Distribution{Type: "poisson", Mean: 4}, false),
+		distribution("rank", "int", schema.Distribution{Type: "zipf", S: 1.5}, true),
+		distribution("total", "price", schema.Distribution{Type: "histogram", Buckets: []schema.Bucket{
+			{Min: 10, Max: 20, Weight: 3}, {Min: 500, Max: 600, Weight: 1},
+		}}, false),
+	}
+
+	rows, err := generateFieldRows(12, fields, 5000, "metrics")
+	if err != nil {
+		t.Fatalf("generateFieldRows() error = %v", err)
+	}
+
+	mean := func(column string) float64 {
+		sum := 0.0
+		for _, row := range rows {
+			switch v := row[column].(type) {
+			case int:
+				sum += float64(v)
+			case float64:
+				sum += v
+			}
+		}
+		return sum / float64(len(rows))
+	}
+	if m := mean("score"); m < 49 || m > 51 {
+		t.Errorf("normal mean = %.2f, want about 50", m)
+	}
+	if m := mean("wait"); m < 18 || m > 22 {
+		t.Errorf("exponential mean = %.2f, want about 20", m)
+	}
+	if m := mean("visits"); m < 3.8 || m > 4.2 {
+		t.Errorf("poisson mean = %.2f, want about 4", m)
+	}
+	if m := mean("latency"); m < 20 || m > 26 {
+		t.Errorf("lognormal mean = %.2f, want about 22.8", m)
+	}
+
+	ranks := make(map[int]int)
+	low := 0
+	for _, row := range rows {
+		if v := row["clipped"].(int); v < 0 || v > 100 {
+			t.Errorf("clipped value %d outside [0, 100]", v)
+		}
+		ranks[row["rank"].(int)]++
+		total := row["total"].(float64)
+		if total != float64(int(total*100+0.5))/100 || !(total >= 10 && total <= 20 || total >= 500 && total <= 600) {
+			t.Errorf("histogram price %v outside its buckets or not rounded to cents", total)
+		}
+		if total <= 20 {
+			low++
+		}
+	}
+	if ranks[0] <= ranks[1] || ranks[1] <= ranks[5] {
+		t.Errorf("zipf ranks are not decreasing: %d, %d, %d", ranks[0], ranks[1], ranks[5])
+	}
+	if low < 3500 || low > 4000 {
+		t.Errorf("%d of 5000 prices in the weight-3 bucket, want about 3750", low)
+	}
+
+	invalid := schema.Schema{Fields: []schema.Field{distribution("x", "float", schema.Distribution{Type: "normal"}, false)}}
+	if err := schema.ValidateSchema(invalid); err == nil {
+		t.Error("ValidateSchema() accepted a normal distribution without stddev")
+	}
+
+	// Integer buckets only produce the whole numbers inside them
+	half := distribution("level", "int", schema.Distribution{Type: "histogram", Buckets: []schema.Bucket{{Min: 0.5, Max: 2.5, Weight: 1}}}, false)
+	rows, err = generateFieldRows(12, []schema.Field{half}, 500, "levels")
+	if err != nil {
+		t.Fatalf("generateFieldRows() error = %v", err)
+	}
+	for _, row := range rows {
+		if level := row["level"].(int); level < 1 || level > 2 {
+			t.Fatalf("histogram level %d outside bucket [0.5, 2.5]", level)
+		}
+	}
+	empty := distribution("level", "int", schema.Distribution{Type: "histogram", Buckets: []schema.Bucket{{Min: 0.2, Max: 0.8, Weight: 1}}}, false)
+	if err := schema.ValidateSchema(schema.Schema{Fields: []schema.Field{empty}}); err == nil || !strings.Contains(err.Error(), "no whole number") {
+		t.Errorf("ValidateSchema() error = %v, want a bucket with no whole number", err)
+	}
+}
+
+func TestDecimalPrecisionAndFloatBounds(t *testing.T) {
+	ten, four, two := 10, 4, 2
+	cent, maxPrice := 0.01, 99.99
+	low, high := 0.25, 0.75
+	fields := []schema.Field{
+		{Name: "price", Type: "float", Precision: &ten, Scale: &two, Constraints: &schema.Constraint{MinValue: &cent, MaxValue: &maxPrice}},
+		{Name: "amount", Type: "float", Precision: &four, Scale: &two},
+		{Name: "ratio", Type: "float", Constraints: &schema.Constraint{MinValue: &low, MaxValue: &high}},
+	}
+
+	rows, err := generateFieldRows(9, fields, 300, "products")
+	if err != nil {
+		t.Fatalf("generateFieldRows() error = %v", err)
+	}
+	twoDecimals := regexp.MustCompile(`^\d+\.\d{2}$`)
+	for _, row := range rows {
+		for column, max := range map[string]float64{"price": maxPrice, "amount": 99.99} {
+			cell := formatCSVValue(row[column], "")
+			encoded, err := json.Marshal(row[column])
+			if err != nil || string(encoded) != cell || !twoDecimals.MatchString(cell) {
+				t.Fatalf("%s %v should have two decimals in JSON and CSV, got %s and %s", column, row[column], encoded, cell)
+			}
+			if value, _ := strconv.ParseFloat(cell, 64); value > max || (column == "price" && value < cent) {
+				t.Errorf("%s %s outside its range", column, cell)
+			}
+		}
+		if ratio, ok := row["ratio"].(float64); !ok || ratio < low || ratio > high {
+			t.Errorf("ratio %v outside [%v, %v]", row["ratio"], low, high)
+		}
+	}
+
+	s := schema.Schema{Fields: []schema.Field{{Name: "price", Type: "float", Precision: &two, Scale: &four}}}
+	if err := schema.ValidateSchema(s); err == nil {
+		t.Error("ValidateSchema() accepted a scale larger than the precision")
+	}
+
+	// Integer bounds with no whole number between them are an error, not a panic
+	lowBound, highBound := 1.5, 1.9
+	fractional := []schema.Field{{Name: "quantity", Type: "int", Constraints: &schema.Constraint{MinValue: &lowBound, MaxValue: &highBound}}}
+	if err := schema.ValidateSchema(schema.Schema{Fields: fractional}); err == nil || !strings.Contains(err.Error(), "no whole number") {
+		t.Errorf("ValidateSchema() error = %v, want no whole number between the bounds", err)
+	}
+	if _, err := generateFieldRows(9, fractional, 10, "items"); err == nil || !strings.Contains(err.Error(), "no whole number") {
+		t.Errorf("generateFieldRows() error = %v, want no whole number between the bounds", err)
+	}
+	// A single integer bound outside the default 1..1000 range still has values
+	above, below := 5000.0, -5.5
+	oneSided := []schema.Field{
+		{Name: "qty", Type: "int", Constraints: &schema.Constraint{MinValue: &above}},
+		{Name: "badge", Type: "int", Unique: true, Constraints: &schema.Constraint{MinValue: &above}},
+		{Name: "debt", Type: "int", Constraints: &schema.Constraint{MaxValue: &below}},
+	}
+	rows, err = generateFieldRows(9, oneSided, 100, "items")
+	if err != nil {
+		t.Fatalf("generateFieldRows() with a single bound error = %v", err)
+	}
+	distinct := make(map[interface{}]bool)
+	for _, row := range rows {
+		qty, _ := row["qty"].(int)
+		badge, _ := row["badge"].(int)
+		debt, _ := row["debt"].(int)
+		if qty < 5000 || badge < 5000 || debt > -6 {
+			t.Errorf("qty %v, badge %v or debt %v outside its single bound", row["qty"], row["badge"], row["debt"])
+		}
+		distinct[row["qty"]] = true
+	}
+	if len(distinct) < 50 {
+		t.Errorf("qty took %d distinct values in 100 rows, want a spread above its bound", len(distinct))
+	}
+	// So does a single float or decimal bound, e.g. CHECK (price > 2000) or CHECK (neg < -5)
+	eight, floor, priceFloor, ceiling := 8, 5000.0, 2000.01, -5.01
+	oneSided = []schema.Field{
+		{Name: "f", Type: "float", Constraints: &schema.Constraint{MinValue: &floor}},
+		{Name: "price", Type: "float", Precision: &ten, Scale: &two, Constraints: &schema.Constraint{MinValue: &priceFloor}},
+		{Name: "neg", Type: "float", Precision: &eight, Scale: &two, Constraints: &schema.Constraint{MaxValue: &ceiling}},
+	}
+	rows, err = generateFieldRows(9, oneSided, 100, "items")
+	if err != nil {
+		t.Fatalf("generateFieldRows() with a single bound error = %v", err)
+	}
+	distinct = make(map[interface{}]bool)
+	for _, row := range rows {
+		f, _ := toFloat(row["f"])
+		price, _ := toFloat(row["price"])
+		neg, _ := toFloat(row["neg"])
+		if f < floor || price < priceFloor || neg > ceiling {
+			t.Errorf("f %v, price %v or neg %v outside its single bound", row["f"], row["price"], row["neg"])
+		}
+		distinct[row["price"]] = true
+		distinct[row["neg"]] = true
+	}
+	if len(distinct) < 100 {
+		t.Errorf("price and neg took %d distinct values in 100 rows, want them spread past their bound", len(distinct))
+	}
+}
+
+func TestStringLengthLimits(t *testing.T) {
+	two, five, eight, twelve, forty := 2, 5, 8, 12, 40
+	fields := []schema.Field{
+		{Name: "name", Type: "name", MaxLength: &eight},
+		{Name: "address", Type: "address", MinLength: &twelve, MaxLength: &forty},
+		{Name: "title", Type: "string", MinLength: &forty},
+		{Name: "code", Type: "string", MaxLength: &five, FixedLength: true},
+		{Name: "country", Type: "country", MaxLength: &two, FixedLength: true},
+		// Pattern and enum values are fitted as well
+		{Name: "ref", Type: "string", MaxLength: &five, Constraints: &schema.Constraint{Pattern: "^A[A-Za-z0-9]*$"}},
+		{Name: "pin", Type: "string", MaxLength: &five, FixedLength: true, Constraints: &schema.Constraint{Pattern: `^\d{2}$`}},
+		{Name: "size", Type: "string", MaxLength: &five, FixedLength: true, Constraints: &schema.Constraint{
+			Enum: &schema.Enum{Values: []interface{}{"S", "M", "XL"}},
+		}},
+	}
+
+	rows, err := generateFieldRows(10, fields, 200, "places")
+	if err != nil {
+		t.Fatalf("generateFieldRows() error = %v", err)
+	}
+	for _, row := range rows {
+		for _, field := range fields {
+			value, ok := row[field.Name].(string)
+			if !ok {
+				t.Fatalf("%s value %v is not a string", field.Name, row[field.Name])
+			}
+			n := len([]rune(value))
+			if (field.MinLength != nil && n < *field.MinLength) || (field.MaxLength != nil && n > *field.MaxLength) {
+				t.Errorf("%s %q has length %d outside its limits", field.Name, value, n)
+			}
+			if field.FixedLength && n != *field.MaxLength {
+				t.Errorf("%s %q should be padded to %d characters", field.Name, value, *field.MaxLength)
+			}
+		}
+	}
+}
+
+func TestDateRangesAndFormats(t *testing.T) {
+	fields := []schema.Field{
+		{Name: "signup_date", Type: "date", Constraints: &schema.Constraint{MinDate: "-90d", MaxDate: "today"}},
+		{Name: "created_at", Type: "timestamp", Format: "rfc3339", Timezone: "Asia/Tokyo",
+			Constraints: &schema.Constraint{MinDate: "2024-01-01", MaxDate: "2024-01-31 23:59:59"}},
+		{Name: "event_time", Type: "datetime", Format: "unix_ms"},
+		{Name: "due_date", Type: "date", Format: "02/01/2006", Constraints: &schema.Constraint{MinDate: "+1d", MaxDate: "+1mo"}},
+	}
+
+	rows, err := generateFieldRows(11, fields, 200, "events")
+	if err != nil {
+		t.Fatalf("generateFieldRows() error = %v", err)
+	}
+	ref := faker.ReferenceTime().UTC()
+	today := time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, time.UTC)
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	for _, row := range rows {
+		signup, err := time.Parse("2006-01-02", fmt.Sprint(row["signup_date"]))
+		if err != nil || signup.Before(today.AddDate(0, 0, -90)) || signup.After(today) {
+			t.Errorf("signup_date %v outside the last 90 days", row["signup_date"])
+		}
+		created, err := time.Parse(time.RFC3339, fmt.Sprint(row["created_at"]))
+		if err != nil || !strings.HasSuffix(fmt.Sprint(row["created_at"]), "+09:00") ||
+			created.Before(time.Date(2024, 1, 1, 0, 0, 0, 0, tokyo)) || created.After(time.Date(2024, 1, 31, 23, 59, 59, 0, tokyo)) {
+			t.Errorf("created_at %v is not an RFC 3339 Tokyo time in January 2024", row["created_at"])
+		}
+		if _, ok := row["event_time"].(int64); !ok {
+			t.Errorf("event_time %v should be epoch milliseconds", row["event_time"])
+		}
+		due, err := time.Parse("02/01/2006", fmt.Sprint(row["due_date"]))
+		if err != nil || !due.After(today) || due.After(today.AddDate(0, 1, 0)) {
+			t.Errorf("due_date %v outside the next month", row["due_date"])
+		}
+	}
+
+	for _, field := range []schema.Field{
+		{Name: "d", Type: "date", Timezone: "Mars/Olympus"},
+		{Name: "d", Type: "date", Format: "YYYY-MM-DD"},
+		{Name: "d", Type: "date", Constraints: &schema.Constraint{MinDate: "+1y", MaxDate: "now"}},
+		{Name: "d", Type: "date", Constraints: &schema.Constraint{MinDate: "last tuesday"}},
+	} {
+		if err := schema.ValidateSchema(schema.Schema{Fields: []schema.Field{field}}); err == nil {
+			t.Errorf("ValidateSchema() accepted %+v", field)
+		}
+	}
+}
+
+func TestDependsOnOrdersValuesWithinRows(t *testing.T) {
+	fields := []schema.Field{
+		// Declared before the columns they depend on
+		{Name: "end_date", Type: "date", Constraints: &schema.Constraint{DependsOn: &schema.Dependency{
+			Field: "start_date", MinOffset: "1d", MaxOffset: "180d"}}},
+		{Name: "updated_at", Type: "timestamp", Format: "rfc3339", Constraints: &schema.Constraint{DependsOn: &schema.Dependency{
+			Field: "created_at", Operator: "on_or_after"}}},
+		{Name: "start_date", Type: "date"},
+		{Name: "created_at", Type: "timestamp"},
+		{Name: "min_qty", Type: "int", Constraints: &schema.Constraint{DependsOn: &schema.Dependency{
+			Field: "max_qty", Operator: "before", MinOffset: "5", MaxOffset: "10"}}},
+		{Name: "max_qty", Type: "int"},
+	}
+
+	rows, err := generateFieldRows(12, fields, 300, "projects")
+	if err != nil {
+		t.Fatalf("generateFieldRows() error = %v", err)
+	}
+	for _, row := range rows {
+		start, err1 := time.Parse("2006-01-02", fmt.Sprint(row["start_date"]))
+		end, err2 := time.Parse("2006-01-02", fmt.Sprint(row["end_date"]))
+		if days := end.Sub(start).Hours() / 24; err1 != nil || err2 != nil || days < 1 || days > 180 {
+			t.Errorf("end_date %v is not 1-180 days after start_date %v", row["end_date"], row["start_date"])
+		}
+		created, err1 := time.Parse("2006-01-02 15:04:05", fmt.Sprint(row["created_at"]))
+		updated, err2 := time.Parse(time.RFC3339, fmt.Sprint(row["updated_at"]))
+		if err1 != nil || err2 != nil || updated.Before(created) {
+			t.Errorf("updated_at %v is before created_at %v", row["updated_at"], row["created_at"])
+		}
+		if diff := row["max_qty"].(int) - row["min_qty"].(int); diff < 5 || diff > 10 {
+			t.Errorf("min_qty %v is not 5-10 below max_qty %v", row["min_qty"], row["max_qty"])
+		}
+	}
+
+	cycle := []schema.Field{
+		{Name: "a", Type: "date", Constraints: &schema.Constraint{DependsOn: &schema.Dependency{Field: "b"}}},
+		{Name: "b", Type: "date", Constraints: &schema.Constraint{DependsOn: &schema.Dependency{Field: "a"}}},
+	}
+	if err := schema.ValidateSchema(schema.Schema{Fields: cycle}); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("ValidateSchema() error = %v, want a depends_on cycle", err)
+	}
+}
+
+func TestDependsOnParentRow(t *testing.T) {
+	s := schema.Schema{
+		Tables: []schema.Table{
+			{Name: "order_items", Fields: []schema.Field{
+				{Name: "id", Type: "int", PrimaryKey: true},
+				{Name: "order_id", Type: "int", Required: true, Constraints: &schema.Constraint{References: &schema.Reference{Table: "orders", Field: "id"}}},
+				{Name: "created_at", Type: "timestamp", Constraints: &schema.Constraint{DependsOn: &schema.Dependency{
+					Parent: "order_id", Field: "placed_at", Operator: "on_or_after", MaxOffset: "2h"}}},
+				{Name: "quantity", Type: "int", Constraints: &schema.Constraint{DependsOn: &schema.Dependency{
+					Parent: "order_id", Field: "max_items", Operator: "on_or_before", MaxOffset: "3"}}},
+			}},
+			{Name: "orders", Fields: []schema.Field{
+				{Name: "id", Type: "int", PrimaryKey: true},
+				{Name: "placed_at", Type: "timestamp", Format: "unix"},
+				{Name: "max_items", Type: "int"},
+			}},
+		},
+	}
+	if err := schema.ValidateSchema(s); err != nil {
+		t.Fatalf("ValidateSchema() unexpected error: %v", err)
+	}
+
+	for _, parallel := range []bool{false, true} {
+		config := PerformanceConfig{EnableParallel: parallel, WorkerPoolSize: 4, BatchSize: 7, Seed: 13}
+		relData := &RelationshipData{
+			TableData:  make(map[string][]map[string]interface{}),
+			References: make(map[string][]interface{}),
+		}
+		var err error
+		if parallel {
+			err = NewParallelTableGenerator(config).GenerateTablesParallel(config.Seed, s.Tables, 50, relData, nil)
+		} else {
+			err = generateTablesSequential(config.Seed, s.Tables, 50, relData, nil)
+		}
+		if err != nil {
+			t.Fatalf("generation (parallel=%v) unexpected error: %v", parallel, err)
+		}
+
+		orders := make(map[interface{}]map[string]interface{})
+		for _, order := range relData.TableData["orders"] {
+			orders[order["id"]] = order
+		}
+		for _, item := range relData.TableData["order_items"] {
+			order := orders[item["order_id"]]
+			placed := time.Unix(order["placed_at"].(int64), 0)
+			created, err := time.ParseInLocation("2006-01-02 15:04:05", fmt.Sprint(item["created_at"]), time.UTC)
+			if err != nil || created.Before(placed) || created.After(placed.Add(2*time.Hour)) {
+				t.Errorf("item created_at %v is not within 2h after its order's %v", item["created_at"], placed.UTC())
+			}
+			if diff := order["max_items"].(int) - item["quantity"].(int); diff < 0 || diff > 3 {
+				t.Errorf("item quantity %v is not 0-3 below its order's max_items %v", item["quantity"], order["max_items"])
+			}
+		}
+	}
+
+	s.Tables[0].Fields[2].Constraints.DependsOn.Parent = "id"
+	if err := schema.ValidateSchema(s); err == nil {
+		t.Error("ValidateSchema() accepted a parent column that is not a foreign key")
+	}
+}
+
+func TestComputedFields(t *testing.T) {
+	precision, scale := 10, 2
+	minQuantity, maxQuantity := 1.0, 5.0
+	s := schema.Schema{
+		Tables: []schema.Table{
+			{Name: "orders", Fields: []schema.Field{
+				{Name: "id", Type: "int", PrimaryKey: true},
+				{Name: "items_total", Type: "float", Precision: &precision, Scale: &scale, Expression: "coalesce(sum(order_items.total), 0)"},
+				{Name: "item_count", Type: "int", Expression: "count(order_items.id)"},
+			}},
+			{Name: "order_items", Fields: []schema.Field{
+				{Name: "id", Type: "int", PrimaryKey: true},
+				{Name: "order_id", Type: "int", Required: true, Constraints: &schema.Constraint{References: &schema.Reference{Table: "orders", Field: "id"}}},
+				{Name: "total", Type: "float", Precision: &precision, Scale: &scale, Expression: "quantity * unit_price"},
+				{Name: "quantity", Type: "int", Required: true, Constraints: &schema.Constraint{MinValue: &minQuantity, MaxValue: &maxQuantity}},
+				{Name: "unit_price", Type: "float", Required: true, Precision: &precision, Scale: &scale},
+				{Name: "first_name", Type: "string", Required: true, Constraints: &schema.Constraint{Enum: &schema.Enum{Values: []interface{}{"Ada", "Alan"}}}},
+				{Name: "last_name", Type: "string", Required: true, Constraints: &schema.Constraint{Enum: &schema.Enum{Values: []interface{}{"Lovelace", "Turing"}}}},
+				{Name: "full_name", Type: "computed", Expression: "first_name || ' ' || last_name"},
+				{Name: "handle", Type: "computed", Expression: "slug(upper(full_name))"},
+				{Name: "size", Type: "computed", Expression: "CASE WHEN quantity >= 3 THEN 'bulk' ELSE 'single' END"},
+				{Name: "label", Type: "computed", Expression: "format('%s x%d', handle, quantity)"},
+			}},
+		},
+	}
+	if err := schema.ValidateSchema(s); err != nil {
+		t.Fatalf("ValidateSchema() unexpected error: %v", err)
+	}
+
+	for _, parallel := range []bool{false, true} {
+		config := PerformanceConfig{EnableParallel: parallel, WorkerPoolSize: 4, BatchSize: 7, Seed: 21}
+		relData := &RelationshipData{
+			TableData:  make(map[string][]map[string]interface{}),
+			References: make(map[string][]interface{}),
+		}
+		var err error
+		if parallel {
+			err = NewParallelTableGenerator(config).GenerateTablesParallel(config.Seed, s.Tables, 30, relData, nil)
+		} else {
+			err = generateTablesSequential(config.Seed, s.Tables, 30, relData, nil)
+		}
+		if err != nil {
+			t.Fatalf("generation (parallel=%v) unexpected error: %v", parallel, err)
+		}
+
+		sums := make(map[interface{}]float64)
+		counts := make(map[interface{}]int)
+		for _, item := range relData.TableData["order_items"] {
+			quantity := item["quantity"].(int)
+			price, _ := strconv.ParseFloat(string(item["unit_price"].(json.Number)), 64)
+			total, _ := strconv.ParseFloat(string(item["total"].(json.Number)), 64)
+			if math.Abs(total-float64(quantity)*price) > 0.005 {
+				t.Errorf("total %v, want %d * %v", item["total"], quantity, price)
+			}
+			sums[item["order_id"]] += total
+			counts[item["order_id"]]++
+
+			fullName := fmt.Sprint(item["first_name"], " ", item["last_name"])
+			if item["full_name"] != fullName {
+				t.Errorf("full_name = %v, want %q", item["full_name"], fullName)
+			}
+			handle := strings.ToLower(strings.ReplaceAll(fullName, " ", "-"))
+			if item["handle"] != handle {
+				t.Errorf("handle = %v, want %q", item["handle"], handle)
+			}
+			if size := item["size"]; (quantity >= 3) != (size == "bulk") {
+				t.Errorf("size = %v for quantity %d", size, quantity)
+			}
+			if label := fmt.Sprintf("%s x%d", handle, quantity); item["label"] != label {
+				t.Errorf("label = %v, want %q", item["label"], label)
+			}
+		}
+
+		for _, order := range relData.TableData["orders"] {
+			got, _ := strconv.ParseFloat(string(order["items_total"].(json.Number)), 64)
+			if math.Abs(got-sums[order["id"]]) > 0.005 {
+				t.Errorf("order %v items_total = %v, want %.2f", order["id"], order["items_total"], sums[order["id"]])
+			}
+			if order["item_count"] != counts[order["id"]] {
+				t.Errorf("order %v item_count = %v, want %d", order["id"], order["item_count"], counts[order["id"]])
+			}
+		}
+	}
+
+	invalid := []schema.Field{
+		{Name: "a", Type: "computed", Expression: "b + 1"},
+		{Name: "b", Type: "computed", Expression: "a * 2"},
+	}
+	if err := schema.ValidateSchema(schema.Schema{Fields: invalid}); err == nil || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Errorf("ValidateSchema() error = %v, want an expression cycle", err)
+	}
+	invalid = []schema.Field{{Name: "a", Type: "computed", Expression: "lower(missing)"}}
+	if err := schema.ValidateSchema(schema.Schema{Fields: invalid}); err == nil {
+		t.Error("ValidateSchema() accepted an expression reading an unknown column")
+	}
+	invalid = []schema.Field{{Name: "a", Type: "computed", Expression: "1 +"}}
+	if err := schema.ValidateSchema(schema.Schema{Fields: invalid}); err == nil {
+		t.Error("ValidateSchema() accepted an invalid expression")
+	}
+}
+
+func TestComputedFieldsReadingAggregates(t *testing.T) {
+	precision, scale, items := 10, 2, 80
+	minAmount, maxAmount := 1.0, 100.0
+	s := schema.Schema{
+		Tables: []schema.Table{
+			{Name: "orders", Fields: []schema.Field{
+				{Name: "id", Type: "int", PrimaryKey: true},
+				{Name: "tax", Type: "float", Required: true, Precision: &precision, Scale: &scale, Expression: "total * 0.1"},
+				{Name: "size", Type: "string", Required: true, Constraints: &schema.Constraint{When: []schema.Rule{
+					{If: "total >= 200", Value: "large"},
+					{Value: "small"},
+				}}},
+				{Name: "total", Type: "int", Required: true, Expression: "sum(order_items.amount)"},
+			}},
+			{Name: "order_items", Rows: &items, Fields: []schema.Field{
+				{Name: "id", Type: "int", PrimaryKey: true},
+				{Name: "order_id", Type: "int", Required: true, Constraints: &schema.Constraint{References: &schema.Reference{Table: "orders", Field: "id"}}},
+				{Name: "amount", Type: "int", Required: true, Constraints: &schema.Constraint{MinValue: &minAmount, MaxValue: &maxAmount}},
+			}},
+		},
+	}
+	if err := schema.ValidateSchema(s); err != nil {
+		t.Fatalf("ValidateSchema() unexpected error: %v", err)
+	}
+
+	for _, parallel := range []bool{false, true} {
+		config := PerformanceConfig{EnableParallel: parallel, WorkerPoolSize: 4, BatchSize: 7, Seed: 1}
+		relData := &RelationshipData{
+			TableData:  make(map[string][]map[string]interface{}),
+			References: make(map[string][]interface{}),
+		}
+		var err error
+		if parallel {
+			err = NewParallelTableGenerator(config).GenerateTablesParallel(config.Seed, s.Tables, 20, relData, nil)
+		} else {
+			err = generateTablesSequential(config.Seed, s.Tables, 20, relData, nil)
+		}
+		if err != nil {
+			t.Fatalf("generation (parallel=%v) unexpected error: %v", parallel, err)
+		}
+
+		sums := make(map[interface{}]int)
+		for _, item := range relData.TableData["order_items"] {
+			sums[item["order_id"]] += item["amount"].(int)
+		}
+		sizes := make(map[string]int)
+		for _, order := range relData.TableData["orders"] {
+			total := sums[order["id"]]
+			if order["total"] != total {
+				t.Errorf("order %v total = %v, want %d", order["id"], order["total"], total)
+			}
+			tax, ok := order["tax"].(json.Number)
+			if !ok {
+				t.Fatalf("order %v tax = %v, want a number computed from total", order["id"], order["tax"])
+			}
+			if value, _ := strconv.ParseFloat(string(tax), 64); math.Abs(value-float64(total)*0.1) > 0.005 {
+				t.Errorf("order %v tax = %v, want %.2f", order["id"], tax, float64(total)*0.1)
+			}
+			want := "small"
+			if total >= 200 {
+				want = "large"
+			}
+			if order["size"] != want {
+				t.Errorf("order %v size = %v for total %d, want %s", order["id"], order["size"], total, want)
+			}
+			sizes[want]++
+		}
+		if sizes["small"] == 0 || sizes["large"] == 0 {
+			t.Errorf("expected both order sizes, got %v", sizes)
+		}
+	}
+}
+
+func TestWhenRules(t *testing.T) {
+	minRefund, maxRefund := 1.0, 50.0
+	s := schema.Schema{
+		Tables: []schema.Table{
+			{Name: "orders", Fields: []schema.Field{
+				{Name: "id", Type: "int", PrimaryKey: true},
+				{Name: "shipped_at", Type: "timestamp", Constraints: &schema.Constraint{When: []schema.Rule{
+					{If: "status IN ('shipped', 'delivered')", Constraints: &schema.Constraint{
+						DependsOn: &schema.Dependency{Field: "created_at", MaxOffset: "5d"}}},
+					{Null: true},
+				}}},
+				{Name: "status", Type: "string", Required: true, Constraints: &schema.Constraint{Enum: &schema.Enum{
+					Values: []interface{}{"pending", "shipped", "delivered", "cancelled"}}}},
+				{Name: "created_at", Type: "timestamp", Required: true},
+				{Name: "is_refunded", Type: "boolean", Required: true},
+				{Name: "refund_amount", Type: "float", Required: true, Constraints: &schema.Constraint{When: []schema.Rule{
+					{If: "NOT is_refunded", Null: true},
+					{Constraints: &schema.Constraint{MinValue: &minRefund, MaxValue: &maxRefund}},
+				}}},
+				{Name: "priority", Type: "string", Constraints: &schema.Constraint{
+					Enum: &schema.Enum{Values: []interface{}{"low", "high"}},
+					When: []schema.Rule{{If: "status = 'cancelled'", Value: "none"}},
+				}},
+			}},
+		},
+	}
+	if err := schema.ValidateSchema(s); err != nil {
+		t.Fatalf("ValidateSchema() unexpected error: %v", err)
+	}
+
+	relData := &RelationshipData{
+		TableData:  make(map[string][]map[string]interface{}),
+		References: make(map[string][]interface{}),
+	}
+	if err := generateTablesSequential(5, s.Tables, 200, relData, nil); err != nil {
+		t.Fatalf("generateTablesSequential() unexpected error: %v", err)
+	}
+	statuses := make(map[interface{}]bool)
+	for _, row := range relData.TableData["orders"] {
+		status := row["status"]
+		statuses[status] = true
+		if status == "shipped" || status == "delivered" {
+			created, shipped := fmt.Sprint(row["created_at"]), fmt.Sprint(row["shipped_at"])
+			if row["shipped_at"] == nil || shipped <= created {
+				t.Errorf("%v order shipped_at %v is not after created_at %s", status, row["shipped_at"], created)
+			}
+		} else if row["shipped_at"] != nil {
+			t.Errorf("%v order has shipped_at %v", status, row["shipped_at"])
+		}
+
+		refund, refunded := row["refund_amount"], row["is_refunded"] == true
+		if !refunded && refund != nil {
+			t.Errorf("order that is not refunded has refund_amount %v", refund)
+		}
+		if amount, ok := refund.(float64); refunded && (!ok || amount < minRefund || amount > maxRefund) {
+			t.Errorf("refunded order has refund_amount %v, want 1-50", refund)
+		}
+
+		if priority := row["priority"]; (status == "cancelled") != (priority == "none") {
+			t.Errorf("%v order has priority %v", status, priority)
+		}
+	}
+	if len(statuses) != 4 {
+		t.Errorf("expected all four statuses, got %v", statuses)
+	}
+
+	s.Tables[0].Fields[1].Constraints.When[0].If = "state = 'shipped'"
+	if err := schema.ValidateSchema(s); err == nil {
+		t.Error("ValidateSchema() accepted a when condition on an unknown column")
+	}
+	s.Tables[0].Fields[1].Constraints.When[0] = schema.Rule{If: "status = 'shipped'", Null: true, Value: "x"}
+	if err := schema.ValidateSchema(s); err == nil {
+		t.Error("ValidateSchema() accepted a when rule with two actions")
+	}
+}
+
+func TestSQLDialectTypes(t *testing.T) {
+	fields := []schema.Field{
+		{Name: "opens", Type: "time"},
+		{Name: "wait", Type: "interval"},
+		{Name: "location", Type: "point"},
+		{Name: "meta", Type: "json"},
+		{Name: "photo", Type: "binary"},
+		{Name: "scores", Type: "int[]"},
+		{Name: "client", Type: "ipaddress"},
+		{Name: "enabled", Type: "TINYINT(1)"},
+	}
+	formats := map[string]*regexp.Regexp{
+		"opens":    regexp.MustCompile(`^\d\d:\d\d:\d\d$`),
+		"wait":     regexp.MustCompile(`^\d+ (hours|days)`),
+		"location": regexp.MustCompile(`^\(-?\d+\.\d+,-?\d+\.\d+\)$`),
+		"photo":    regexp.MustCompile(`^([0-9a-f]{2}){4,16}$`),
+		"scores":   regexp.MustCompile(`^\{\d+(,\d+){0,2}\}$`),
+		"client":   regexp.MustCompile(`^\d+\.\d+\.\d+\.\d+$`),
+	}
+
+	rows, err := generateFieldRows(3, fields, 50, "places")
+	if err != nil {
+		t.Fatalf("generateFieldRows() error = %v", err)
+	}
+	for _, row := range rows {
+		for name, format := range formats {
+			if value, ok := row[name].(string); !ok || !format.MatchString(value) {
+				t.Errorf("%s value %v does not match %s", name, row[name], format)
+			}
+		}
+		var meta map[string]interface{}
+		if err := json.Unmarshal([]byte(fmt.Sprint(row["meta"])), &meta); err != nil {
+			t.Errorf("meta %v is not a JSON object: %v", row["meta"], err)
+		}
+		if _, ok := row["enabled"].(bool); !ok {
+			t.Errorf("TINYINT(1) value %v is not a boolean", row["enabled"])
+		}
+	}
+
+	if got := faker.FormatArray([]interface{}{"New York", "a\"b", 3, ""}); got != `{"New York","a\"b",3,""}` {
+		t.Errorf("FormatArray() = %s", got)
+	}
+}
+
+func TestColumnDefaults(t *testing.T) {
+	half, never, always := 0.5, 0.0, 1.0
+	s := withFieldRatios(schema.Schema{
+		DefaultRatio: &half,
+		Tables: []schema.Table{{Name: "accounts", Fields: []schema.Field{
+			{Name: "id", Type: "int", PrimaryKey: true, Default: &schema.Default{Value: -1}},
+			{Name: "status", Type: "string", Default: &schema.Default{Value: "active"}},
+			{Name: "plan", Type: "string", Default: &schema.Default{Value: "free"}, DefaultRatio: &never},
+			{Name: "token", Type: "string", Default: &schema.Default{Function: "uuid"}, DefaultRatio: &always},
+			{Name: "created_at", Type: "timestamp", NullRatio: &half, Default: &schema.Default{Function: "now"}},
+			{Name: "nickname", Type: "username", Default: &schema.Default{Function: "today"}},
+		}}},
+	})
+
+	rows, err := compileTable(4, NewFieldTypeInference(), nil, s.Tables[0], nil).generateTable(4, 200, nil)
+	if err != nil {
+		t.Fatalf("generateTable() error = %v", err)
+	}
+
+	counts := make(map[string]int)
+	uuidRe := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	for _, row := range rows {
+		for _, column := range []string{"status", "plan"} {
+			if row[column] == "active" || row[column] == "free" {
+				counts[column]++
+			}
+		}
+		if row["id"] == -1 {
+			counts["id"]++
+		}
+		if !uuidRe.MatchString(fmt.Sprint(row["token"])) {
+			t.Errorf("token %v should be a UUID from its default", row["token"])
+		}
+		if row["created_at"] == nil {
+			counts["created_at"]++
+		}
+		if _, err := time.Parse("2006-01-02", fmt.Sprint(row["nickname"])); err != nil {
+			counts["nickname"]++
+		}
+	}
+	if counts["id"] != 0 || counts["plan"] != 0 {
+		t.Errorf("Primary keys and ignored defaults should keep generated values: %v", counts)
+	}
+	if counts["status"] < 60 || counts["status"] > 140 {
+		t.Errorf("status has the default in %d of 200 rows, want about half", counts["status"])
+	}
+	if counts["created_at"] < 20 || counts["created_at"] > 80 {
+		t.Errorf("created_at is null in %d of 200 rows, want about a quarter", counts["created_at"])
+	}
+	if counts["nickname"] < 60 || counts["nickname"] > 140 {
+		t.Errorf("nickname is not a date in %d of 200 rows, want about half", counts["nickname"])
+	}
+
+	// depends_on fields are derived from their column, not given a function default
+	dependent := withFieldRatios(schema.Schema{DefaultRatio: &always, Fields: []schema.Field{
+		{Name: "created_at", Type: "timestamp", Default: &schema.Default{Function: "now"}},
+		{Name: "updated_at", Type: "timestamp", Default: &schema.Default{Function: "now"},
+			Constraints: &schema.Constraint{DependsOn: &schema.Dependency{Field: "created_at", Operator: "on_or_after"}}},
+	}})
+	rows, err = generateFieldRows(4, dependent.Fields, 200, "events")
+	if err != nil {
+		t.Fatalf("generateFieldRows() error = %v", err)
+	}
+	for _, row := range rows {
+		if fmt.Sprint(row["updated_at"]) < fmt.Sprint(row["created_at"]) {
+			t.Errorf("updated_at %v is before created_at %v", row["updated_at"], row["created_at"])
+		}
+	}
+
+	// Fixed defaults that break the field's constraints are dropped
+	one, three := 1.0, 3
+	checked := withCheckedDefaults(schema.Schema{Tables: []schema.Table{{Name: "items", Fields: []schema.Field{
+		{Name: "qty", Type: "int", Default: &schema.Default{Value: 0}, Constraints: &schema.Constraint{MinValue: &one}},
+		{Name: "size", Type: "string", Default: &schema.Default{Value: "XXL"}, Constraints: &schema.Constraint{
+			Enum: &schema.Enum{Values: []interface{}{"S", "M"}}}},
+		{Name: "sku", Type: "string", Default: &schema.Default{Value: "none"}, Constraints: &schema.Constraint{Pattern: `[A-Z]{3}-\d+`}},
+		{Name: "code", Type: "string", Default: &schema.Default{Value: "long"}, MaxLength: &three},
+		{Name: "stock", Type: "int", Default: &schema.Default{Value: 0}, Constraints: &schema.Constraint{MaxValue: &one}},
+		{Name: "status", Type: "string", Default: &schema.Default{Value: "M"}, Constraints: &schema.Constraint{
+			Enum: &schema.Enum{Values: []interface{}{"S", "M"}}, Pattern: "[A-Z]"}},
+	}}}})
+	for _, field := range checked.Tables[0].Fields {
+		if kept := field.Default != nil; kept != (field.Name == "stock" || field.Name == "status") {
+			t.Errorf("%s default kept = %v", field.Name, kept)
+		}
+	}
+	rows, err = generateFieldRows(4, withFieldRatios(schema.Schema{DefaultRatio: &always, Fields: checked.Tables[0].Fields}).Fields, 50, "items")
+	if err != nil {
+		t.Fatalf("generateFieldRows() error = %v", err)
+	}
+	for _, row := range rows {
+		if qty, _ := row["qty"].(int); qty < 1 {
+			t.Errorf("qty %v is below its CHECK bound", row["qty"])
+		}
+	}
+}
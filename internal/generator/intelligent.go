@@ -1,310 +1,320 @@
 package generator
 
 import (
+	"go-fake/internal/dialect"
 	"go-fake/internal/schema"
 	"go-fake/pkg/faker"
+	"math"
 	"math/rand/v2"
 	"regexp"
+	"sort"
 	"strings"
 )
 
 // FieldTypeInference handles intelligent field type detection and value generation
 type FieldTypeInference struct {
-	patterns map[string][]string // type -> patterns
+	patterns []typePatterns      // checked in declaration order
 	semantic map[string]string   // semantic name -> type
 	aiClient *OpenAIFieldInference // AI-powered inference client
 }
 
+// typePatterns lists the field names that map to a single inferred type.
+// Earlier entries take precedence when a name matches more than one type.
+type typePatterns struct {
+	targetType string
+	names      []string
+}
+
 // NewFieldTypeInference creates a new intelligent field type detector
 func NewFieldTypeInference() *FieldTypeInference {
 	return &FieldTypeInference{
-		patterns: map[string][]string{
+		patterns: []typePatterns{
 			// Email patterns
-			"email": {
+			{"email", []string{
 				"email", "e_mail", "email_address", "e_mail_address", 
 				"contact_email", "user_email", "customer_email", "work_email",
-			},
+			}},
 			
 			// Name patterns
-			"name": {
+			{"name", []string{
 				"name", "full_name", "fullname", "display_name", "user_name", 
 				"customer_name", "client_name", "person_name", "contact_name",
-			},
+			}},
 			
 			// First name patterns
-			"firstname": {
+			{"firstname", []string{
 				"first_name", "firstname", "fname", "given_name", "forename",
 				"first", "christian_name",
-			},
+			}},
 			
 			// Last name patterns
-			"lastname": {
+			{"lastname", []string{
 				"last_name", "lastname", "lname", "surname", "family_name", 
 				"last", "sur_name",
-			},
+			}},
 			
 			// Phone patterns
-			"phone": {
+			{"phone", []string{
 				"phone", "phone_number", "phonenumber", "mobile", "mobile_number",
 				"cell", "cell_phone", "telephone", "contact_number", "contact_phone",
 				"work_phone", "home_phone", "fax", "fax_number",
-			},
+			}},
 			
 			// Address patterns
-			"address": {
+			{"address", []string{
 				"address", "street_address", "street", "address_line", "addr",
 				"home_address", "work_address", "mailing_address", "shipping_address",
 				"billing_address", "physical_address",
-			},
+			}},
 			
 			// City patterns
-			"city": {
+			{"city", []string{
 				"city", "town", "municipality", "locality", "place", "city_name",
 				"hometown", "residence_city",
-			},
+			}},
 			
 			// State patterns
-			"state": {
+			{"state", []string{
 				"state", "province", "region", "territory", "state_code", 
 				"province_code", "state_name", "province_name",
-			},
+			}},
 			
 			// ZIP code patterns
-			"zipcode": {
+			{"zipcode", []string{
 				"zip", "zipcode", "zip_code", "postal_code", "postcode", 
 				"postal", "zip_postal", "post_code",
-			},
+			}},
 			
 			// Country patterns
-			"country": {
+			{"country", []string{
 				"country", "country_name", "country_code", "nation", "nationality",
 				"country_iso", "country_alpha", "homeland",
-			},
+			}},
 			
 			// Company patterns
-			"company": {
+			{"company", []string{
 				"company", "company_name", "organization", "org", "business",
 				"corporation", "corp", "firm", "enterprise", "employer",
 				"organization_name", "business_name",
-			},
+			}},
 			
 			// UUID patterns
-			"uuid": {
+			{"uuid", []string{
 				"uuid", "guid", "id", "identifier", "unique_id", "key", 
 				"primary_key", "ref_id", "reference_id", "external_id",
-			},
+			}},
 			
 			// Date patterns
-			"date": {
+			{"date", []string{
 				"date", "created_date", "updated_date", "birth_date", "birthdate",
 				"start_date", "end_date", "due_date", "expiry_date", "expiration_date",
 				"registration_date", "join_date", "hired_date",
-			},
+			}},
 			
 			// DateTime/Timestamp patterns
-			"datetime": {
+			{"datetime", []string{
 				"datetime", "timestamp", "created_at", "updated_at", "modified_at",
 				"last_login", "last_seen", "login_time", "access_time", "event_time",
 				"created_on", "updated_on", "processed_at",
-			},
+			}},
 			
 			// Price/Money patterns
-			"price": {
+			{"price", []string{
 				"price", "cost", "amount", "fee", "charge", "rate", "salary",
 				"wage", "payment", "total", "subtotal", "tax", "discount",
 				"revenue", "income", "expense", "budget", "balance",
-			},
+			}},
 			
 			// Boolean patterns
-			"boolean": {
+			{"boolean", []string{
 				"active", "enabled", "disabled", "verified", "confirmed", "approved",
 				"published", "deleted", "archived", "featured", "premium", "paid",
 				"completed", "finished", "closed", "open", "available", "visible",
 				"public", "private", "is_active", "is_enabled", "is_deleted",
-			},
+			}},
 			
 			// Description/Text patterns
-			"text": {
+			{"text", []string{
 				"description", "bio", "biography", "summary", "notes", "comments",
 				"details", "content", "body", "message", "review", "feedback",
 				"about", "info", "information", "remarks", "observations",
-			},
+			}},
 			
 			// URL patterns
-			"url": {
+			{"url", []string{
 				"url", "website", "link", "homepage", "site", "web_address",
 				"web_url", "site_url", "profile_url", "image_url", "avatar_url",
-			},
+			}},
 			
 			// Image patterns
-			"image": {
+			{"image", []string{
 				"image", "photo", "picture", "avatar", "thumbnail", "logo",
 				"banner", "icon", "profile_image", "profile_picture", "img",
-			},
+			}},
 			
 			// Age patterns
-			"age": {
+			{"age", []string{
 				"age", "years_old", "birth_year", "year_of_birth", "age_years",
-			},
+			}},
 			
 			// Job title patterns (should come before gender patterns for precedence)
-			"jobtitle": {
+			{"jobtitle", []string{
 				"job_title", "job", "position", "role", "occupation", "profession",
 				"title", "job_position", "work_title", "career", "designation",
-			},
+			}},
 			
 			// Gender patterns (removed generic "title" to avoid conflict)
-			"gender": {
+			{"gender", []string{
 				"gender", "sex", "mr_mrs", "salutation",
-			},
+			}},
 			
 			// Department patterns
-			"department": {
+			{"department", []string{
 				"department", "dept", "division", "team", "unit", "section",
 				"department_name", "work_department", "business_unit",
-			},
+			}},
 			
 			// Skill patterns
-			"skill": {
+			{"skill", []string{
 				"skill", "skills", "technology", "technologies", "expertise", 
 				"competency", "competencies", "capability", "abilities", "talent",
-			},
+			}},
 			
 			// Color patterns
-			"color": {
+			{"color", []string{
 				"color", "colour", "hue", "shade", "tint", "pigment",
 				"primary_color", "background_color", "text_color",
-			},
+			}},
 			
 			// Product patterns
-			"product": {
+			{"product", []string{
 				"product", "product_name", "item", "item_name", "merchandise",
 				"goods", "article", "commodity", "sku", "model",
-			},
+			}},
 			
 			// Brand patterns
-			"brand": {
+			{"brand", []string{
 				"brand", "brand_name", "manufacturer", "make", "label",
 				"trademark", "vendor", "supplier", "producer",
-			},
+			}},
 			
 			// Username patterns
-			"username": {
+			{"username", []string{
 				"username", "user_name", "login", "handle", "nickname", "nick",
 				"screen_name", "display_name", "alias", "login_name",
-			},
+			}},
 			
 			// Password patterns
-			"password": {
+			{"password", []string{
 				"password", "passwd", "pass", "pwd", "secret", "pin",
 				"passcode", "access_code", "security_code",
-			},
+			}},
 			
 			// IP Address patterns
-			"ipaddress": {
+			{"ipaddress", []string{
 				"ip", "ip_address", "ipv4", "ipv6", "host", "server_ip",
 				"client_ip", "remote_ip", "local_ip", "network_address",
-			},
+			}},
 			
 			// MAC Address patterns
-			"macaddress": {
+			{"macaddress", []string{
 				"mac", "mac_address", "hardware_address", "physical_address",
 				"ethernet_address", "wifi_mac", "device_mac",
-			},
+			}},
 			
 			// Credit Card patterns
-			"creditcard": {
+			{"creditcard", []string{
 				"credit_card", "creditcard", "card_number", "cc_number",
 				"payment_card", "debit_card", "card", "cc",
-			},
+			}},
 			
 			// Bank Account patterns
-			"bankaccount": {
+			{"bankaccount", []string{
 				"bank_account", "routing_number", "account_number",
 				"iban", "swift", "bic", "sort_code", "account_no",
-			},
+			}},
 			
 			// Social Security patterns
-			"ssn": {
+			{"ssn", []string{
 				"ssn", "social_security", "social_security_number", "tax_id",
 				"national_id", "personal_id", "citizen_id",
-			},
+			}},
 			
 			// License patterns
-			"license": {
+			{"license", []string{
 				"license", "licence", "license_number", "permit", "certificate",
 				"registration", "license_plate", "driver_license",
-			},
+			}},
 			
 			// Version patterns
-			"version": {
+			{"version", []string{
 				"version", "ver", "release", "build", "revision", "v",
 				"software_version", "app_version", "api_version",
-			},
+			}},
 			
 			// Status patterns
-			"status": {
+			{"status", []string{
 				"status", "state", "condition", "stage", "phase", "mode",
 				"current_status", "order_status", "payment_status",
-			},
+			}},
 			
 			// Priority patterns
-			"priority": {
+			{"priority", []string{
 				"priority", "importance", "urgency", "level", "rank", "grade",
 				"priority_level", "severity", "criticality",
-			},
+			}},
 			
 			// Duration patterns
-			"duration": {
+			{"duration", []string{
 				"duration", "length", "time", "period", "interval", "span",
 				"elapsed_time", "runtime", "execution_time",
-			},
+			}},
 			
 			// File patterns
-			"filename": {
+			{"filename", []string{
 				"file", "filename", "file_name", "document", "attachment",
 				"upload", "media", "resource", "asset", "path",
-			},
+			}},
 			
 			// Hashtag patterns
-			"hashtag": {
+			{"hashtag", []string{
 				"hashtag", "tag", "tags", "keyword", "keywords", "label",
 				"category_tag", "search_tag", "topic",
-			},
+			}},
 			
 			// Longitude patterns
-			"longitude": {
+			{"longitude", []string{
 				"longitude", "lng", "lon", "long", "x_coordinate", "east_west",
-			},
+			}},
 			
 			// Latitude patterns  
-			"latitude": {
+			{"latitude", []string{
 				"latitude", "lat", "y_coordinate", "north_south",
-			},
+			}},
 			
 			// Temperature patterns
-			"temperature": {
+			{"temperature", []string{
 				"temperature", "temp", "celsius", "fahrenheit", "kelvin",
 				"degrees", "thermal", "heat",
-			},
+			}},
 			
 			// Weight patterns
-			"weight": {
+			{"weight", []string{
 				"weight", "mass", "kg", "kilogram", "pound", "lb", "gram",
 				"ounce", "ton", "stone",
-			},
+			}},
 			
 			// Height patterns
-			"height": {
+			{"height", []string{
 				"height", "tall", "stature", "elevation", "altitude", "length",
 				"inches", "feet", "cm", "centimeter", "meter",
-			},
+			}},
 			
 			// Category patterns
-			"category": {
+			{"category", []string{
 				"category", "type", "kind", "classification", "group", "class",
 				"tag", "label", "status", "role", "department", "division",
-			},
+			}},
 		},
 		
 		semantic: map[string]string{
@@ -357,6 +367,12 @@ func NewFieldTypeInference() *FieldTypeInference {
 func (f *FieldTypeInference) InferFieldType(field schema.Field) string {
 	fieldName := strings.ToLower(field.Name)
 	fieldType := strings.ToLower(field.Type)
+
+	// Arrays such as text[] hold values of their element type
+	if element, ok := strings.CutSuffix(fieldType, "[]"); ok {
+		field.Type = element
+		return f.InferFieldType(field) + "[]"
+	}
 	
 	// First, check for direct SQL type mappings
 	if sqlType := f.mapSQLType(fieldType); sqlType != "" {
@@ -364,27 +380,27 @@ func (f *FieldTypeInference) InferFieldType(field schema.Field) string {
 	}
 	
 	// Check for exact pattern matches in field name
-	for targetType, patterns := range f.patterns {
-		for _, pattern := range patterns {
+	for _, entry := range f.patterns {
+		for _, pattern := range entry.names {
 			if fieldName == pattern {
-				return targetType
+				return entry.targetType
 			}
 		}
 	}
 	
 	// Check for partial pattern matches (contains)
-	for targetType, patterns := range f.patterns {
-		for _, pattern := range patterns {
+	for _, entry := range f.patterns {
+		for _, pattern := range entry.names {
 			if strings.Contains(fieldName, pattern) {
-				return targetType
+				return entry.targetType
 			}
 		}
 	}
 	
 	// Check semantic understanding (word parts)
-	for semantic, targetType := range f.semantic {
+	for _, semantic := range sortedKeys(f.semantic) {
 		if strings.Contains(fieldName, semantic) {
-			return targetType
+			return f.semantic[semantic]
 		}
 	}
 	
@@ -410,37 +426,28 @@ func (f *FieldTypeInference) InferFieldType(field schema.Field) string {
 	return f.defaultInference(fieldName, fieldType)
 }
 
-// mapSQLType handles SQL type mappings
+// mapSQLType handles SQL type mappings, using the column types of every
+// supported SQL dialect
 func (f *FieldTypeInference) mapSQLType(sqlType string) string {
-	sqlType = strings.ToUpper(sqlType)
-	
-	switch {
-	case strings.Contains(sqlType, "SERIAL"), strings.Contains(sqlType, "AUTO_INCREMENT"):
-		return "int"
-	case strings.Contains(sqlType, "INTEGER"), strings.Contains(sqlType, "INT"), strings.Contains(sqlType, "BIGINT"):
-		return "int"
-	// Don't immediately return "string" for generic types - let intelligent inference handle it
-	case strings.Contains(sqlType, "VARCHAR"), strings.Contains(sqlType, "TEXT"), strings.Contains(sqlType, "CHAR"):
-		return "" // Let intelligent inference determine the actual type
-	case sqlType == "STRING": // Only for exact "STRING" match, let intelligent inference handle generic string
+	switch sqlType {
+	case "ipaddress", "macaddress":
+		// Types the SQL parser gives INET and MACADDR columns
+		return sqlType
+	case "image":
+		// The image URL type, not the SQL Server IMAGE column type
 		return ""
-	case strings.Contains(sqlType, "DECIMAL"), strings.Contains(sqlType, "NUMERIC"), strings.Contains(sqlType, "FLOAT"), strings.Contains(sqlType, "DOUBLE"):
-		return "float"
-	case strings.Contains(sqlType, "BOOLEAN"), strings.Contains(sqlType, "BOOL"):
-		return "boolean"
-	case strings.Contains(sqlType, "DATE") && !strings.Contains(sqlType, "TIME"):
-		return "date"
-	case strings.Contains(sqlType, "TIMESTAMP"), strings.Contains(sqlType, "DATETIME"):
-		return "datetime"
-	case strings.Contains(sqlType, "UUID"), strings.Contains(sqlType, "GUID"):
+	case "guid":
 		return "uuid"
-	case strings.Contains(sqlType, "JSON"):
-		return "text"
-	case strings.Contains(sqlType, "BINARY"), strings.Contains(sqlType, "BLOB"):
-		return "string"
 	}
-	
-	return ""
+
+	switch internal := dialect.Generic.MapType(sqlType); internal {
+	case "string":
+		return "" // Let intelligent inference determine the actual type
+	case "timestamp":
+		return "datetime"
+	default:
+		return internal
+	}
 }
 
 // regexPatternMatch uses regex patterns for advanced matching
@@ -457,8 +464,8 @@ func (f *FieldTypeInference) regexPatternMatch(fieldName string) string {
 		"uuid":     regexp.MustCompile(`^.*(id|key|uuid|guid)$`),
 	}
 	
-	for fieldType, pattern := range patterns {
-		if pattern.MatchString(fieldName) {
+	for _, fieldType := range sortedKeys(patterns) {
+		if patterns[fieldType].MatchString(fieldName) {
 			return fieldType
 		}
 	}
@@ -502,9 +509,9 @@ func (f *FieldTypeInference) contextualInference(fieldName string) string {
 		"_comments":    "text",
 	}
 	
-	for suffix, fieldType := range suffixes {
+	for _, suffix := range sortedKeys(suffixes) {
 		if strings.HasSuffix(fieldName, suffix) {
-			return fieldType
+			return suffixes[suffix]
 		}
 	}
 	
@@ -526,9 +533,9 @@ func (f *FieldTypeInference) contextualInference(fieldName string) string {
 		"shipping_": "address",
 	}
 	
-	for prefix, fieldType := range prefixes {
+	for _, prefix := range sortedKeys(prefixes) {
 		if strings.HasPrefix(fieldName, prefix) {
-			return fieldType
+			return prefixes[prefix]
 		}
 	}
 	
@@ -601,200 +608,207 @@ func (f *FieldTypeInference) defaultInference(fieldName, fieldType string) strin
 }
 
 // GenerateIntelligentValue generates a value using the intelligent field type inference
-func (f *FieldTypeInference) GenerateIntelligentValue(field schema.Field) interface{} {
-	inferredType := f.InferFieldType(field)
-	
+func (f *FieldTypeInference) GenerateIntelligentValue(r *rand.Rand, field schema.Field) interface{} {
+	return f.generateValue(r, field, f.InferFieldType(field))
+}
+
+// generateValue generates a value for an already inferred type
+func (f *FieldTypeInference) generateValue(r *rand.Rand, field schema.Field, inferredType string) interface{} {
 	// Handle constraints if present
 	if field.Constraints != nil {
-		return f.generateConstrainedValue(field, inferredType)
+		return f.generateConstrainedValue(r, field, inferredType)
 	}
 	
-	return f.GenerateValueByType(inferredType, field.Name)
+	return f.GenerateValueByType(r, inferredType, field.Name)
 }
 
 // generateConstrainedValue generates values respecting field constraints
-func (f *FieldTypeInference) generateConstrainedValue(field schema.Field, inferredType string) interface{} {
+func (f *FieldTypeInference) generateConstrainedValue(r *rand.Rand, field schema.Field, inferredType string) interface{} {
 	constraints := field.Constraints
 	
 	// Handle min/max for numeric types
 	if inferredType == "int" || inferredType == "age" {
-		min := 1
-		max := 1000
-		
-		if constraints.MinValue != nil {
-			min = *constraints.MinValue
-		}
-		if constraints.MaxValue != nil {
-			max = *constraints.MaxValue
-		}
+		minValue, maxValue := valueRange(constraints.MinValue, constraints.MaxValue, 1, 1000)
+		min := int(math.Ceil(minValue))
+		max := int(math.Floor(maxValue))
 		
 		if inferredType == "age" && constraints.MinValue == nil && constraints.MaxValue == nil {
 			min = 18
 			max = 80
 		}
 		
-		return rand.IntN(max-min+1) + min
+		return r.IntN(max-min+1) + min
 	}
 	
 	if inferredType == "float" || inferredType == "price" {
 		// For price fields, use more realistic ranges
 		if inferredType == "price" && constraints.MinValue == nil && constraints.MaxValue == nil {
-			return float64(rand.IntN(100000)) / 100.0 // $0.00 to $1000.00
-		}
-		
-		min := 0.0
-		max := 1000.0
-		
-		if constraints.MinValue != nil {
-			min = float64(*constraints.MinValue)
-		}
-		if constraints.MaxValue != nil {
-			max = float64(*constraints.MaxValue)
+			return float64(r.IntN(100000)) / 100.0 // $0.00 to $1000.00
 		}
 		
-		return min + (max-min)*rand.Float64()
+		min, max := valueRange(constraints.MinValue, constraints.MaxValue, 0, 1000)
+		return min + (max-min)*r.Float64()
 	}
 	
 	// For other types, generate normally
-	return f.GenerateValueByType(inferredType, field.Name)
+	return f.GenerateValueByType(r, inferredType, field.Name)
 }
 
 // generateValueByType generates values based on the inferred type
 // GenerateValueByType generates a value for the specified field type (exported for performance optimizations)
-func (f *FieldTypeInference) GenerateValueByType(fieldType, fieldName string) interface{} {
+func (f *FieldTypeInference) GenerateValueByType(r *rand.Rand, fieldType, fieldName string) interface{} {
+	// Arrays hold one to three values of their element type
+	if element, ok := strings.CutSuffix(fieldType, "[]"); ok {
+		values := make([]interface{}, 1+r.IntN(3))
+		for i := range values {
+			values[i] = f.GenerateValueByType(r, element, fieldName)
+		}
+		return faker.FormatArray(values)
+	}
+
 	switch fieldType {
 	case "email":
-		return faker.GenerateEmail()
+		return faker.GenerateEmail(r)
 	case "name":
-		return faker.GenerateName()
+		return faker.GenerateName(r)
 	case "firstname":
-		return faker.GenerateFirstName()
+		return faker.GenerateFirstName(r)
 	case "lastname":
-		return faker.GenerateLastName()
+		return faker.GenerateLastName(r)
 	case "phone":
-		return faker.GeneratePhone()
+		return faker.GeneratePhone(r)
 	case "address":
-		return faker.GenerateAddress()
+		return faker.GenerateAddress(r)
 	case "city":
-		return faker.GenerateCity()
+		return faker.GenerateCity(r)
 	case "state":
-		return faker.GenerateState()
+		return faker.GenerateState(r)
 	case "zipcode":
-		return faker.GenerateZipCode()
+		return faker.GenerateZipCode(r)
 	case "country":
-		return faker.GenerateCountry()
+		return faker.GenerateCountry(r)
 	case "company":
-		return faker.GenerateCompany()
+		return faker.GenerateCompany(r)
 	case "uuid":
-		return faker.GenerateUUID()
+		return faker.GenerateUUID(r)
 	case "date":
-		return faker.GenerateDate()
+		return faker.GenerateDate(r)
 	case "datetime":
-		return faker.GenerateDateTime()
+		return faker.GenerateDateTime(r)
+	case "time":
+		return faker.GenerateTime(r)
+	case "interval":
+		return faker.GenerateDuration(r)
 	case "price":
-		return faker.GeneratePrice()
+		return faker.GeneratePrice(r)
 	case "boolean":
-		return rand.IntN(2) == 1
+		return r.IntN(2) == 1
 	case "text":
-		return faker.GenerateText()
+		return faker.GenerateText(r)
 	case "url":
-		return faker.GenerateURL()
+		return faker.GenerateURL(r)
 	case "image":
-		return faker.GenerateImageURL()
+		return faker.GenerateImageURL(r)
 	case "jobtitle":
-		return faker.GenerateJobTitle()
+		return faker.GenerateJobTitle(r)
 	case "department":
-		return faker.GenerateDepartment()
+		return faker.GenerateDepartment(r)
 	case "skill":
-		return faker.GenerateSkill()
+		return faker.GenerateSkill(r)
 	case "color":
-		return faker.GenerateColor()
+		return faker.GenerateColor(r)
 	case "product":
-		return faker.GenerateProductName()
+		return faker.GenerateProductName(r)
 	case "brand":
-		return faker.GenerateBrandName()
+		return faker.GenerateBrandName(r)
 	case "username":
-		return faker.GenerateUsername()
+		return faker.GenerateUsername(r)
 	case "password":
-		return faker.GeneratePassword()
+		return faker.GeneratePassword(r)
 	case "ipaddress":
-		return faker.GenerateIPAddress()
+		return faker.GenerateIPAddress(r)
 	case "macaddress":
-		return faker.GenerateMACAddress()
+		return faker.GenerateMACAddress(r)
+	case "point":
+		return faker.GeneratePoint(r)
+	case "json":
+		return faker.GenerateJSON(r)
+	case "binary":
+		return faker.GenerateBinary(r)
 	case "creditcard":
-		return faker.GenerateCreditCard()
+		return faker.GenerateCreditCard(r)
 	case "bankaccount":
-		return faker.GenerateBankAccount()
+		return faker.GenerateBankAccount(r)
 	case "ssn":
-		return faker.GenerateSSN()
+		return faker.GenerateSSN(r)
 	case "license":
-		return faker.GenerateLicense()
+		return faker.GenerateLicense(r)
 	case "version":
-		return faker.GenerateVersion()
+		return faker.GenerateVersion(r)
 	case "status":
-		return faker.GenerateStatus()
+		return faker.GenerateStatus(r)
 	case "priority":
-		return faker.GeneratePriority()
+		return faker.GeneratePriority(r)
 	case "duration":
-		return faker.GenerateDuration()
+		return faker.GenerateDuration(r)
 	case "filename":
-		return faker.GenerateFilename()
+		return faker.GenerateFilename(r)
 	case "hashtag":
-		return faker.GenerateHashtag()
+		return faker.GenerateHashtag(r)
 	case "longitude":
-		return faker.GenerateLongitude()
+		return faker.GenerateLongitude(r)
 	case "latitude":
-		return faker.GenerateLatitude()
+		return faker.GenerateLatitude(r)
 	case "temperature":
-		return faker.GenerateTemperature()
+		return faker.GenerateTemperature(r)
 	case "weight":
-		return faker.GenerateWeight()
+		return faker.GenerateWeight(r)
 	case "height":
-		return faker.GenerateHeight()
+		return faker.GenerateHeight(r)
 	case "age":
-		return rand.IntN(63) + 18 // 18-80 years old
+		return r.IntN(63) + 18 // 18-80 years old
 	case "gender":
-		return faker.GenerateGender()
+		return faker.GenerateGender(r)
 	case "category":
-		return faker.GenerateCategory()
+		return faker.GenerateCategory(r)
 	case "int", "integer":
-		return rand.IntN(1000) + 1
+		return r.IntN(1000) + 1
 	case "float":
-		return rand.Float64() * 1000
+		return r.Float64() * 1000
 	case "string":
 		// Try to make a contextual guess based on field name
-		return f.generateContextualString(fieldName)
+		return f.generateContextualString(r, fieldName)
 	default:
-		return faker.GenerateName()
+		return faker.GenerateName(r)
 	}
 }
 
 // generateContextualString generates contextual strings based on field name
-func (f *FieldTypeInference) generateContextualString(fieldName string) string {
+func (f *FieldTypeInference) generateContextualString(r *rand.Rand, fieldName string) string {
 	fieldName = strings.ToLower(fieldName)
 	
 	// Context-based string generation
 	if strings.Contains(fieldName, "title") {
-		return faker.GenerateJobTitle()
+		return faker.GenerateJobTitle(r)
 	}
 	if strings.Contains(fieldName, "department") {
-		return faker.GenerateDepartment()
+		return faker.GenerateDepartment(r)
 	}
 	if strings.Contains(fieldName, "skill") || strings.Contains(fieldName, "technology") {
-		return faker.GenerateSkill()
+		return faker.GenerateSkill(r)
 	}
 	if strings.Contains(fieldName, "color") {
-		return faker.GenerateColor()
+		return faker.GenerateColor(r)
 	}
 	if strings.Contains(fieldName, "product") {
-		return faker.GenerateProductName()
+		return faker.GenerateProductName(r)
 	}
 	if strings.Contains(fieldName, "brand") {
-		return faker.GenerateBrandName()
+		return faker.GenerateBrandName(r)
 	}
 	
 	// Default to name if no context match
-	return faker.GenerateName()
+	return faker.GenerateName(r)
 }
 
 // InferFieldTypeWithContext performs enhanced inference using table context and AI when available
@@ -813,7 +827,7 @@ func (f *FieldTypeInference) InferFieldTypeWithContext(field schema.Field, table
 }
 
 // GenerateAIEnhancedValue generates values using AI for complex or contextual fields
-func (f *FieldTypeInference) GenerateAIEnhancedValue(field schema.Field, tableName string) interface{} {
+func (f *FieldTypeInference) GenerateAIEnhancedValue(r *rand.Rand, field schema.Field, tableName string) interface{} {
 	// For certain complex fields, we could use AI to generate more contextual data
 	fieldName := strings.ToLower(field.Name)
 	
@@ -826,5 +840,16 @@ func (f *FieldTypeInference) GenerateAIEnhancedValue(field schema.Field, tableNa
 	}
 	
 	// Fall back to standard intelligent value generation
-	return f.GenerateIntelligentValue(field)
+	return f.GenerateIntelligentValue(r, field)
+}
+
+// sortedKeys returns the keys of m in sorted order so that inference does not
+// depend on map iteration order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
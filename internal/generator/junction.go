@@ -0,0 +1,247 @@
+package generator
+
+import (
+	"fmt"
+	"go-fake/internal/schema"
+	"math/rand/v2"
+	"sort"
+	"strings"
+)
+
+// Default number of links per from_table row of a many_to_many relationship
+const (
+	defaultMinLinks = 1
+	defaultMaxLinks = 3
+)
+
+// isManyToMany reports whether a relationship is linked through a junction table
+func isManyToMany(rel schema.Relationship) bool {
+	return strings.EqualFold(rel.Type, "many_to_many")
+}
+
+// singular turns a table name into the prefix of a junction column (users -> user)
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(name, "ss"):
+		return name
+	case strings.HasSuffix(name, "s") && len(name) > 1:
+		return name[:len(name)-1]
+	}
+	return name
+}
+
+// junctionTable returns the name and the two columns of the junction table of
+// a many_to_many relationship, e.g. users_roles(user_id, role_id)
+func junctionTable(rel schema.Relationship) (string, string, string) {
+	name := rel.Through
+	if name == "" {
+		name = rel.FromTable + "_" + rel.ToTable
+	}
+	fromColumn := rel.ThroughFromField
+	if fromColumn == "" {
+		fromColumn = singular(rel.FromTable) + "_" + rel.FromField
+	}
+	toColumn := rel.ThroughToField
+	if toColumn == "" {
+		toColumn = singular(rel.ToTable) + "_" + rel.ToField
+		if toColumn == fromColumn {
+			toColumn = "related_" + toColumn
+		}
+	}
+	return name, fromColumn, toColumn
+}
+
+// withJunctionTables returns the tables with the junction table of every
+// many_to_many relationship added, or its two columns turned into foreign keys
+// when the schema already defines it. The input is not modified.
+func withJunctionTables(tables []schema.Table, relationships []schema.Relationship) []schema.Table {
+	result := tables
+	copied := false
+
+	for _, rel := range relationships {
+		if !isManyToMany(rel) {
+			continue
+		}
+		name, fromColumn, toColumn := junctionTable(rel)
+		if !copied {
+			result = append([]schema.Table(nil), tables...)
+			copied = true
+		}
+
+		index := -1
+		for i, table := range result {
+			if table.Name == name {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			result = append(result, schema.Table{Name: name})
+			index = len(result) - 1
+		}
+
+		junction := &result[index]
+		junction.Fields = append([]schema.Field(nil), junction.Fields...)
+		setJunctionColumn(junction, fromColumn, referencedType(tables, rel.FromTable, rel.FromField), rel.FromTable, rel.FromField)
+		setJunctionColumn(junction, toColumn, referencedType(tables, rel.ToTable, rel.ToField), rel.ToTable, rel.ToField)
+	}
+
+	return result
+}
+
+// setJunctionColumn makes a junction column a required foreign key, adding it when missing
+func setJunctionColumn(junction *schema.Table, column, fieldType, refTable, refField string) {
+	for i, field := range junction.Fields {
+		if field.Name != column {
+			continue
+		}
+		constraints := schema.Constraint{}
+		if field.Constraints != nil {
+			constraints = *field.Constraints
+		}
+		constraints.References = &schema.Reference{Table: refTable, Field: refField}
+		junction.Fields[i].Constraints = &constraints
+		junction.Fields[i].Required = true
+		return
+	}
+	junction.Fields = append(junction.Fields, schema.Field{
+		Name:        column,
+		Type:        fieldType,
+		Required:    true,
+		Constraints: &schema.Constraint{References: &schema.Reference{Table: refTable, Field: refField}},
+	})
+}
+
+// referencedType returns the type of a column, defaulting to int
+func referencedType(tables []schema.Table, tableName, fieldName string) string {
+	for _, table := range tables {
+		if table.Name != tableName {
+			continue
+		}
+		for _, field := range table.Fields {
+			if field.Name == fieldName {
+				return field.Type
+			}
+		}
+	}
+	return "int"
+}
+
+// linkRange resolves the min/max links per row of one side of a relationship
+func linkRange(minCount, maxCount *int, defaultMin, defaultMax, partners int) (int, int) {
+	min, max := defaultMin, defaultMax
+	if minCount != nil {
+		min = *minCount
+	}
+	if maxCount != nil {
+		max = *maxCount
+	}
+	if max < 0 || max > partners {
+		max = partners
+	}
+	if min > max && minCount == nil {
+		min = max
+	}
+	return min, max
+}
+
+// planLinks picks the distinct (from row, to row) pairs of a many_to_many
+// relationship, respecting the min/max links per row on both sides. Minimums
+// of the to side are met first, then every from row gets a random number of
+// links within its range. Rows of a table are never linked to themselves.
+func planLinks(r *rand.Rand, rel schema.Relationship, fromRows, toRows int) ([][2]int, error) {
+	sameTable := rel.FromTable == rel.ToTable
+	fromPartners, toPartners := toRows, fromRows
+	if sameTable {
+		fromPartners, toPartners = toRows-1, fromRows-1
+	}
+
+	minFrom, maxFrom := linkRange(rel.MinPerFrom, rel.MaxPerFrom, defaultMinLinks, defaultMaxLinks, fromPartners)
+	minTo, maxTo := linkRange(rel.MinPerTo, rel.MaxPerTo, 0, -1, toPartners)
+	if minFrom > maxFrom || minTo > maxTo || toRows*minTo > fromRows*maxFrom || fromRows*minFrom > toRows*maxTo {
+		return nil, fmt.Errorf("many_to_many %s <-> %s: cannot link %d and %d rows with %d-%d links per %s row and %d-%d per %s row",
+			rel.FromTable, rel.ToTable, fromRows, toRows, minFrom, maxFrom, rel.FromTable, minTo, maxTo, rel.ToTable)
+	}
+
+	linked := make(map[[2]int]bool)
+	fromCount := make([]int, fromRows)
+	toCount := make([]int, toRows)
+	canLink := func(i, j int) bool {
+		return !(sameTable && i == j) && !linked[[2]int{i, j}] && fromCount[i] < maxFrom && toCount[j] < maxTo
+	}
+	link := func(i, j int) {
+		linked[[2]int{i, j}] = true
+		fromCount[i]++
+		toCount[j]++
+	}
+
+	for _, j := range r.Perm(toRows) {
+		for toCount[j] < minTo {
+			// Prefer from rows still below their own minimum
+			i := pickRow(r, fromRows, func(i int) bool { return fromCount[i] < minFrom && canLink(i, j) })
+			if i < 0 {
+				i = pickRow(r, fromRows, func(i int) bool { return canLink(i, j) })
+			}
+			if i < 0 {
+				break
+			}
+			link(i, j)
+		}
+	}
+
+	for _, i := range r.Perm(fromRows) {
+		want := minFrom + r.IntN(maxFrom-minFrom+1)
+		for fromCount[i] < want {
+			j := pickRow(r, toRows, func(j int) bool { return canLink(i, j) })
+			if j < 0 {
+				break
+			}
+			link(i, j)
+		}
+	}
+
+	for i := range fromCount {
+		if fromCount[i] < minFrom {
+			return nil, fmt.Errorf("many_to_many %s <-> %s: could not give every %s row at least %d links", rel.FromTable, rel.ToTable, rel.FromTable, minFrom)
+		}
+	}
+	for j := range toCount {
+		if toCount[j] < minTo {
+			return nil, fmt.Errorf("many_to_many %s <-> %s: could not give every %s row at least %d links", rel.FromTable, rel.ToTable, rel.ToTable, minTo)
+		}
+	}
+
+	pairs := make([][2]int, 0, len(linked))
+	for pair := range linked {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(a, b int) bool {
+		if pairs[a][0] != pairs[b][0] {
+			return pairs[a][0] < pairs[b][0]
+		}
+		return pairs[a][1] < pairs[b][1]
+	})
+	return pairs, nil
+}
+
+// pickRow returns a random row index accepted by ok, or -1 when there is none.
+// A few random probes are tried before falling back to a scan.
+func pickRow(r *rand.Rand, n int, ok func(int) bool) int {
+	if n == 0 {
+		return -1
+	}
+	for attempt := 0; attempt < 32; attempt++ {
+		if i := r.IntN(n); ok(i) {
+			return i
+		}
+	}
+	start := r.IntN(n)
+	for k := 0; k < n; k++ {
+		if i := (start + k) % n; ok(i) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"go-fake/internal/schema"
+	"math/rand/v2"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLengthAttempts bounds the values regenerated to fit min/max length
+// before they are truncated or extended
+const maxLengthAttempts = 10
+
+// lengthLimits fits generated strings to the min_length and max_length of a field
+type lengthLimits struct {
+	min, max int  // max is 0 when unbounded
+	fixed    bool // pad to max with spaces, as CHAR(n) does
+}
+
+// newLengthLimits compiles the length limits of a field, or returns nil when it has none
+func newLengthLimits(field schema.Field) *lengthLimits {
+	if field.MinLength == nil && field.MaxLength == nil {
+		return nil
+	}
+	l := &lengthLimits{fixed: field.FixedLength}
+	if field.MinLength != nil {
+		l.min = *field.MinLength
+	}
+	if field.MaxLength != nil {
+		l.max = *field.MaxLength
+	}
+	return l
+}
+
+// fits reports whether a string is within the limits
+func (l *lengthLimits) fits(s string) bool {
+	n := utf8.RuneCountInString(s)
+	return n >= l.min && (l.max == 0 || n <= l.max)
+}
+
+// fit makes a generated string satisfy the limits. Values that do not fit are
+// regenerated a few times; a value still too short is extended with further
+// generated values separated by spaces, and one too long is truncated.
+// Fixed-length values are then padded with spaces. Values that are not
+// strings are returned unchanged.
+func (l *lengthLimits) fit(r *rand.Rand, value interface{}, regenerate func(*rand.Rand) interface{}) interface{} {
+	s, ok := value.(string)
+	if !ok {
+		return value
+	}
+
+	for attempt := 0; !l.fits(s) && attempt < maxLengthAttempts; attempt++ {
+		if next, ok := regenerate(r).(string); ok {
+			s = next
+		}
+	}
+	for utf8.RuneCountInString(s) < l.min {
+		next, ok := regenerate(r).(string)
+		if !ok || next == "" {
+			break
+		}
+		s += " " + next
+	}
+	if l.max > 0 && utf8.RuneCountInString(s) > l.max {
+		s = truncate(s, l.max)
+		// Do not leave a dangling space where a word was cut
+		if trimmed := strings.TrimRight(s, " "); utf8.RuneCountInString(trimmed) >= l.min {
+			s = trimmed
+		}
+	}
+	if l.fixed {
+		if n := utf8.RuneCountInString(s); n < l.max {
+			s += strings.Repeat(" ", l.max-n)
+		}
+	}
+	return s
+}
+
+// truncate cuts a string to at most n characters
+func truncate(s string, n int) string {
+	i := 0
+	for pos := range s {
+		if i == n {
+			return s[:pos]
+		}
+		i++
+	}
+	return s
+}
@@ -0,0 +1,68 @@
+package generator
+
+import (
+	"fmt"
+	"go-fake/internal/schema"
+	"slices"
+)
+
+// nullRatio returns the share of null values generated for a field. Required,
+// primary key and auto-increment columns are never null.
+func nullRatio(field schema.Field) float64 {
+	if field.Required || field.PrimaryKey || field.AutoIncrement || field.NullRatio == nil {
+		return 0
+	}
+	return *field.NullRatio
+}
+
+// keyNullRatio returns the share of rows in which a composite foreign key is
+// null as a whole: the lowest null ratio of its columns, so that a key with a
+// required column is never null
+func keyNullRatio(table schema.Table, columns []string) float64 {
+	ratio := 1.0
+	for _, field := range table.Fields {
+		if slices.Contains(columns, field.Name) && nullRatio(field) < ratio {
+			ratio = nullRatio(field)
+		}
+	}
+	return ratio
+}
+
+// withFieldRatios returns the schema with its null_ratio and default_ratio
+// copied onto every field that does not set its own, so that generation only
+// has to look at fields. The input is not modified.
+func withFieldRatios(s schema.Schema) schema.Schema {
+	if s.NullRatio == nil && s.DefaultRatio == nil {
+		return s
+	}
+
+	apply := func(fields []schema.Field) []schema.Field {
+		result := append([]schema.Field(nil), fields...)
+		for i := range result {
+			if result[i].NullRatio == nil {
+				result[i].NullRatio = s.NullRatio
+			}
+			if result[i].DefaultRatio == nil {
+				result[i].DefaultRatio = s.DefaultRatio
+			}
+		}
+		return result
+	}
+
+	tables := make([]schema.Table, len(s.Tables))
+	for i, table := range s.Tables {
+		tables[i] = table
+		tables[i].Fields = apply(table.Fields)
+	}
+	s.Tables = tables
+	s.Fields = apply(s.Fields)
+	return s
+}
+
+// formatCSVValue renders a value for a CSV cell, writing nulls as the null token
+func formatCSVValue(value interface{}, nullToken string) string {
+	if value == nil {
+		return nullToken
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -14,6 +14,8 @@ type PerformanceConfig struct {
 	BatchSize          int  // Number of rows to generate in each batch
 	PreallocateMemory  bool // Pre-allocate slices and maps for better memory usage
 	CacheFieldInference bool // Cache field inference results
+	Seed               int64 // Seed for reproducible output (0 = random)
+	NullToken          string // Text written to CSV files for null values
 }
 
 // DefaultPerformanceConfig returns optimized default settings
@@ -41,16 +43,22 @@ func NewFieldInferenceCache() *FieldInferenceCache {
 	}
 }
 
-// Get retrieves cached inference result
+// Get retrieves cached inference result. A nil cache never has a result.
 func (c *FieldInferenceCache) Get(fieldName string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 	result, exists := c.cache[fieldName]
 	return result, exists
 }
 
-// Set stores inference result in cache
+// Set stores inference result in cache. Setting on a nil cache is a no-op.
 func (c *FieldInferenceCache) Set(fieldName, inferredType string) {
+	if c == nil {
+		return
+	}
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 	c.cache[fieldName] = inferredType
@@ -68,122 +76,142 @@ func NewParallelTableGenerator(config PerformanceConfig) *ParallelTableGenerator
 	return &ParallelTableGenerator{
 		config:         config,
 		inferenceCache: NewFieldInferenceCache(),
-		fieldInference: NewFieldTypeInference(),
+		fieldInference: fieldInference, // share the global instance so -ai applies here too
 	}
 }
 
-// GenerateTablesParallel generates multiple tables in parallel
-func (ptg *ParallelTableGenerator) GenerateTablesParallel(tables []schema.Table, numRows int, relData *RelationshipData, relationships []schema.Relationship) {
-	// Separate independent and dependent tables
-	var independentTables, dependentTables []schema.Table
-	
-	for _, table := range tables {
-		if !hasReferences(table.Fields) {
-			independentTables = append(independentTables, table)
-		} else {
-			dependentTables = append(dependentTables, table)
-		}
+// GenerateTablesParallel generates multiple tables in parallel, one dependency level at a time
+func (ptg *ParallelTableGenerator) GenerateTablesParallel(seed int64, tables []schema.Table, numRows int, relData *RelationshipData, relationships []schema.Relationship) error {
+	plan, err := planGeneration(tables, relationships)
+	if err != nil {
+		return err
 	}
 	
-	// Generate independent tables in parallel
-	if len(independentTables) > 0 {
-		logger.Debug("Generating %d independent tables in parallel", len(independentTables))
-		ptg.generateTablesBatch(independentTables, numRows, relData, relationships)
+	// Tables in a level only reference earlier levels, so each level can run in parallel
+	for depth, level := range plan.levels {
+		logger.Debug("Generating dependency level %d (%d tables) in parallel", depth, len(level))
+		if err := ptg.generateTablesBatch(seed, level, numRows, relData, plan); err != nil {
+			return err
+		}
 	}
 	
-	// Generate dependent tables in parallel (but after independent ones)
-	if len(dependentTables) > 0 {
-		logger.Debug("Generating %d dependent tables in parallel", len(dependentTables))
-		ptg.generateTablesBatch(dependentTables, numRows, relData, relationships)
+	if err := fillDeferredReferences(seed, plan, relData); err != nil {
+		return err
 	}
+	fillAggregates(seed, ptg.fieldInference, plan, relData)
+	return nil
 }
 
-// generateTablesBatch generates a batch of tables in parallel
-func (ptg *ParallelTableGenerator) generateTablesBatch(tables []schema.Table, numRows int, relData *RelationshipData, relationships []schema.Relationship) {
+// generateTablesBatch generates a batch of tables in parallel. When several
+// tables fail, the error of the first one in schema order is returned.
+func (ptg *ParallelTableGenerator) generateTablesBatch(seed int64, tables []schema.Table, numRows int, relData *RelationshipData, plan *generationPlan) error {
 	var wg sync.WaitGroup
 	semaphore := make(chan struct{}, ptg.config.WorkerPoolSize)
+	results := make([][]map[string]interface{}, len(tables))
+	errs := make([]error, len(tables))
 	
-	for _, table := range tables {
+	for i, table := range tables {
 		wg.Add(1)
-		go func(t schema.Table) {
+		go func(i int, t schema.Table) {
 			defer wg.Done()
 			semaphore <- struct{}{} // Acquire worker
 			defer func() { <-semaphore }() // Release worker
 			
 			logger.Debug("Generating data for table: %s", t.Name)
-			data := ptg.generateTableDataOptimized(t.Fields, numRows, t.Name, relData, relationships)
-			
-			// Thread-safe update of relData
-			relData.mutex.Lock()
-			relData.TableData[t.Name] = data
-			populateReferences(t.Name, t.Fields, data, relData)
-			relData.mutex.Unlock()
-		}(table)
+			results[i], errs[i] = ptg.generateTableDataOptimized(seed, t, numRows, relData, plan)
+			if errs[i] == nil {
+				fillSelfReferences(seed, t, results[i])
+			}
+		}(i, table)
 	}
 	
 	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	
+	// Publish results in schema order once the whole batch is done, so that no
+	// table observes a sibling's data depending on which goroutine finished first
+	relData.mutex.Lock()
+	defer relData.mutex.Unlock()
+	for i, t := range tables {
+		relData.TableData[t.Name] = results[i]
+		populateReferences(t.Name, t.Fields, results[i], relData)
+	}
+	return nil
 }
 
 // generateTableDataOptimized generates table data with performance optimizations
-func (ptg *ParallelTableGenerator) generateTableDataOptimized(fields []schema.Field, numRows int, tableName string, relData *RelationshipData, relationships []schema.Relationship) []map[string]interface{} {
-	// Pre-allocate slice with exact capacity
-	rows := make([]map[string]interface{}, 0, numRows)
-	
-	// Cache field inference for all fields once
-	fieldTypes := make(map[string]string, len(fields))
+func (ptg *ParallelTableGenerator) generateTableDataOptimized(seed int64, table schema.Table, numRows int, relData *RelationshipData, plan *generationPlan) ([]map[string]interface{}, error) {
+	// Compile field generators once; with caching enabled, inferred types are
+	// shared between tables that have fields with the same name and type
+	var cache *FieldInferenceCache
 	if ptg.config.CacheFieldInference {
-		for _, field := range fields {
-			if cachedType, exists := ptg.inferenceCache.Get(field.Name); exists {
-				fieldTypes[field.Name] = cachedType
-			} else {
-				inferredType := ptg.fieldInference.InferFieldType(field)
-				fieldTypes[field.Name] = inferredType
-				ptg.inferenceCache.Set(field.Name, inferredType)
-			}
-		}
+		cache = ptg.inferenceCache
 	}
+	tg := compileTable(seed, ptg.fieldInference, cache, table, plan)
+	numRows, assignments, err := planRowCount(seed, table, numRows, plan.relationships, relData)
+	if err != nil {
+		return nil, err
+	}
+	tg.assignParents(assignments)
+	if err := tg.checkValueRanges(); err != nil {
+		return nil, err
+	}
+	if err := tg.checkRowCount(numRows); err != nil {
+		return nil, err
+	}
+	
+	// Pre-allocate slice with exact capacity
+	rows := make([]map[string]interface{}, 0, numRows)
 	
 	// Generate rows in batches for better memory usage
 	batchSize := ptg.config.BatchSize
 	if numRows < batchSize {
 		batchSize = numRows
 	}
+	if batchSize <= 0 {
+		return rows, nil
+	}
+	
+	// Each batch draws from its own stream, so batches can run concurrently
+	// and still produce the same rows as a single worker would
+	numBatches := (numRows + batchSize - 1) / batchSize
+	batches := make([][]map[string]interface{}, numBatches)
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, ptg.config.WorkerPoolSize)
 	
-	for batchStart := 0; batchStart < numRows; batchStart += batchSize {
+	for b := 0; b < numBatches; b++ {
+		batchStart := b * batchSize
 		batchEnd := batchStart + batchSize
 		if batchEnd > numRows {
 			batchEnd = numRows
 		}
 		
-		// Generate batch of rows
-		batch := ptg.generateRowBatch(fields, batchStart, batchEnd, tableName, relData, fieldTypes)
-		rows = append(rows, batch...)
+		wg.Add(1)
+		go func(b, batchStart, batchEnd int) {
+			defer wg.Done()
+			semaphore <- struct{}{} // Acquire worker
+			defer func() { <-semaphore }() // Release worker
+			
+			batches[b] = tg.generateRows(deriveRand(seed, table.Name, b), batchStart, batchEnd, relData)
+		}(b, batchStart, batchEnd)
 	}
 	
-	return rows
-}
-
-// generateRowBatch generates a batch of rows efficiently
-func (ptg *ParallelTableGenerator) generateRowBatch(fields []schema.Field, startRow, endRow int, tableName string, relData *RelationshipData, fieldTypes map[string]string) []map[string]interface{} {
-	batchSize := endRow - startRow
-	batch := make([]map[string]interface{}, 0, batchSize)
+	wg.Wait()
 	
-	for i := startRow; i < endRow; i++ {
-		row := make(map[string]interface{}, len(fields))
-		
-		for _, field := range fields {
-			// Use cached inference type if available
-			var value interface{}
-			if ptg.config.CacheFieldInference && fieldTypes[field.Name] != "" {
-				value = ptg.fieldInference.GenerateValueByType(fieldTypes[field.Name], field.Name)
-			} else {
-				value = generateConstrainedValue(field, relData, tableName, i)
-			}
-			row[field.Name] = value
-		}
-		
-		batch = append(batch, row)
+	for _, batch := range batches {
+		rows = append(rows, batch...)
 	}
 	
-	return batch
+	// Duplicates are resolved over the whole table, after every batch is done
+	if err := tg.enforceUniqueness(seed, rows, relData); err != nil {
+		return nil, err
+	}
+	if err := tg.enforceKeyUniqueness(seed, rows, relData); err != nil {
+		return nil, err
+	}
+	return rows, nil
 }
@@ -0,0 +1,121 @@
+package generator
+
+import (
+	"go-fake/internal/expr"
+	"go-fake/internal/schema"
+	"go-fake/pkg/logger"
+	"math/rand/v2"
+)
+
+// fieldRule is the compiled form of a when rule: in the rows matching its
+// condition the field is null, fixed, computed or generated by an override
+type fieldRule struct {
+	cond     *expr.Expr // nil matches every row
+	null     bool
+	value    interface{}
+	computed *computedField
+	override *fieldGenerator // the field with the rule's type and constraints
+}
+
+// compileRules compiles the when rules of a field. Rules with an invalid
+// condition are ignored; schema validation reports them.
+func (tg *tableGenerator) compileRules(seed int64, cache *FieldInferenceCache, fg *fieldGenerator, plan *generationPlan) {
+	if fg.field.Constraints == nil {
+		return
+	}
+	for _, rule := range fg.field.Constraints.When {
+		compiled := &fieldRule{null: rule.Null, value: rule.Value}
+		if rule.If != "" {
+			cond, err := expr.Parse(rule.If)
+			if err != nil {
+				logger.Debug("Field %s: ignoring when rule: %v", fg.field.Name, err)
+				continue
+			}
+			compiled.cond = cond
+		}
+
+		field := fg.field
+		field.Expression = rule.Expression
+		compiled.computed = newComputedField(field)
+
+		if rule.Type != "" || rule.Constraints != nil {
+			field.Expression = ""
+			field.Default = nil // the rule decides the value instead of the column default
+			if rule.Type != "" {
+				field.Type = rule.Type
+			}
+			field.Constraints = mergeConstraints(fg.field.Constraints, rule.Constraints)
+			compiled.override = compileField(seed, fg.inference, cache, tg.name, field, fg.composite, plan)
+			tg.compileDependency(compiled.override, plan)
+		}
+		fg.rules = append(fg.rules, compiled)
+	}
+}
+
+// mergeConstraints returns the constraints of a field with those a rule sets
+// replaced, without the field's when rules. References and hierarchies are
+// kept, since rules cannot change them.
+func mergeConstraints(base, override *schema.Constraint) *schema.Constraint {
+	merged := schema.Constraint{}
+	if base != nil {
+		merged = *base
+	}
+	merged.When = nil
+	if override == nil {
+		return &merged
+	}
+	if override.DependsOn != nil {
+		merged.DependsOn = override.DependsOn
+	}
+	if override.Pattern != "" {
+		merged.Pattern = override.Pattern
+	}
+	if override.MinValue != nil {
+		merged.MinValue = override.MinValue
+	}
+	if override.MaxValue != nil {
+		merged.MaxValue = override.MaxValue
+	}
+	if override.UniqueCount != nil {
+		merged.UniqueCount = override.UniqueCount
+	}
+	if override.Enum != nil {
+		merged.Enum = override.Enum
+	}
+	if override.Distribution != nil {
+		merged.Distribution = override.Distribution
+	}
+	if override.MinDate != "" {
+		merged.MinDate = override.MinDate
+	}
+	if override.MaxDate != "" {
+		merged.MaxDate = override.MaxDate
+	}
+	return &merged
+}
+
+// matchRule returns the first rule whose condition holds for the row, or nil
+func (fg *fieldGenerator) matchRule(row map[string]interface{}) *fieldRule {
+	for _, rule := range fg.rules {
+		if rule.cond == nil || expr.Truthy(rule.cond.Eval(expr.Env{Row: row})) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// generate produces the value a rule sets. It reports false for rules with no
+// action, which keep the field's own generator.
+func (rule *fieldRule) generate(r *rand.Rand, rowIndex int, row map[string]interface{}, relData *RelationshipData) (interface{}, bool) {
+	switch {
+	case rule.null:
+		return nil, true
+	case rule.value != nil:
+		return rule.value, true
+	case rule.computed != nil:
+		return rule.computed.value(row, nil), true
+	case rule.override != nil:
+		return rule.override.generate(r, rowIndex, row, relData), true
+	}
+	return nil, false
+}
@@ -0,0 +1,370 @@
+package generator
+
+import (
+	"fmt"
+	"go-fake/internal/schema"
+	"go-fake/pkg/logger"
+	"math"
+	"math/bits"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// isUnique reports whether every value of a field must be distinct
+func isUnique(field schema.Field) bool {
+	return field.PrimaryKey || field.Unique
+}
+
+// integerRange returns the explicit value range of an integer field
+func integerRange(field schema.Field, inferredType string) (int, int, bool) {
+	if inferredType != "int" && inferredType != "integer" && inferredType != "age" {
+		return 0, 0, false
+	}
+	minValue, maxValue := numericBounds(field)
+	if minValue == nil && maxValue == nil {
+		if inferredType == "age" {
+			return 18, 80, true
+		}
+		return 0, 0, false
+	}
+	min, max := valueRange(minValue, maxValue, 1, 1000)
+	return int(math.Ceil(min)), int(math.Floor(max)), true
+}
+
+// permutation maps row indexes onto distinct values of [min, min+size) with an
+// affine bijection, so unique integers need no coordination between batches.
+type permutation struct {
+	min       int
+	size      uint64
+	step, off uint64
+}
+
+// newPermutation draws a random bijection over size values
+func newPermutation(r *rand.Rand, min int, size uint64) *permutation {
+	p := &permutation{min: min, size: size, step: 1}
+	if size > 1 {
+		for {
+			p.step = 1 + r.Uint64N(size-1)
+			if gcd(p.step, size) == 1 {
+				break
+			}
+		}
+		p.off = r.Uint64N(size)
+	}
+	return p
+}
+
+// value returns the value for one row index
+func (p *permutation) value(rowIndex int) int {
+	hi, lo := bits.Mul64(p.step, uint64(rowIndex))
+	_, rem := bits.Div64(hi%p.size, lo, p.size)
+	return p.min + int((rem+p.off)%p.size)
+}
+
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// compileUnique picks a collision-free strategy for unique integer fields:
+// sequential ids for auto-increment columns and unbounded integer keys, and a
+// permutation of the range for integers with explicit bounds.
+func (fg *fieldGenerator) compileUnique(seed int64, tableName string) {
+	field := fg.field
+	if fg.refTable != "" || fg.composite || fg.computed != nil || fg.enum != nil || fg.pattern != nil || fg.distribution != nil || len(fg.uniqueValues) > 0 {
+		return
+	}
+
+	if field.AutoIncrement {
+		fg.sequential = true
+		return
+	}
+	if !isUnique(field) {
+		return
+	}
+
+	if min, max, ok := integerRange(field, fg.inferredType); ok {
+		if max < min {
+			return
+		}
+		r := deriveRand(seed, tableName+"."+field.Name+":unique", 0)
+		fg.permutation = newPermutation(r, min, uint64(max-min)+1)
+		return
+	}
+	if fg.inferredType == "int" || fg.inferredType == "integer" {
+		fg.sequential = true
+	}
+}
+
+// sequenceStart is the first id of an auto-increment column
+func (fg *fieldGenerator) sequenceStart() int {
+	if fg.field.Constraints != nil && fg.field.Constraints.MinValue != nil {
+		return int(math.Ceil(*fg.field.Constraints.MinValue))
+	}
+	return 1
+}
+
+// valueSpace returns how many distinct values a field can take, or 0 when unknown
+func (fg *fieldGenerator) valueSpace() uint64 {
+	switch {
+	case fg.sequential:
+		return 0
+	case fg.permutation != nil:
+		return fg.permutation.size
+	case len(fg.uniqueValues) > 0:
+		return uint64(len(fg.uniqueValues))
+	case fg.refTable == "" && fg.enum != nil:
+		return fg.enum.size()
+	case fg.refTable == "" && fg.inferredType == "boolean":
+		return 2
+	}
+	return 0
+}
+
+// checkRowCount fails early when a unique column cannot hold numRows distinct values
+func (tg *tableGenerator) checkRowCount(numRows int) error {
+	for _, fg := range tg.fields {
+		if !isUnique(fg.field) {
+			continue
+		}
+		if space := fg.valueSpace(); space > 0 && uint64(numRows) > space {
+			return fmt.Errorf("cannot generate %d rows for table %s: unique column %s only has %d possible values",
+				numRows, tg.name, fg.field.Name, space)
+		}
+	}
+	return nil
+}
+
+// enforceUniqueness replaces duplicate values in primary key and unique
+// columns, and regenerates the fields that read a replaced value. Columns are
+// checked in generation order and rows are walked in order with a stream
+// derived from the seed, so the result does not depend on how the rows were
+// batched. Null values never count as duplicates.
+func (tg *tableGenerator) enforceUniqueness(seed int64, rows []map[string]interface{}, relData *RelationshipData) error {
+	for _, fg := range tg.order {
+		if !isUnique(fg.field) || fg.delayed || fg.composite || fg.computed != nil || fg.sequential || fg.permutation != nil {
+			continue
+		}
+
+		name := fg.field.Name
+		r := deriveRand(seed, tg.name+"."+name+":unique", 0)
+		seen := make(map[interface{}]bool, len(rows))
+		suffix := 1
+		replaced := 0
+
+		for i, row := range rows {
+			value := row[name]
+			if value == nil {
+				continue
+			}
+			if seen[value] {
+				var err error
+				if value, err = fg.uniqueValue(r, i, row, relData, seen, &suffix); err != nil {
+					return fmt.Errorf("table %s: %v", tg.name, err)
+				}
+				row[name] = value
+				tg.refreshDependents(r, i, row, []string{name}, relData)
+				replaced++
+			}
+			seen[value] = true
+		}
+
+		if replaced > 0 {
+			logger.Debug("Replaced %d duplicate values in unique column %s.%s", replaced, tg.name, name)
+		}
+	}
+	return nil
+}
+
+// uniqueValue finds a value not in seen for one row. Regular values are
+// retried a bounded number of times; strings then fall back to a numeric
+// suffix, and foreign keys to a parent value not used yet.
+func (fg *fieldGenerator) uniqueValue(r *rand.Rand, rowIndex int, row map[string]interface{}, relData *RelationshipData, seen map[interface{}]bool, suffix *int) (interface{}, error) {
+	name := fg.field.Name
+
+	if fg.refTable != "" {
+		if relData == nil {
+			return nil, fmt.Errorf("unique foreign key %s has no referenced values", name)
+		}
+		values := relData.References[fg.refTable+"."+fg.refField]
+		if fg.parentRows == nil && len(values) > 0 {
+			start := r.IntN(len(values))
+			for i := range values {
+				if value := values[(start+i)%len(values)]; !seen[value] {
+					return value, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("unique foreign key %s needs more distinct %s.%s values than the %d available",
+			name, fg.refTable, fg.refField, len(values))
+	}
+
+	var last interface{}
+	for attempt := 0; attempt < maxUniqueAttemptsPerValue; attempt++ {
+		value := fg.generate(r, rowIndex, row, relData)
+		if value != nil && !seen[value] {
+			return value, nil
+		}
+		last = value
+	}
+
+	// Enum, pattern and unique_count values must stay within their value set
+	if base, ok := last.(string); ok && fg.enum == nil && fg.pattern == nil && len(fg.uniqueValues) == 0 {
+		for ; ; *suffix++ {
+			value := fg.withSuffix(base, *suffix)
+			if fg.length != nil && !fg.length.fits(value) {
+				break
+			}
+			if !seen[value] {
+				*suffix++
+				return value, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("could not find a distinct value for unique column %s after %d attempts; its value space (%s) is too small for the requested rows",
+		name, maxUniqueAttemptsPerValue, fg.inferredType)
+}
+
+// withSuffix makes a value distinct by appending a number, keeping emails
+// valid. The value is cut to leave room for the number within max_length, and
+// fixed-length values are padded again.
+func (fg *fieldGenerator) withSuffix(value string, n int) string {
+	head, tail := value, ""
+	if fg.inferredType == "email" {
+		if at := strings.LastIndex(value, "@"); at >= 0 {
+			head, tail = value[:at], value[at:]
+		}
+	}
+	suffix := strconv.Itoa(n)
+	if fg.length == nil || fg.length.max == 0 {
+		return head + suffix + tail
+	}
+
+	head = strings.TrimRight(head, " ")
+	room := fg.length.max - utf8.RuneCountInString(suffix+tail)
+	if room < 0 {
+		room = 0
+	}
+	value = truncate(head, room) + suffix + tail
+	if length := utf8.RuneCountInString(value); fg.length.fixed && length < fg.length.max {
+		value += strings.Repeat(" ", fg.length.max-length)
+	}
+	return value
+}
+
+// keyIsDistinct reports whether a key already contains a column whose values
+// are distinct on their own, which makes the combined values distinct too
+func (tg *tableGenerator) keyIsDistinct(key []string) bool {
+	for _, name := range key {
+		fg := tg.field(name)
+		if fg == nil {
+			continue
+		}
+		if fg.sequential || fg.permutation != nil || (isUnique(fg.field) && !fg.composite && !fg.delayed) {
+			return true
+		}
+	}
+	return false
+}
+
+// tupleKey joins the values of a key into one comparable string. It reports
+// false when one of the values is null, since such rows never collide.
+func tupleKey(row map[string]interface{}, key []string) (string, bool) {
+	var b strings.Builder
+	for _, name := range key {
+		value := row[name]
+		if value == nil {
+			return "", false
+		}
+		fmt.Fprintf(&b, "%v\x1f", value)
+	}
+	return b.String(), true
+}
+
+// enforceKeyUniqueness makes the combined values of composite primary and
+// unique keys distinct. A colliding row gets its key columns regenerated,
+// picking a new parent row for composite foreign keys, a bounded number of times.
+func (tg *tableGenerator) enforceKeyUniqueness(seed int64, rows []map[string]interface{}, relData *RelationshipData) error {
+	for _, key := range tg.uniqueKeys {
+		if len(key) == 0 || (len(key) > 1 && tg.keyIsDistinct(key)) {
+			continue
+		}
+
+		columns := strings.Join(key, ", ")
+		r := deriveRand(seed, tg.name+"."+strings.Join(key, ",")+":unique", 0)
+		seen := make(map[string]bool, len(rows))
+		replaced := 0
+
+		for i, row := range rows {
+			value, ok := tupleKey(row, key)
+			for attempt := 0; ok && seen[value]; attempt++ {
+				if attempt == maxUniqueAttemptsPerValue {
+					return fmt.Errorf("table %s: could not find a distinct value for unique key (%s) after %d attempts; its value space is too small for the requested rows",
+						tg.name, columns, maxUniqueAttemptsPerValue)
+				}
+				tg.regenerateKey(r, i, row, key, relData)
+				value, ok = tupleKey(row, key)
+				replaced++
+			}
+			if ok {
+				seen[value] = true
+			}
+		}
+
+		if replaced > 0 {
+			logger.Debug("Regenerated %d duplicate values of unique key %s.(%s)", replaced, tg.name, columns)
+		}
+	}
+	return nil
+}
+
+// regenerateKey draws new values for the columns of a key in one row
+func (tg *tableGenerator) regenerateKey(r *rand.Rand, rowIndex int, row map[string]interface{}, key []string, relData *RelationshipData) {
+	inKey := make(map[string]bool, len(key))
+	for _, name := range key {
+		inKey[name] = true
+	}
+
+	for _, fk := range tg.foreignKeys {
+		for _, name := range fk.Fields {
+			if inKey[name] {
+				tg.pickParentKey(r, row, fk.ForeignKey, relData)
+				break
+			}
+		}
+	}
+	for _, name := range key {
+		if fg := tg.field(name); fg != nil && !fg.composite && !fg.delayed {
+			row[name] = fg.generate(r, rowIndex, row, relData)
+		}
+	}
+	tg.refreshDependents(r, rowIndex, row, key, relData)
+}
+
+// refreshDependents regenerates the fields of a row that read the changed
+// columns, directly or through other fields, in dependency order
+func (tg *tableGenerator) refreshDependents(r *rand.Rand, rowIndex int, row map[string]interface{}, changed []string, relData *RelationshipData) {
+	stale := make(map[string]bool, len(changed))
+	for _, name := range changed {
+		stale[name] = true
+	}
+	for _, fg := range tg.order {
+		if stale[fg.field.Name] {
+			continue
+		}
+		for _, name := range fg.inputs() {
+			if stale[name] {
+				stale[fg.field.Name] = true
+				if !fg.composite && !fg.delayed {
+					row[fg.field.Name] = fg.generate(r, rowIndex, row, relData)
+				}
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,654 @@
+package parser
+
+import (
+	"fmt"
+	"go-fake/internal/dialect"
+	"go-fake/internal/schema"
+	"go-fake/pkg/faker"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// checkOperand is one side of a condition of a CHECK constraint: a column,
+// the length of a column, a literal or the current date or time
+type checkOperand struct {
+	field  *schema.Field
+	length bool        // length(field)
+	value  interface{} // int, float64 or string literal
+	now    string      // now or today
+}
+
+// flipped are the comparison operators with their operands swapped
+var flipped = map[string]string{
+	"=": "=", "<>": "<>", "!=": "!=", "<": ">", "<=": ">=", ">": "<", ">=": "<=",
+}
+
+// dependencyOperators are the depends_on operators of the comparisons
+// between a column and an earlier one
+var dependencyOperators = map[string]string{
+	">": "after", ">=": "on_or_after", "<": "before", "<=": "on_or_before",
+}
+
+// lengthFunctions are the functions that measure a string
+var lengthFunctions = map[string]bool{
+	"length": true, "char_length": true, "character_length": true, "len": true,
+}
+
+// currentTime are the functions and keywords for the current time, such as
+// now(), CURRENT_DATE or SQLite date('now'). Only those with a * are also
+// used without parentheses.
+var currentTime = map[string]string{
+	"current_timestamp*": "now", "localtimestamp*": "now", "sysdate*": "now", "current_date*": "today",
+	"now": "now", "getdate": "now", "getutcdate": "now", "sysdatetime": "now",
+	"utc_timestamp": "now", "datetime": "now", "curdate": "today", "date": "today",
+}
+
+// applyCheck maps a CHECK constraint onto the constraints of the columns it
+// names. Conditions joined by AND are applied one by one; those that no
+// constraint can express are reported as warnings.
+func (p *sqlParser) applyCheck(table *schema.Table, body []sqlToken) {
+	for _, cond := range splitCondition(unwrap(body), "AND") {
+		if cond = unwrap(cond); len(cond) == 0 {
+			continue
+		}
+		if err := p.applyCondition(table, cond); err != nil {
+			p.warnf(cond[0], "ignoring CHECK (%s) on table %s: %v", p.text(cond), table.Name, err)
+		}
+	}
+}
+
+// applyCondition applies one condition of a CHECK constraint
+func (p *sqlParser) applyCondition(table *schema.Table, cond []sqlToken) error {
+	// col IS NULL OR ... allows the nulls a nullable column has anyway
+	if parts := splitCondition(cond, "OR"); len(parts) > 1 {
+		var rest [][]sqlToken
+		for _, part := range parts {
+			if part = unwrap(part); len(part) < 3 || !part[len(part)-2].is("IS") || !part[len(part)-1].is("NULL") {
+				rest = append(rest, part)
+			}
+		}
+		if len(rest) != 1 {
+			return fmt.Errorf("OR is not supported")
+		}
+		p.applyCheck(table, rest[0])
+		return nil
+	}
+	if cond[0].is("NOT") {
+		return fmt.Errorf("NOT is not supported")
+	}
+
+	i := topLevel(cond, func(tok sqlToken) bool {
+		return tok.is("IS") || tok.is("BETWEEN") || tok.is("IN") || tok.is("NOT") || tok.is("LIKE") ||
+			tok.is("ILIKE") || tok.is("REGEXP") || tok.is("RLIKE") || tok.is("SIMILAR") ||
+			(tok.kind == sqlSymbol && (flipped[tok.text] != "" || strings.Contains(tok.text, "~")))
+	})
+	if i < 0 {
+		return fmt.Errorf("no comparison found")
+	}
+	left, op, right := cond[:i], cond[i], cond[i+1:]
+	switch {
+	case op.is("IS"):
+		if len(right) != 2 || !right[0].is("NOT") || !right[1].is("NULL") {
+			return fmt.Errorf("only IS NOT NULL is supported")
+		}
+		field, err := p.column(table, left)
+		if err != nil {
+			return err
+		}
+		field.Required = true
+		return nil
+	case op.is("BETWEEN"):
+		j := topLevel(right, func(tok sqlToken) bool { return tok.is("AND") })
+		if j < 0 {
+			return fmt.Errorf("BETWEEN without AND")
+		}
+		if err := p.applyComparison(table, left, ">=", right[:j]); err != nil {
+			return err
+		}
+		return p.applyComparison(table, left, "<=", right[j+1:])
+	case op.is("IN"):
+		if len(right) < 2 || !right[0].isSymbol("(") || closing(right, 0) != len(right)-1 {
+			return fmt.Errorf("IN needs a list of values")
+		}
+		return p.applyValues(table, left, right[1:len(right)-1])
+	case op.isSymbol("=") && len(right) > 0 && (right[0].is("ANY") || right[0].is("SOME")):
+		// Postgres writes IN (...) as = ANY (ARRAY[...])
+		list := stripCasts(right[1:])
+		if len(list) < 3 || !list[0].is("ARRAY") || !list[1].isSymbol("[") || !list[len(list)-1].isSymbol("]") {
+			return fmt.Errorf("ANY needs an ARRAY[...] of values")
+		}
+		return p.applyValues(table, left, list[2:len(list)-1])
+	case op.is("LIKE"), op.is("ILIKE"), op.isSymbol("~~"), op.isSymbol("~~*"):
+		return p.applyLike(table, left, right)
+	case op.is("REGEXP"), op.is("RLIKE"), op.isSymbol("~"), op.isSymbol("~*"):
+		return p.applyRegexp(table, left, right, op.isSymbol("~*"))
+	case op.kind == sqlSymbol && flipped[op.text] != "":
+		return p.applyComparison(table, left, op.text, right)
+	}
+	return fmt.Errorf("%s is not supported", strings.ToUpper(op.text))
+}
+
+// applyComparison applies a comparison between two operands
+func (p *sqlParser) applyComparison(table *schema.Table, leftTokens []sqlToken, op string, rightTokens []sqlToken) error {
+	left, err := p.operand(table, leftTokens)
+	if err != nil {
+		return err
+	}
+	right, err := p.operand(table, rightTokens)
+	if err != nil {
+		return err
+	}
+	if left.field == nil {
+		left, right, op = right, left, flipped[op]
+	}
+
+	switch {
+	case left.field == nil:
+		return fmt.Errorf("no column is compared")
+	case left.length:
+		return applyLength(left.field, op, right)
+	case right.length:
+		return fmt.Errorf("a column is compared with a length")
+	case right.field != nil:
+		return applyDependency(table, left.field, op, right.field)
+	case right.now != "":
+		return applyDateBound(left.field, op, right.now, true)
+	}
+
+	field, value := left.field, right.value
+	switch {
+	case op == "=":
+		setEnum(field, []interface{}{value})
+		return nil
+	case (op == "<>" || op == "!=") && value == "":
+		if field.MinLength == nil || *field.MinLength < 1 {
+			one := 1
+			field.MinLength = &one
+		}
+		return nil
+	case op == "<>" || op == "!=":
+		return fmt.Errorf("%s is not supported", op)
+	case field.Type == "date" || field.Type == "timestamp":
+		if s, ok := value.(string); ok {
+			return applyDateBound(field, op, s, false)
+		}
+	case field.Type == "int" || field.Type == "float" || field.Type == "price":
+		switch n := value.(type) {
+		case int:
+			applyNumericBound(field, op, float64(n))
+			return nil
+		case float64:
+			applyNumericBound(field, op, n)
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot compare %s column %s with %v", field.Type, field.Name, value)
+}
+
+// applyNumericBound sets the minimum or maximum value of a numeric column,
+// unless it already has a tighter one. Exclusive bounds move by the smallest
+// step the column can hold.
+func applyNumericBound(field *schema.Field, op string, n float64) {
+	step := func(up bool) float64 {
+		switch {
+		case field.Type == "int" && up:
+			return math.Floor(n) + 1
+		case field.Type == "int":
+			return math.Ceil(n) - 1
+		case field.Scale != nil:
+			unit := math.Pow10(-*field.Scale)
+			if !up {
+				unit = -unit
+			}
+			return math.Round((n+unit)/math.Abs(unit)) * math.Abs(unit)
+		case up:
+			return math.Nextafter(n, math.Inf(1))
+		}
+		return math.Nextafter(n, math.Inf(-1))
+	}
+
+	c := constraints(field)
+	switch op {
+	case ">=", ">":
+		min := n
+		if op == ">" {
+			min = step(true)
+		}
+		if c.MinValue == nil || min > *c.MinValue {
+			c.MinValue = &min
+		}
+	case "<=", "<":
+		max := n
+		if op == "<" {
+			max = step(false)
+		}
+		if c.MaxValue == nil || max < *c.MaxValue {
+			c.MaxValue = &max
+		}
+	}
+}
+
+// applyDateBound sets the earliest or latest date of a date or timestamp
+// column. Exclusive bounds move by a day for dates and a second for timestamps.
+func applyDateBound(field *schema.Field, op, bound string, now bool) error {
+	if field.Type != "date" && field.Type != "timestamp" {
+		return fmt.Errorf("cannot compare %s column %s with a date", field.Type, field.Name)
+	}
+	layout, unit, step := faker.DateLayout, "1d", 24*time.Hour
+	if field.Type == "timestamp" {
+		layout, unit, step = faker.DateTimeLayout, "1s", time.Second
+	}
+	if op == "<" {
+		unit, step = "-"+unit, -step
+	} else {
+		unit = "+" + unit
+	}
+
+	if !now {
+		t, err := faker.ParseTimeBound(bound, time.Time{}, time.UTC)
+		if err != nil {
+			return err
+		}
+		if op == ">" || op == "<" {
+			bound = t.Add(step).Format(layout)
+		}
+	} else if op == ">" || op == "<" {
+		bound += unit
+	}
+
+	c := constraints(field)
+	switch op {
+	case ">", ">=":
+		c.MinDate = bound
+	case "<", "<=":
+		c.MaxDate = bound
+	default:
+		return fmt.Errorf("%s is not supported for dates", op)
+	}
+	return nil
+}
+
+// applyLength sets the minimum or maximum length of a string column
+func applyLength(field *schema.Field, op string, right checkOperand) error {
+	n, ok := right.value.(int)
+	if !ok {
+		return fmt.Errorf("a length is compared with %v", right.value)
+	}
+	setMin := func(n int) {
+		if field.MinLength == nil || *field.MinLength < n {
+			field.MinLength = &n
+		}
+	}
+	setMax := func(n int) {
+		if field.MaxLength == nil || *field.MaxLength > n {
+			field.MaxLength = &n
+		}
+	}
+	switch op {
+	case ">=":
+		setMin(n)
+	case ">":
+		setMin(n + 1)
+	case "<=":
+		setMax(n)
+	case "<":
+		setMax(n - 1)
+	case "=":
+		setMin(n)
+		setMax(n)
+	default:
+		if n != 0 {
+			return fmt.Errorf("%s is not supported for lengths", op)
+		}
+		setMin(1)
+	}
+	return nil
+}
+
+// applyDependency orders two columns with depends_on: the column defined
+// later is generated after the earlier one, e.g. end_date > start_date
+func applyDependency(table *schema.Table, a *schema.Field, op string, b *schema.Field) error {
+	kind := func(field *schema.Field) string {
+		switch field.Type {
+		case "int", "float", "price":
+			return "number"
+		case "date", "timestamp":
+			return field.Type
+		}
+		return ""
+	}
+	if dependencyOperators[op] == "" {
+		return fmt.Errorf("%s between columns is not supported", op)
+	}
+	if kind(a) == "" || kind(a) != kind(b) {
+		return fmt.Errorf("%s column %s cannot be ordered after %s column %s", a.Type, a.Name, b.Type, b.Name)
+	}
+
+	if fieldIndex(table, a.Name) < fieldIndex(table, b.Name) {
+		a, b, op = b, a, flipped[op]
+	}
+	c := constraints(a)
+	if c.DependsOn != nil {
+		return fmt.Errorf("%s already depends on %s", a.Name, c.DependsOn.Field)
+	}
+	c.DependsOn = &schema.Dependency{Field: b.Name, Operator: dependencyOperators[op]}
+	return nil
+}
+
+// applyValues restricts a column to a list of literal values
+func (p *sqlParser) applyValues(table *schema.Table, left, list []sqlToken) error {
+	field, err := p.column(table, left)
+	if err != nil {
+		return err
+	}
+	var values []interface{}
+	for _, item := range splitTokens(list) {
+		value, err := p.operand(table, item)
+		if err != nil {
+			return err
+		}
+		if value.value == nil {
+			return fmt.Errorf("%s is not a literal", p.text(item))
+		}
+		values = append(values, value.value)
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("empty list of values")
+	}
+	setEnum(field, values)
+	return nil
+}
+
+// applyLike gives a column the pattern of a LIKE condition, with its ESCAPE
+// character. SQL Server patterns may hold [a-z] classes.
+func (p *sqlParser) applyLike(table *schema.Table, left, right []sqlToken) error {
+	field, err := p.column(table, left)
+	if err != nil {
+		return err
+	}
+	escape := '\\'
+	if p.dialect == dialect.SQLServer {
+		escape = 0
+	}
+	if i := topLevel(right, func(tok sqlToken) bool { return tok.is("ESCAPE") }); i >= 0 {
+		value, err := p.operand(table, right[i+1:])
+		if s, ok := value.value.(string); err != nil || !ok || len([]rune(s)) != 1 {
+			return fmt.Errorf("ESCAPE needs a single character")
+		} else {
+			escape = []rune(s)[0]
+		}
+		right = right[:i]
+	}
+	value, err := p.operand(table, right)
+	if err != nil {
+		return err
+	}
+	like, ok := value.value.(string)
+	if !ok {
+		return fmt.Errorf("LIKE needs a string pattern")
+	}
+	maxLength := 0
+	if field.MaxLength != nil {
+		maxLength = *field.MaxLength
+	}
+	return setPattern(field, likePattern(like, escape, p.dialect == dialect.SQLServer, maxLength))
+}
+
+// applyRegexp gives a column the pattern of a regular expression match
+func (p *sqlParser) applyRegexp(table *schema.Table, left, right []sqlToken, foldCase bool) error {
+	field, err := p.column(table, left)
+	if err != nil {
+		return err
+	}
+	value, err := p.operand(table, right)
+	if err != nil {
+		return err
+	}
+	expr, ok := value.value.(string)
+	if !ok {
+		return fmt.Errorf("the regular expression is not a string")
+	}
+	if foldCase {
+		expr = "(?i)" + expr
+	}
+	return setPattern(field, expr)
+}
+
+// likePattern converts a LIKE pattern to a regular expression, where % stands
+// for any letters and digits and _ for one of them. With a max length, the
+// repeats of % are bounded so that values fit the column.
+func likePattern(like string, escape rune, classes bool, maxLength int) string {
+	// Every part matches one character, except the empty parts standing for %
+	var parts []string
+	wildcards := 0
+	runes := []rune(like)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == escape && i+1 < len(runes):
+			i++
+			parts = append(parts, regexp.QuoteMeta(string(runes[i])))
+		case c == '%':
+			parts = append(parts, "")
+			wildcards++
+		case c == '_':
+			parts = append(parts, "[A-Za-z0-9]")
+		case c == '[' && classes:
+			end := i + 1
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			class := strings.ReplaceAll(string(runes[i+1:min(end, len(runes))]), `\`, `\\`)
+			parts = append(parts, "["+class+"]")
+			i = end
+		default:
+			parts = append(parts, regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	repeat := "*"
+	if maxLength > 0 && wildcards > 0 {
+		repeat = fmt.Sprintf("{0,%d}", max(0, maxLength-(len(parts)-wildcards))/wildcards)
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for _, part := range parts {
+		if part == "" {
+			part = "[A-Za-z0-9]" + repeat
+		}
+		b.WriteString(part)
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// setPattern gives a column a pattern, if values can be generated from it
+func setPattern(field *schema.Field, expr string) error {
+	if _, err := faker.CompilePattern(expr); err != nil {
+		return err
+	}
+	constraints(field).Pattern = expr
+	return nil
+}
+
+// column reads a condition operand that must be a column
+func (p *sqlParser) column(table *schema.Table, tokens []sqlToken) (*schema.Field, error) {
+	value, err := p.operand(table, tokens)
+	if err != nil {
+		return nil, err
+	}
+	if value.field == nil || value.length {
+		return nil, fmt.Errorf("%s is not a column", p.text(tokens))
+	}
+	return value.field, nil
+}
+
+// operand reads a column, length(column), a literal or the current date or
+// time, ignoring parentheses and casts such as (price)::numeric
+func (p *sqlParser) operand(table *schema.Table, tokens []sqlToken) (checkOperand, error) {
+	tokens = stripCasts(tokens)
+	if len(tokens) == 0 {
+		return checkOperand{}, fmt.Errorf("missing value")
+	}
+
+	switch tok := tokens[0]; {
+	case len(tokens) == 1 && tok.kind == sqlString:
+		return checkOperand{value: tok.text}, nil
+	case len(tokens) == 1 && tok.kind == sqlNumber:
+		return checkOperand{value: sqlLiteral(tok.text)}, nil
+	case len(tokens) == 2 && tok.isSymbol("-") && tokens[1].kind == sqlNumber:
+		return checkOperand{value: sqlLiteral("-" + tokens[1].text)}, nil
+	case len(tokens) == 2 && tok.kind == sqlWord && tokens[1].kind == sqlString:
+		// Typed literals such as DATE '2024-01-31'
+		return checkOperand{value: tokens[1].text}, nil
+	case len(tokens) == 1 && tok.isName():
+		if field := findField(table, tok.text); field != nil {
+			return checkOperand{field: field}, nil
+		}
+		if now := currentTime[strings.ToLower(tok.text)+"*"]; now != "" && tok.kind == sqlWord {
+			return checkOperand{now: now}, nil
+		}
+		return checkOperand{}, fmt.Errorf("unknown column %s", tok.text)
+	case len(tokens) > 2 && tok.kind == sqlWord && tokens[1].isSymbol("(") && closing(tokens, 1) == len(tokens)-1:
+		name, args := strings.ToLower(tok.text), tokens[2:len(tokens)-1]
+		if lengthFunctions[name] {
+			arg, err := p.column(table, args)
+			if err != nil {
+				return checkOperand{}, err
+			}
+			return checkOperand{field: arg, length: true}, nil
+		}
+		now := currentTime[name]
+		if now == "" {
+			now = currentTime[name+"*"]
+		}
+		// Optional arguments: the precision of CURRENT_TIMESTAMP(6) or SQLite date('now')
+		if now != "" && (len(args) == 0 || (len(args) == 1 && (args[0].kind == sqlNumber || (args[0].kind == sqlString && strings.EqualFold(args[0].text, "now"))))) {
+			return checkOperand{now: now}, nil
+		}
+	}
+	return checkOperand{}, fmt.Errorf("%s is not supported", p.text(tokens))
+}
+
+// stripCasts removes the parentheses and casts around a value, as in
+// (price)::numeric, 'a'::character varying or CAST(price AS numeric)
+func stripCasts(tokens []sqlToken) []sqlToken {
+	for {
+		tokens = unwrap(tokens)
+		if i := topLevel(tokens, func(tok sqlToken) bool { return tok.isSymbol("::") }); i > 0 {
+			tokens = tokens[:i]
+			continue
+		}
+		if len(tokens) > 3 && tokens[0].is("CAST") && tokens[1].isSymbol("(") && closing(tokens, 1) == len(tokens)-1 {
+			inner := tokens[2 : len(tokens)-1]
+			if i := topLevel(inner, func(tok sqlToken) bool { return tok.is("AS") }); i > 0 {
+				tokens = inner[:i]
+				continue
+			}
+		}
+		return tokens
+	}
+}
+
+// splitCondition splits a condition at the AND or OR keywords outside
+// parentheses. The AND of BETWEEN x AND y does not split it.
+func splitCondition(tokens []sqlToken, keyword string) [][]sqlToken {
+	var parts [][]sqlToken
+	depth, start, between := 0, 0, false
+	for i, tok := range tokens {
+		switch {
+		case tok.isSymbol("("):
+			depth++
+		case tok.isSymbol(")"):
+			depth--
+		case depth > 0:
+		case tok.is("BETWEEN"):
+			between = true
+		case tok.is("AND") && between:
+			between = false
+		case tok.is(keyword):
+			parts = append(parts, tokens[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, tokens[start:])
+}
+
+// topLevel returns the index of the first token outside parentheses and
+// brackets that matches, or -1
+func topLevel(tokens []sqlToken, match func(sqlToken) bool) int {
+	depth := 0
+	for i, tok := range tokens {
+		switch {
+		case tok.isSymbol("("), tok.isSymbol("["):
+			depth++
+		case tok.isSymbol(")"), tok.isSymbol("]"):
+			depth--
+		case depth == 0 && match(tok):
+			return i
+		}
+	}
+	return -1
+}
+
+// unwrap removes the parentheses around a whole condition or value, e.g. ((a > 0))
+func unwrap(tokens []sqlToken) []sqlToken {
+	for len(tokens) >= 2 && tokens[0].isSymbol("(") && closing(tokens, 0) == len(tokens)-1 {
+		tokens = tokens[1 : len(tokens)-1]
+	}
+	return tokens
+}
+
+// closing returns the index of the ")" that closes the "(" at i, or -1
+func closing(tokens []sqlToken, i int) int {
+	depth := 0
+	for j := i; j < len(tokens); j++ {
+		switch {
+		case tokens[j].isSymbol("("):
+			depth++
+		case tokens[j].isSymbol(")"):
+			if depth--; depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// findField returns the column of a table with a name, matching its case first
+func findField(table *schema.Table, name string) *schema.Field {
+	if i := fieldIndex(table, name); i >= 0 {
+		return &table.Fields[i]
+	}
+	return nil
+}
+
+// fieldIndex returns the position of a column, matching its case first, or -1
+func fieldIndex(table *schema.Table, name string) int {
+	for i := range table.Fields {
+		if table.Fields[i].Name == name {
+			return i
+		}
+	}
+	for i := range table.Fields {
+		if strings.EqualFold(table.Fields[i].Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// constraints returns the constraints of a field, adding them if it has none
+func constraints(field *schema.Field) *schema.Constraint {
+	if field.Constraints == nil {
+		field.Constraints = &schema.Constraint{}
+	}
+	return field.Constraints
+}
+
+// setEnum restricts a field to a list of values
+func setEnum(field *schema.Field, values []interface{}) {
+	constraints(field).Enum = &schema.Enum{Values: values}
+}
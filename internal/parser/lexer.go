@@ -0,0 +1,227 @@
+package parser
+
+import (
+	"fmt"
+	"go-fake/internal/dialect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// sqlTokenKind classifies the tokens of an SQL file
+type sqlTokenKind int
+
+const (
+	sqlEOF    sqlTokenKind = iota
+	sqlWord                // keyword or unquoted identifier
+	sqlQuoted              // quoted identifier: "order", `user` or [dbo]
+	sqlString              // 'text' or $$text$$
+	sqlNumber
+	sqlSymbol // punctuation and operators
+)
+
+// sqlToken is a token with its unquoted text and its byte range in the source
+type sqlToken struct {
+	kind       sqlTokenKind
+	text       string
+	start, end int
+}
+
+// is reports whether the token is the keyword, in any case
+func (t sqlToken) is(keyword string) bool {
+	return t.kind == sqlWord && strings.EqualFold(t.text, keyword)
+}
+
+// isSymbol reports whether the token is the punctuation or operator
+func (t sqlToken) isSymbol(symbol string) bool {
+	return t.kind == sqlSymbol && t.text == symbol
+}
+
+// isName reports whether the token can name a table or a column
+func (t sqlToken) isName() bool {
+	return t.kind == sqlWord || t.kind == sqlQuoted
+}
+
+func (t sqlToken) String() string {
+	switch t.kind {
+	case sqlEOF:
+		return "end of file"
+	case sqlString:
+		return "string '" + t.text + "'"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// sqlSyntaxError is an error at a position of an SQL file
+type sqlSyntaxError struct {
+	path      string
+	line, col int
+	msg       string
+}
+
+func (e *sqlSyntaxError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.path, e.line, e.col, e.msg)
+}
+
+// syntaxError reports an error at a byte offset of the source
+func syntaxError(path, src string, offset int, format string, args ...interface{}) error {
+	line, col := 1, 1
+	for _, r := range src[:offset] {
+		if r == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	return &sqlSyntaxError{path: path, line: line, col: col, msg: fmt.Sprintf(format, args...)}
+}
+
+// sqlSymbols are the operators of more than one character, longest first
+var sqlSymbols = []string{"!~~*", "!~~", "~~*", "!~*", "::", "<=", ">=", "<>", "!=", "||", "~~", "~*", "!~"}
+
+// lexSQL splits SQL source into tokens, skipping whitespace and -- and /* */
+// comments. # starts a comment in MySQL, but names temporary tables in SQL
+// Server and is an operator in Postgres, whose [ ] are array brackets rather
+// than quotes. The last token is always sqlEOF.
+func lexSQL(path, src string, d dialect.Dialect) ([]sqlToken, error) {
+	hashComments := d != dialect.SQLServer && d != dialect.Postgres
+	bracketNames := d != dialect.Postgres
+	var tokens []sqlToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+			continue
+		case strings.HasPrefix(src[i:], "--") || (c == '#' && hashComments):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, syntaxError(path, src, start, "unterminated /* comment")
+			}
+			i += end + 4
+			continue
+
+		case c == '\'':
+			text, end, ok := readQuoted(src, i, '\'')
+			if !ok {
+				return nil, syntaxError(path, src, start, "unterminated string")
+			}
+			tokens = append(tokens, sqlToken{kind: sqlString, text: text, start: start, end: end})
+			i = end
+		case c == '"' || c == '`':
+			text, end, ok := readQuoted(src, i, c)
+			if !ok {
+				return nil, syntaxError(path, src, start, "unterminated quoted identifier")
+			}
+			tokens = append(tokens, sqlToken{kind: sqlQuoted, text: text, start: start, end: end})
+			i = end
+		case c == '[' && bracketNames && i+1 < len(src) && src[i+1] != ']' && !isDigit(src[i+1]) &&
+			(len(tokens) == 0 || !tokens[len(tokens)-1].is("ARRAY")):
+			// SQL Server [name]; [] and [3] are array types, ARRAY[...] an array
+			end := strings.IndexByte(src[i:], ']')
+			if end < 0 {
+				return nil, syntaxError(path, src, start, "unterminated quoted identifier")
+			}
+			i += end + 1
+			tokens = append(tokens, sqlToken{kind: sqlQuoted, text: src[start+1 : i-1], start: start, end: i})
+		case c == '$' && dollarTag(src[i:]) != "":
+			// Postgres dollar-quoted strings, e.g. function bodies in $$ ... $$
+			tag := dollarTag(src[i:])
+			end := strings.Index(src[i+len(tag):], tag)
+			if end < 0 {
+				return nil, syntaxError(path, src, start, "unterminated %s string", tag)
+			}
+			i += len(tag) + end + len(tag)
+			tokens = append(tokens, sqlToken{kind: sqlString, text: src[start+len(tag) : i-len(tag)], start: start, end: i})
+
+		case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])):
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+				j := i + 1
+				if j < len(src) && (src[j] == '+' || src[j] == '-') {
+					j++
+				}
+				if j < len(src) && isDigit(src[j]) {
+					for i = j; i < len(src) && isDigit(src[i]); i++ {
+					}
+				}
+			}
+			tokens = append(tokens, sqlToken{kind: sqlNumber, text: src[start:i], start: start, end: i})
+		case isWordStart(src[i:]) || (c == '#' && isWordStart(strings.TrimLeft(src[i:], "#"))):
+			// SQL Server temporary tables are named #name or ##name
+			for i < len(src) && src[i] == '#' {
+				i++
+			}
+			for i < len(src) && isWordPart(src[i:]) {
+				_, size := utf8.DecodeRuneInString(src[i:])
+				i += size
+			}
+			tokens = append(tokens, sqlToken{kind: sqlWord, text: src[start:i], start: start, end: i})
+
+		default:
+			symbol := src[i : i+1]
+			for _, s := range sqlSymbols {
+				if strings.HasPrefix(src[i:], s) {
+					symbol = s
+					break
+				}
+			}
+			i += len(symbol)
+			tokens = append(tokens, sqlToken{kind: sqlSymbol, text: symbol, start: start, end: i})
+		}
+	}
+	return append(tokens, sqlToken{kind: sqlEOF, start: len(src), end: len(src)}), nil
+}
+
+// readQuoted reads text between quotes starting at i, where a doubled quote
+// stands for the quote itself. It returns the text and the offset after it.
+func readQuoted(src string, i int, quote byte) (string, int, bool) {
+	var b strings.Builder
+	for i++; i < len(src); i++ {
+		if src[i] == quote {
+			if i+1 < len(src) && src[i+1] == quote {
+				b.WriteByte(quote)
+				i++
+				continue
+			}
+			return b.String(), i + 1, true
+		}
+		b.WriteByte(src[i])
+	}
+	return "", 0, false
+}
+
+// dollarTag returns the $tag$ opening a dollar-quoted string, or ""
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '$':
+			return s[:i+1]
+		case c != '_' && !isDigit(c) && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z'):
+			return ""
+		}
+	}
+	return ""
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordStart(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isWordPart(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
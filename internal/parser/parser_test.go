@@ -1,8 +1,13 @@
 package parser
 
 import (
+	"fmt"
+	"go-fake/internal/dialect"
+	"go-fake/internal/schema"
 	"io/ioutil"
+	"math"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -82,4 +87,808 @@ func TestParseSQLSchema(t *testing.T) {
 			t.Errorf("First field incorrect: got %+v", table.Fields[0])
 		}
 	}
-}
\ No newline at end of file
+}
+// writeTempFile writes content to a temporary file removed when the test ends
+func writeTempFile(t *testing.T, pattern, content string) string {
+	t.Helper()
+	tmpFile, err := ioutil.TempFile("", pattern)
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	t.Cleanup(func() { os.Remove(tmpFile.Name()) })
+
+	if _, err := tmpFile.Write([]byte(content)); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	tmpFile.Close()
+	return tmpFile.Name()
+}
+
+func TestParseSQLKeyColumns(t *testing.T) {
+	path := writeTempFile(t, "test-schema-*.sql", `CREATE TABLE accounts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    email VARCHAR(100) UNIQUE NOT NULL,
+    nickname VARCHAR(50)
+);`)
+
+	result, err := ParseSQLSchema(path)
+	if err != nil {
+		t.Fatalf("ParseSQLSchema() error = %v", err)
+	}
+	if len(result.Tables) != 1 || len(result.Tables[0].Fields) != 3 {
+		t.Fatalf("Expected 1 table with 3 fields, got %+v", result.Tables)
+	}
+
+	fields := result.Tables[0].Fields
+	if id := fields[0]; !id.PrimaryKey || !id.AutoIncrement || !id.Required {
+		t.Errorf("id should be a required auto-increment primary key: got %+v", id)
+	}
+	if email := fields[1]; !email.Unique || email.PrimaryKey {
+		t.Errorf("email should be unique only: got %+v", email)
+	}
+	if nickname := fields[2]; nickname.Unique || nickname.PrimaryKey || nickname.AutoIncrement {
+		t.Errorf("nickname should have no key flags: got %+v", nickname)
+	}
+}
+
+func TestParseSQLTableLevelKeys(t *testing.T) {
+	path := writeTempFile(t, "test-schema-*.sql", `CREATE TABLE order_lines (
+    order_id INT,
+    line_no INT,
+    sku VARCHAR(20),
+    PRIMARY KEY (order_id, line_no),
+    UNIQUE (order_id, sku)
+);
+
+CREATE TABLE shipments (
+    id INT,
+    order_id INT,
+    line_no INT,
+    CONSTRAINT pk_shipments PRIMARY KEY (id),
+    CONSTRAINT fk_line FOREIGN KEY (order_id, line_no) REFERENCES order_lines (order_id, line_no)
+);`)
+
+	result, err := ParseSQLSchema(path)
+	if err != nil {
+		t.Fatalf("ParseSQLSchema() error = %v", err)
+	}
+	if len(result.Tables) != 2 {
+		t.Fatalf("Expected 2 tables, got %d", len(result.Tables))
+	}
+
+	lines := result.Tables[0]
+	if len(lines.Fields) != 3 {
+		t.Errorf("Expected 3 order_lines fields, got %+v", lines.Fields)
+	}
+	if strings.Join(lines.PrimaryKey, ",") != "order_id,line_no" {
+		t.Errorf("Expected composite primary key, got %v", lines.PrimaryKey)
+	}
+	if !lines.Fields[0].Required || !lines.Fields[1].Required {
+		t.Errorf("Composite primary key columns should be required: %+v", lines.Fields)
+	}
+	if len(lines.UniqueKeys) != 1 || strings.Join(lines.UniqueKeys[0], ",") != "order_id,sku" {
+		t.Errorf("Expected unique key (order_id, sku), got %v", lines.UniqueKeys)
+	}
+
+	shipments := result.Tables[1]
+	if len(shipments.PrimaryKey) != 0 || !shipments.Fields[0].PrimaryKey {
+		t.Errorf("Single-column primary key should move onto the id field: %+v", shipments)
+	}
+	if len(shipments.ForeignKeys) != 1 {
+		t.Fatalf("Expected 1 composite foreign key, got %+v", shipments.ForeignKeys)
+	}
+	fk := shipments.ForeignKeys[0]
+	if fk.ToTable != "order_lines" || strings.Join(fk.Fields, ",") != "order_id,line_no" || strings.Join(fk.ToFields, ",") != "order_id,line_no" {
+		t.Errorf("Unexpected composite foreign key %+v", fk)
+	}
+}
+
+func TestParseSQLEnums(t *testing.T) {
+	path := writeTempFile(t, "test-schema-*.sql", `CREATE TYPE mood AS ENUM (
+    'sad', 'ok', 'happy'
+);
+
+CREATE TABLE people (
+    id SERIAL PRIMARY KEY,
+    current_mood mood NOT NULL,
+    size ENUM('small', 'medium', 'x-large'),
+    status VARCHAR(20) CHECK (status IN ('pending', 'it''s shipped')),
+    rating INT,
+    CONSTRAINT rating_values CHECK (rating IN (1, 2, 3))
+);`)
+
+	result, err := ParseSQLSchema(path)
+	if err != nil {
+		t.Fatalf("ParseSQLSchema() error = %v", err)
+	}
+	if len(result.Tables) != 1 || len(result.Tables[0].Fields) != 5 {
+		t.Fatalf("Expected 1 table with 5 fields, got %+v", result.Tables)
+	}
+
+	expected := map[string][]interface{}{
+		"current_mood": {"sad", "ok", "happy"},
+		"size":         {"small", "medium", "x-large"},
+		"status":       {"pending", "it's shipped"},
+		"rating":       {1, 2, 3},
+	}
+	for _, field := range result.Tables[0].Fields[1:] {
+		if field.Constraints == nil || field.Constraints.Enum == nil {
+			t.Errorf("Field %s has no enum", field.Name)
+			continue
+		}
+		if got, want := fmt.Sprint(field.Constraints.Enum.Values), fmt.Sprint(expected[field.Name]); got != want {
+			t.Errorf("Field %s enum = %s, want %s", field.Name, got, want)
+		}
+	}
+	if result.Tables[0].Fields[1].Type != "string" {
+		t.Errorf("Enum type column should be a string, got %s", result.Tables[0].Fields[1].Type)
+	}
+}
+
+func TestParseSQLDecimals(t *testing.T) {
+	path := writeTempFile(t, "test-schema-*.sql", `CREATE TABLE products (
+    id INT PRIMARY KEY,
+    price DECIMAL(10, 2) CHECK (price >= 0.01 AND price <= 99.99),
+    weight NUMERIC(6),
+    ratio FLOAT
+);`)
+
+	result, err := ParseSQLSchema(path)
+	if err != nil {
+		t.Fatalf("ParseSQLSchema() error = %v", err)
+	}
+	fields := result.Tables[0].Fields
+
+	price := fields[1]
+	if price.Type != "float" || price.Precision == nil || *price.Precision != 10 || price.Scale == nil || *price.Scale != 2 {
+		t.Errorf("price should be a float with precision 10 and scale 2, got %+v", price)
+	}
+	if price.Constraints == nil || price.Constraints.MinValue == nil || *price.Constraints.MinValue != 0.01 ||
+		price.Constraints.MaxValue == nil || *price.Constraints.MaxValue != 99.99 {
+		t.Errorf("price should be bounded to [0.01, 99.99], got %+v", price.Constraints)
+	}
+
+	weight := fields[2]
+	if weight.Precision == nil || *weight.Precision != 6 || weight.Scale == nil || *weight.Scale != 0 {
+		t.Errorf("weight should have precision 6 and scale 0, got %+v", weight)
+	}
+	if fields[3].Precision != nil || fields[3].Scale != nil {
+		t.Errorf("ratio should have no precision or scale, got %+v", fields[3])
+	}
+}
+
+func TestParseSQLStringLengths(t *testing.T) {
+	path := writeTempFile(t, "test-schema-*.sql", `CREATE TABLE countries (
+    code CHAR(2) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    native_name CHARACTER VARYING (60),
+    notes TEXT
+);`)
+
+	result, err := ParseSQLSchema(path)
+	if err != nil {
+		t.Fatalf("ParseSQLSchema() error = %v", err)
+	}
+	expected := []struct {
+		maxLength int
+		fixed     bool
+	}{{2, true}, {100, false}, {60, false}, {0, false}}
+	for i, field := range result.Tables[0].Fields {
+		maxLength := 0
+		if field.MaxLength != nil {
+			maxLength = *field.MaxLength
+		}
+		if field.Type != "string" || maxLength != expected[i].maxLength || field.FixedLength != expected[i].fixed {
+			t.Errorf("Field %s: type %s, max_length %d, fixed %v; want string, %d, %v",
+				field.Name, field.Type, maxLength, field.FixedLength, expected[i].maxLength, expected[i].fixed)
+		}
+	}
+}
+
+func TestParseJSONEnum(t *testing.T) {
+	path := writeTempFile(t, "test-schema-*.json", `{
+		"fields": [
+			{"name": "status", "type": "string", "constraints": {"enum": {"values": ["pending", "shipped"], "weights": [0.2, 0.8]}}},
+			{"name": "tier", "type": "string", "constraints": {"enum": ["free", "pro"]}}
+		]
+	}`)
+
+	result, err := ParseJSONSchema(path)
+	if err != nil {
+		t.Fatalf("ParseJSONSchema() error = %v", err)
+	}
+	status, tier := result.Fields[0].Constraints.Enum, result.Fields[1].Constraints.Enum
+	if len(status.Values) != 2 || len(status.Weights) != 2 || status.Weights[1] != 0.8 {
+		t.Errorf("Unexpected weighted enum %+v", status)
+	}
+	if len(tier.Values) != 2 || tier.Values[1] != "pro" || tier.Weights != nil {
+		t.Errorf("Unexpected enum list %+v", tier)
+	}
+}
+
+func TestParseJSONDependsOn(t *testing.T) {
+	path := writeTempFile(t, "test-schema-*.json", `{
+		"fields": [
+			{"name": "start_date", "type": "date"},
+			{"name": "end_date", "type": "date", "constraints": {"depends_on": {"field": "start_date", "min_offset": "1d", "max_offset": "180d"}}},
+			{"name": "updated_at", "type": "timestamp", "constraints": {"depends_on": "created_at"}},
+			{"name": "max_qty", "type": "int", "constraints": {"depends_on": {"field": "min_qty", "min_offset": 5, "max_offset": 10}}}
+		]
+	}`)
+
+	result, err := ParseJSONSchema(path)
+	if err != nil {
+		t.Fatalf("ParseJSONSchema() error = %v", err)
+	}
+	end, updated, qty := result.Fields[1].Constraints.DependsOn, result.Fields[2].Constraints.DependsOn, result.Fields[3].Constraints.DependsOn
+	if end.Field != "start_date" || end.MinOffset != "1d" || end.MaxOffset != "180d" {
+		t.Errorf("Unexpected dependency %+v", end)
+	}
+	if updated.Field != "created_at" || updated.Operator != "" {
+		t.Errorf("Unexpected dependency %+v", updated)
+	}
+	if qty.MinOffset != "5" || qty.MaxOffset != "10" {
+		t.Errorf("Numeric offsets should be read as numbers, got %+v", qty)
+	}
+}
+
+func TestParseSQLGeneratedColumns(t *testing.T) {
+	path := writeTempFile(t, "test-schema-*.sql", `CREATE TABLE order_items (
+    id INT PRIMARY KEY,
+    quantity INT NOT NULL,
+    unit_price DECIMAL(10, 2) NOT NULL,
+    total DECIMAL(10, 2) GENERATED ALWAYS AS (quantity * unit_price) STORED,
+    code VARCHAR(20) AS (upper(replace(sku, ' ', '(')) ),
+    sku VARCHAR(20) UNIQUE
+);`)
+
+	result, err := ParseSQLSchema(path)
+	if err != nil {
+		t.Fatalf("ParseSQLSchema() error = %v", err)
+	}
+	fields := result.Tables[0].Fields
+	if len(fields) != 6 {
+		t.Fatalf("expected 6 fields, got %d", len(fields))
+	}
+
+	total := fields[3]
+	if total.Expression != "quantity * unit_price" || total.Type != "float" || total.Scale == nil || *total.Scale != 2 {
+		t.Errorf("total should be a DECIMAL(10, 2) computed as quantity * unit_price, got %+v", total)
+	}
+	code := fields[4]
+	if code.Expression != "upper(replace(sku, ' ', '('))" || code.Unique || code.MaxLength == nil || *code.MaxLength != 20 {
+		t.Errorf("code should be a VARCHAR(20) computed from sku, got %+v", code)
+	}
+	if fields[5].Expression != "" || !fields[5].Unique {
+		t.Errorf("sku should be a plain unique column, got %+v", fields[5])
+	}
+}
+
+func TestParseSQLStatementLayout(t *testing.T) {
+	path := writeTempFile(t, "test-schema-*.sql", `/* Dump header;
+   CREATE TABLE ignored (x INT); */
+CREATE TABLE public.users (id SERIAL PRIMARY KEY, name TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS "order" (
+    id INT
+        PRIMARY KEY,
+    user_id INT NOT NULL
+        REFERENCES public.users (id),   -- trailing comment );
+    total DECIMAL(10, 2) CHECK (total >= 0 AND total <= 5000),
+    note VARCHAR(40) CHECK (note <> ');'),
+    `+"`user`"+` VARCHAR(20)
+) ENGINE=InnoDB;
+CREATE TABLE [dbo].[line items] ([id] INT NOT NULL, [order_id] INT, FOREIGN KEY ([order_id]) REFERENCES "order" ([id]))`)
+
+	result, err := ParseSQLSchema(path)
+	if err != nil {
+		t.Fatalf("ParseSQLSchema() error = %v", err)
+	}
+	if len(result.Tables) != 3 {
+		t.Fatalf("Expected 3 tables, got %+v", result.Tables)
+	}
+
+	users, order, items := result.Tables[0], result.Tables[1], result.Tables[2]
+	if users.Name != "users" || len(users.Fields) != 2 || !users.Fields[0].AutoIncrement || !users.Fields[1].Required {
+		t.Errorf("Unexpected users table %+v", users)
+	}
+	if order.Name != "order" || len(order.Fields) != 5 {
+		t.Fatalf("Unexpected order table %+v", order)
+	}
+	if !order.Fields[0].PrimaryKey {
+		t.Errorf("id should be the primary key: %+v", order.Fields[0])
+	}
+	if ref := order.Fields[1].Constraints; ref == nil || ref.References == nil || ref.References.Table != "users" || ref.References.Field != "id" {
+		t.Errorf("user_id should reference users.id: %+v", order.Fields[1])
+	}
+	total := order.Fields[2]
+	if total.Scale == nil || *total.Scale != 2 || total.Constraints == nil || total.Constraints.MaxValue == nil || *total.Constraints.MaxValue != 5000 {
+		t.Errorf("total should be a DECIMAL(10, 2) up to 5000: %+v", total)
+	}
+	if order.Fields[3].Name != "note" || order.Fields[4].Name != "user" || *order.Fields[4].MaxLength != 20 {
+		t.Errorf("Unexpected columns after a CHECK containing ');': %+v", order.Fields[3:])
+	}
+
+	if items.Name != "line items" || len(items.Fields) != 2 || !items.Fields[0].Required {
+		t.Errorf("Unexpected line items table %+v", items)
+	}
+	if ref := items.Fields[1].Constraints; ref == nil || ref.References == nil || ref.References.Table != "order" {
+		t.Errorf("order_id should reference order.id: %+v", items.Fields[1])
+	}
+	if len(result.Relationships) != 2 {
+		t.Errorf("Expected 2 relationships, got %+v", result.Relationships)
+	}
+}
+
+func TestParseSQLSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"CREATE TABLE users (\n    id INT,\n    name TEXT\n;", ":1:20: CREATE TABLE users is missing its closing \")\""},
+		{"CREATE TABLE t (\n  a INT,\n  'b' TEXT\n);", ":3:3: expected column name, found string 'b'"},
+		{"CREATE TABLE t (a INT)\n);", ":2:1: unexpected \")\" after the columns of table t"},
+		{"CREATE TABLE t (a INT);\n/* unfinished", ":2:1: unterminated /* comment"},
+		{"CREATE TABLE t (\n  a VARCHAR(10) CHECK (a <> 'x),\n  b INT\n);", ":2:29: unterminated string"},
+		{"CREATE TABLE t (a INT, CONSTRAINT c b INT);", ":1:37: expected PRIMARY KEY, UNIQUE, FOREIGN KEY or CHECK"},
+	}
+	for _, tt := range tests {
+		path := writeTempFile(t, "test-schema-*.sql", tt.sql)
+		_, err := ParseSQLSchema(path)
+		if err == nil || !strings.Contains(err.Error(), path+tt.want) {
+			t.Errorf("ParseSQLSchema(%q) error = %v, want %s", tt.sql, err, tt.want)
+		}
+	}
+}
+
+func TestParseSQLReferencesWithoutColumns(t *testing.T) {
+	sql := `CREATE TABLE posts (
+    id INT PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users
+);
+CREATE TABLE comments (id INT, post_id INT, FOREIGN KEY (post_id) REFERENCES posts);
+CREATE TABLE pairs (a INT, b INT, PRIMARY KEY (a, b));
+CREATE TABLE links (a INT, b INT, FOREIGN KEY (a, b) REFERENCES pairs, note_id INT REFERENCES notes);
+CREATE TABLE users (id INT NOT NULL, name TEXT);
+ALTER TABLE users ADD PRIMARY KEY (id);
+CREATE TABLE tags (post_id INT REFERENCES pairs);`
+
+	result, warnings, err := parseSQL("posts.sql", sql, dialect.Postgres)
+	if err != nil {
+		t.Fatalf("parseSQL() error = %v", err)
+	}
+	reference := func(table schema.Table, column int) *schema.Reference {
+		if c := table.Fields[column].Constraints; c != nil {
+			return c.References
+		}
+		return nil
+	}
+
+	if ref := reference(result.Tables[0], 1); ref == nil || ref.Table != "users" || ref.Field != "id" {
+		t.Errorf("posts.user_id should reference users.id, got %+v", ref)
+	}
+	if ref := reference(result.Tables[1], 1); ref == nil || ref.Table != "posts" || ref.Field != "id" {
+		t.Errorf("comments.post_id should reference posts.id, got %+v", ref)
+	}
+	links := result.Tables[3]
+	if len(links.ForeignKeys) != 1 || strings.Join(links.ForeignKeys[0].ToFields, ",") != "a,b" {
+		t.Errorf("links (a, b) should reference pairs (a, b), got %+v", links.ForeignKeys)
+	}
+	if ref := reference(links, 2); ref != nil {
+		t.Errorf("links.note_id references a table that does not exist, got %+v", ref)
+	}
+	if ref := reference(result.Tables[5], 0); ref != nil {
+		t.Errorf("tags.post_id references a composite primary key, got %+v", ref)
+	}
+
+	wantRelationships := map[string]bool{"posts.user_id->users.id": true, "comments.post_id->posts.id": true}
+	for _, rel := range result.Relationships {
+		key := rel.FromTable + "." + rel.FromField + "->" + rel.ToTable + "." + rel.ToField
+		if !wantRelationships[key] {
+			t.Errorf("Unexpected relationship %s", key)
+		}
+		delete(wantRelationships, key)
+	}
+	if len(wantRelationships) > 0 {
+		t.Errorf("Missing relationships %v", wantRelationships)
+	}
+
+	want := []string{
+		"posts.sql:7:95: ignoring foreign key (note_id) of links: table notes is not created in this file",
+		"posts.sql:10:43: ignoring foreign key (post_id) of tags: REFERENCES pairs has no column list and pairs does not have a single-column primary key",
+	}
+	if len(warnings) != len(want) {
+		t.Fatalf("Expected %d warnings, got %v", len(want), warnings)
+	}
+	for i, w := range want {
+		if warnings[i].Error() != w {
+			t.Errorf("Warning %d = %v, want %s", i, warnings[i], w)
+		}
+	}
+}
+
+func TestParseSQLDialectTypes(t *testing.T) {
+	tests := []struct {
+		dialect dialect.Dialect
+		sqlType string
+		want    string
+	}{
+		{dialect.Postgres, "POINT", "point"},
+		{dialect.Postgres, "INTERVAL", "interval"},
+		{dialect.Postgres, "JSONB", "json"},
+		{dialect.Postgres, "INET", "ipaddress"},
+		{dialect.Postgres, "MONEY", "price"},
+		{dialect.Postgres, "BYTEA", "binary"},
+		{dialect.Postgres, "TEXT[]", "string[]"},
+		{dialect.Postgres, "INTEGER ARRAY", "int[]"},
+		{dialect.Postgres, "TIMESTAMP(3) WITH TIME ZONE", "timestamp"},
+		{dialect.Postgres, "pg_catalog.int4", "int"},
+		{dialect.MySQL, "TINYINT(1)", "boolean"},
+		{dialect.MySQL, "TINYINT(4)", "int"},
+		{dialect.MySQL, "INT(11) UNSIGNED", "int"},
+		{dialect.MySQL, "DATETIME", "timestamp"},
+		{dialect.MySQL, "LONGBLOB", "binary"},
+		{dialect.SQLServer, "NVARCHAR(MAX)", "string"},
+		{dialect.SQLServer, "BIT", "boolean"},
+		{dialect.SQLServer, "UNIQUEIDENTIFIER", "uuid"},
+		{dialect.SQLServer, "DATETIME2(7)", "timestamp"},
+		{dialect.SQLite, "UNSIGNED BIG INT", "int"},
+		{dialect.SQLite, "VARYING CHARACTER(70)", "string"},
+		{dialect.SQLite, "DOUBLE", "float"},
+		{dialect.Generic, "MEDIUMTEXT", "string"},
+		{dialect.Generic, "GEOGRAPHY", "string"},
+	}
+	for _, tt := range tests {
+		sql := fmt.Sprintf("CREATE TABLE t (c %s);", tt.sqlType)
+		result, _, err := parseSQL("test.sql", sql, tt.dialect)
+		if err != nil {
+			t.Fatalf("parseSQL(%q) error = %v", sql, err)
+		}
+		if got := result.Tables[0].Fields[0].Type; got != tt.want {
+			t.Errorf("%s %s mapped to %q, want %q", tt.dialect, tt.sqlType, got, tt.want)
+		}
+	}
+}
+
+func TestParseSQLDialects(t *testing.T) {
+	mysql := "CREATE TABLE `flags` (\n" +
+		"  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,\n" +
+		"  `level` TINYINT UNSIGNED,\n" +
+		"  `score` INT UNSIGNED CHECK (`score` >= 1 AND `score` <= 10),\n" +
+		"  PRIMARY KEY (`id`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;\n"
+	sqlserver := `SET ANSI_NULLS ON
+GO
+CREATE TABLE [dbo].[accounts] (
+  [id] INT IDENTITY(1,1) NOT NULL,
+  [name] NVARCHAR(MAX) NULL,
+  [code] NCHAR(4) NOT NULL,
+  CONSTRAINT [PK_accounts] PRIMARY KEY CLUSTERED ([id] ASC)
+) ON [PRIMARY]
+GO
+CREATE TABLE #scratch (n TINYINT)
+GO
+`
+	postgres := "CREATE TABLE places (id BIGSERIAL PRIMARY KEY, tags VARCHAR(20)[], meta JSONB);"
+
+	for sql, want := range map[string]dialect.Dialect{mysql: dialect.MySQL, sqlserver: dialect.SQLServer, postgres: dialect.Postgres} {
+		if got := dialect.Detect(sql); got != want {
+			t.Errorf("Detect(%q) = %s, want %s", sql, got, want)
+		}
+	}
+	if got := dialect.Detect("CREATE TABLE t (id INT);"); got != dialect.Generic {
+		t.Errorf("Detect() of plain SQL = %s, want generic", got)
+	}
+
+	result, err := ParseSQLSchema(writeTempFile(t, "test-schema-*.sql", mysql))
+	if err != nil {
+		t.Fatalf("ParseSQLSchema() error = %v", err)
+	}
+	id, level, score := result.Tables[0].Fields[0], result.Tables[0].Fields[1], result.Tables[0].Fields[2]
+	if !id.AutoIncrement || id.Constraints != nil {
+		t.Errorf("Auto-increment id should keep counting from 1: %+v", id)
+	}
+	if c := level.Constraints; c == nil || *c.MinValue != 0 || *c.MaxValue != 255 {
+		t.Errorf("TINYINT UNSIGNED should range from 0 to 255: %+v", level)
+	}
+	if c := score.Constraints; c == nil || *c.MinValue != 1 || *c.MaxValue != 10 {
+		t.Errorf("The CHECK on score should decide its range: %+v", score)
+	}
+
+	result, err = ParseSQLSchema(writeTempFile(t, "test-schema-*.sql", sqlserver))
+	if err != nil {
+		t.Fatalf("ParseSQLSchema() error = %v", err)
+	}
+	if len(result.Tables) != 2 || result.Tables[1].Name != "#scratch" {
+		t.Fatalf("Expected the accounts and #scratch tables, got %+v", result.Tables)
+	}
+	accounts := result.Tables[0]
+	if !accounts.Fields[0].PrimaryKey || !accounts.Fields[0].AutoIncrement {
+		t.Errorf("id should be an IDENTITY primary key: %+v", accounts.Fields[0])
+	}
+	if accounts.Fields[1].MaxLength != nil {
+		t.Errorf("NVARCHAR(MAX) should have no length: %+v", accounts.Fields[1])
+	}
+	if code := accounts.Fields[2]; code.MaxLength == nil || *code.MaxLength != 4 || !code.FixedLength {
+		t.Errorf("NCHAR(4) should be 4 characters: %+v", code)
+	}
+
+	result, err = ParseSQLSchema(writeTempFile(t, "test-schema-*.sql", postgres))
+	if err != nil {
+		t.Fatalf("ParseSQLSchema() error = %v", err)
+	}
+	if tags := result.Tables[0].Fields[1]; tags.Type != "string[]" || tags.MaxLength != nil {
+		t.Errorf("VARCHAR(20)[] should be a string array without a length: %+v", tags)
+	}
+}
+
+func TestParseSQLAlterTableAndIndexes(t *testing.T) {
+	sql := `CREATE TABLE public.users (
+    id integer NOT NULL,
+    email character varying(100) NOT NULL,
+    tenant_id integer NOT NULL,
+    handle text
+);
+ALTER TABLE public.users OWNER TO app;
+
+CREATE TABLE public.orders (
+    id integer NOT NULL,
+    user_id integer,
+    tenant_id integer,
+    total numeric(10,2)
+);
+
+ALTER TABLE ONLY public.users ALTER COLUMN id SET DEFAULT nextval('public.users_id_seq'::regclass);
+ALTER TABLE ONLY public.users
+    ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.orders
+    ADD CONSTRAINT orders_pkey PRIMARY KEY (id),
+    ADD CONSTRAINT orders_total_check CHECK (total >= 0 AND total <= 500),
+    ADD COLUMN note text;
+CREATE UNIQUE INDEX users_email_key ON public.users USING btree (email);
+CREATE UNIQUE INDEX users_tenant_handle ON public.users USING btree (tenant_id, handle);
+CREATE UNIQUE INDEX users_lower_handle ON public.users USING btree (lower(handle));
+CREATE UNIQUE INDEX users_live_handle ON public.users USING btree (handle) WHERE (deleted_at IS NULL);
+CREATE INDEX orders_user_idx ON public.orders USING btree (user_id);
+ALTER TABLE ONLY public.orders
+    ADD CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+ALTER TABLE ONLY public.orders
+    ADD CONSTRAINT orders_tenant_fkey FOREIGN KEY (tenant_id, user_id) REFERENCES public.users(tenant_id, id);
+ALTER TABLE public.archive ADD PRIMARY KEY (id);
+`
+	result, err := ParseSQLSchema(writeTempFile(t, "test-schema-*.sql", sql))
+	if err != nil {
+		t.Fatalf("ParseSQLSchema() error = %v", err)
+	}
+	users, orders := result.Tables[0], result.Tables[1]
+	if !users.Fields[0].PrimaryKey || !users.Fields[1].Unique || users.Fields[3].Unique {
+		t.Errorf("Expected primary key id and unique email only: %+v", users.Fields)
+	}
+	if fmt.Sprint(users.UniqueKeys) != "[[tenant_id handle]]" {
+		t.Errorf("Expected the unique key (tenant_id, handle), got %v", users.UniqueKeys)
+	}
+	if !orders.Fields[0].PrimaryKey || len(orders.Fields) != 5 || orders.Fields[4].Name != "note" {
+		t.Errorf("Expected primary key id and the added note column: %+v", orders.Fields)
+	}
+	if c := orders.Fields[3].Constraints; c == nil || *c.MinValue != 0 || *c.MaxValue != 500 {
+		t.Errorf("total should range from 0 to 500: %+v", orders.Fields[3])
+	}
+	if c := orders.Fields[1].Constraints; c == nil || c.References == nil || c.References.Table != "users" || c.References.Field != "id" {
+		t.Errorf("user_id should reference users.id: %+v", orders.Fields[1])
+	}
+	if len(orders.ForeignKeys) != 1 || orders.ForeignKeys[0].ToTable != "users" || len(result.Relationships) != 1 {
+		t.Errorf("Expected one composite foreign key and one relationship: %+v %+v", orders.ForeignKeys, result.Relationships)
+	}
+
+	// MySQL dumps add keys after the tables; SQL Server scripts separate statements with GO
+	sql = "CREATE TABLE `users` (`id` int(11) NOT NULL, `email` varchar(50) NOT NULL);\n" +
+		"CREATE TABLE `posts` (`id` int(11) NOT NULL, `user_id` int(11) NOT NULL);\n" +
+		"ALTER TABLE `users` ADD PRIMARY KEY (`id`), ADD UNIQUE KEY `email` (`email`(10));\n" +
+		"ALTER TABLE `posts` ADD PRIMARY KEY (`id`), ADD KEY `user_id` (`user_id`);\n" +
+		"ALTER TABLE `users` MODIFY `id` int(11) NOT NULL AUTO_INCREMENT, AUTO_INCREMENT=3;\n" +
+		"ALTER TABLE `posts`\n  ADD CONSTRAINT `posts_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`);\n"
+	result, err = ParseSQLSchema(writeTempFile(t, "test-schema-*.sql", sql))
+	if err != nil {
+		t.Fatalf("ParseSQLSchema() error = %v", err)
+	}
+	if users := result.Tables[0]; !users.Fields[0].PrimaryKey || !users.Fields[1].Unique {
+		t.Errorf("Expected primary key id and unique email: %+v", users.Fields)
+	}
+	if posts := result.Tables[1]; !posts.Fields[0].PrimaryKey || posts.Fields[1].Constraints == nil || posts.Fields[1].Constraints.References == nil {
+		t.Errorf("Expected primary key id and user_id referencing users: %+v", posts.Fields)
+	}
+
+	sql = `CREATE TABLE [dbo].[accounts] ([id] INT NOT NULL, [code] NCHAR(4) NOT NULL)
+GO
+CREATE TABLE [dbo].[logins] ([id] INT NOT NULL, [account_id] INT NOT NULL)
+GO
+ALTER TABLE [dbo].[accounts] ADD CONSTRAINT [PK_accounts] PRIMARY KEY CLUSTERED ([id] ASC)
+GO
+ALTER TABLE [dbo].[accounts] ADD CONSTRAINT [DF_code] DEFAULT ('AAAA') FOR [code]
+GO
+CREATE UNIQUE NONCLUSTERED INDEX [IX_code] ON [dbo].[accounts] ([code] ASC)
+GO
+ALTER TABLE [dbo].[logins] WITH CHECK ADD CONSTRAINT [FK_logins] FOREIGN KEY([account_id]) REFERENCES [dbo].[accounts] ([id])
+GO
+ALTER TABLE [dbo].[logins] CHECK CONSTRAINT [FK_logins]
+GO
+`
+	result, err = ParseSQLSchema(writeTempFile(t, "test-schema-*.sql", sql))
+	if err != nil {
+		t.Fatalf("ParseSQLSchema() error = %v", err)
+	}
+	if accounts := result.Tables[0]; !accounts.Fields[0].PrimaryKey || !accounts.Fields[1].Unique {
+		t.Errorf("Expected primary key id and unique code: %+v", accounts.Fields)
+	}
+	if logins := result.Tables[1]; logins.Fields[1].Constraints == nil || logins.Fields[1].Constraints.References == nil {
+		t.Errorf("account_id should reference accounts.id: %+v", logins.Fields)
+	}
+	// Partial unique indexes are skipped with a warning
+	sql = "CREATE TABLE users (id INT, email TEXT, deleted_at TIMESTAMP);\n" +
+		"CREATE UNIQUE INDEX users_live_email ON users (email) WHERE deleted_at IS NULL;"
+	result, warnings, err := parseSQL("users.sql", sql, dialect.Postgres)
+	if err != nil {
+		t.Fatalf("parseSQL() error = %v", err)
+	}
+	if result.Tables[0].Fields[1].Unique || len(result.Tables[0].UniqueKeys) != 0 {
+		t.Errorf("A partial index should not make email unique: %+v", result.Tables[0])
+	}
+	want := "users.sql:2:55: ignoring partial unique index on users: it only applies to rows matching deleted_at IS NULL"
+	if len(warnings) != 1 || warnings[0].Error() != want {
+		t.Errorf("Expected warning %q, got %v", want, warnings)
+	}
+}
+
+func TestParseSQLCheckConstraints(t *testing.T) {
+	sql := `CREATE TABLE bookings (
+    id SERIAL PRIMARY KEY,
+    guests INT CHECK (guests BETWEEN 1 AND 8),
+    nights INT CHECK (nights > 0),
+    deposit NUMERIC(8, 2) CHECK (deposit > 0 AND deposit < 1000),
+    stock INT CHECK (stock >= 10 AND stock > 5 AND stock < 100 AND stock <= 200),
+    room VARCHAR(10) CHECK (room LIKE 'R\_%'),
+    sku CHAR(6) CHECK (sku LIKE '%-%'),
+    tag TEXT CHECK (tag LIKE '#%'),
+    code TEXT CHECK (length(code) <= 6 AND char_length(code) > 2),
+    note TEXT CHECK (note IS NULL OR note <> ''),
+    status character varying(10),
+    start_date DATE NOT NULL,
+    end_date DATE,
+    booked_at TIMESTAMP CHECK (booked_at >= '2020-01-01' AND booked_at < CURRENT_TIMESTAMP),
+    nickname TEXT CHECK (nickname ~ '^[a-z]+$' OR nickname = upper(nickname)),
+    CONSTRAINT bookings_status_check CHECK (((status)::text = ANY ((ARRAY['new'::character varying, 'paid'::character varying])::text[]))),
+    CHECK (end_date > start_date)
+);
+ALTER TABLE ONLY bookings ADD CONSTRAINT bookings_guests_check CHECK (guests <> 3);`
+
+	result, warnings, err := parseSQL("bookings.sql", sql, dialect.Postgres)
+	if err != nil {
+		t.Fatalf("parseSQL() error = %v", err)
+	}
+	fields := map[string]schema.Field{}
+	for _, field := range result.Tables[0].Fields {
+		fields[field.Name] = field
+	}
+
+	bounds := map[string][2]float64{"guests": {1, 8}, "nights": {1, math.Inf(1)}, "deposit": {0.01, 999.99}, "stock": {10, 99}}
+	for name, want := range bounds {
+		c := fields[name].Constraints
+		if c == nil || c.MinValue == nil || *c.MinValue != want[0] || (c.MaxValue == nil) != math.IsInf(want[1], 1) ||
+			(c.MaxValue != nil && *c.MaxValue != want[1]) {
+			t.Errorf("%s should range over %v, got %+v", name, want, c)
+		}
+	}
+	// The repeats of % leave room for the other characters within the max length
+	for name, want := range map[string]string{"room": `^R_[A-Za-z0-9]{0,8}$`, "sku": `^[A-Za-z0-9]{0,2}-[A-Za-z0-9]{0,2}$`, "tag": `^#[A-Za-z0-9]*$`} {
+		if c := fields[name].Constraints; c == nil || c.Pattern != want {
+			t.Errorf("%s should have the LIKE pattern %s, got %+v", name, want, c)
+		}
+	}
+	if code := fields["code"]; code.MinLength == nil || *code.MinLength != 3 || code.MaxLength == nil || *code.MaxLength != 6 {
+		t.Errorf("code should be 3 to 6 characters, got %+v", code)
+	}
+	if note := fields["note"]; note.MinLength == nil || *note.MinLength != 1 {
+		t.Errorf("note should not be empty, got %+v", note)
+	}
+	if c := fields["status"].Constraints; c == nil || c.Enum == nil || fmt.Sprint(c.Enum.Values) != "[new paid]" {
+		t.Errorf("status should be new or paid, got %+v", c)
+	}
+	if c := fields["end_date"].Constraints; c == nil || c.DependsOn == nil ||
+		c.DependsOn.Field != "start_date" || c.DependsOn.Operator != "after" {
+		t.Errorf("end_date should come after start_date, got %+v", c)
+	}
+	if c := fields["booked_at"].Constraints; c == nil || c.MinDate != "2020-01-01" || c.MaxDate != "now-1s" {
+		t.Errorf("booked_at should be between 2020 and now, got %+v", c)
+	}
+
+	if len(warnings) != 2 {
+		t.Fatalf("Expected warnings for nickname and guests, got %v", warnings)
+	}
+	for i, want := range []string{"bookings.sql:16:", "bookings.sql:20:"} {
+		if !strings.HasPrefix(warnings[i].Error(), want) || !strings.Contains(warnings[i].Error(), "ignoring CHECK") {
+			t.Errorf("Warning %d = %v, want it at %s", i, warnings[i], want)
+		}
+	}
+}
+
+func TestParseSQLDefaults(t *testing.T) {
+	postgres := `CREATE TABLE public.users (
+    id integer NOT NULL,
+    token uuid DEFAULT gen_random_uuid() NOT NULL,
+    status character varying(20) DEFAULT 'active'::character varying NOT NULL,
+    active boolean DEFAULT true,
+    balance numeric(8,2) DEFAULT -1.5,
+    joined date DEFAULT CURRENT_DATE,
+    created_at timestamp with time zone DEFAULT now(),
+    note text DEFAULT NULL,
+    slug text DEFAULT lower('X')
+);
+ALTER TABLE ONLY public.users ALTER COLUMN id SET DEFAULT nextval('public.users_id_seq'::regclass);`
+
+	result, warnings, err := parseSQL("users.sql", postgres, dialect.Postgres)
+	if err != nil {
+		t.Fatalf("parseSQL() error = %v", err)
+	}
+	fields := result.Tables[0].Fields
+	if !fields[0].AutoIncrement || fields[0].Default != nil {
+		t.Errorf("SET DEFAULT nextval() should make id auto-increment: %+v", fields[0])
+	}
+	expected := []schema.Default{
+		{Function: "uuid"}, {Value: "active"}, {Value: true}, {Value: -1.5}, {Function: "today"}, {Function: "now"},
+	}
+	for i, want := range expected {
+		field := fields[i+1]
+		if field.Default == nil || fmt.Sprint(*field.Default) != fmt.Sprint(want) {
+			t.Errorf("Field %s default = %+v, want %+v", field.Name, field.Default, want)
+		}
+	}
+	if fields[7].Default != nil || fields[8].Default != nil {
+		t.Errorf("DEFAULT NULL and unknown expressions should set no default: %+v, %+v", fields[7].Default, fields[8].Default)
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0].Error(), "users.sql:10:23: ignoring DEFAULT lower('X')") {
+		t.Errorf("Expected a warning for the slug default, got %v", warnings)
+	}
+
+	sqlserver := `CREATE TABLE [dbo].[flags] ([id] INT NOT NULL, [enabled] BIT NOT NULL, [added] DATETIME2 NULL)
+GO
+ALTER TABLE [dbo].[flags] ADD CONSTRAINT [DF_flags_enabled] DEFAULT ((1)) FOR [enabled]
+GO
+ALTER TABLE [dbo].[flags] ADD DEFAULT (getdate()) FOR [added]
+GO
+`
+	result, _, err = parseSQL("flags.sql", sqlserver, dialect.SQLServer)
+	if err != nil {
+		t.Fatalf("parseSQL() error = %v", err)
+	}
+	enabled, added := result.Tables[0].Fields[1], result.Tables[0].Fields[2]
+	if enabled.Default == nil || enabled.Default.Value != true {
+		t.Errorf("DEFAULT ((1)) FOR a BIT column should default to true: %+v", enabled.Default)
+	}
+	if added.Default == nil || added.Default.Function != "now" {
+		t.Errorf("DEFAULT (getdate()) should default to now: %+v", added.Default)
+	}
+
+	path := writeTempFile(t, "test-schema-*.json", `{
+		"default_ratio": 0.5,
+		"fields": [
+			{"name": "status", "type": "string", "default": "active"},
+			{"name": "created_at", "type": "timestamp", "default": {"function": "now"}, "default_ratio": 1},
+			{"name": "settings", "type": "json", "default": {"theme": "dark"}}
+		]
+	}`)
+	parsed, err := ParseJSONSchema(path)
+	if err != nil {
+		t.Fatalf("ParseJSONSchema() error = %v", err)
+	}
+	if parsed.DefaultRatio == nil || *parsed.DefaultRatio != 0.5 || parsed.Fields[1].DefaultRatio == nil {
+		t.Errorf("Unexpected default ratios %+v", parsed)
+	}
+	if d := parsed.Fields[0].Default; d == nil || d.Value != "active" {
+		t.Errorf("Unexpected default %+v", d)
+	}
+	if d := parsed.Fields[1].Default; d == nil || d.Function != "now" || d.Value != nil {
+		t.Errorf("Unexpected function default %+v", d)
+	}
+	if d := parsed.Fields[2].Default; d == nil || fmt.Sprint(d.Value) != "map[theme:dark]" {
+		t.Errorf("An object default should be kept as the value, got %+v", d)
+	}
+}
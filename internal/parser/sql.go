@@ -1,188 +1,1115 @@
 package parser
 
 import (
-	"bufio"
+	"go-fake/internal/dialect"
 	"go-fake/internal/schema"
+	"go-fake/pkg/logger"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
 )
 
+var (
+	// Column types that number rows by themselves
+	serialTypes = map[string]bool{
+		"SERIAL": true, "SMALLSERIAL": true, "BIGSERIAL": true,
+		"SERIAL2": true, "SERIAL4": true, "SERIAL8": true,
+	}
+
+	// Exact numeric column types: DECIMAL(p, s) and NUMERIC(p, s)
+	decimalTypes = map[string]bool{"DECIMAL": true, "NUMERIC": true, "DEC": true}
+
+	// Character column types with a length, and whether values are padded
+	// to it: VARCHAR(n), CHARACTER VARYING(n), NVARCHAR(n) and the
+	// fixed-length CHAR(n), CHARACTER(n), NCHAR(n)
+	charTypes = map[string]bool{
+		"VARCHAR": false, "VARCHAR2": false, "NVARCHAR": false, "NVARCHAR2": false,
+		"CHARACTER VARYING": false, "CHAR": true, "NCHAR": true, "CHARACTER": true,
+	}
+)
+
 // ParseSQLSchema reads an SQL file and returns a structured schema with multiple tables.
+// CREATE TABLE and CREATE TYPE ... AS ENUM statements are read; other
+// statements are skipped. Syntax errors are reported with their line and column.
+// The SQL dialect is detected from the file.
 func ParseSQLSchema(filePath string) (schema.Schema, error) {
-	file, err := os.Open(filePath)
+	return ParseSQLSchemaWithDialect(filePath, dialect.Generic)
+}
+
+// ParseSQLSchemaWithDialect reads an SQL file written for a dialect, which
+// decides how column types map to field types. With dialect.Generic the
+// dialect is detected from the file.
+func ParseSQLSchemaWithDialect(filePath string, d dialect.Dialect) (schema.Schema, error) {
+	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return schema.Schema{}, err
 	}
-	defer file.Close()
+	if d == dialect.Generic {
+		d = dialect.Detect(string(content))
+		logger.Debug("Detected SQL dialect: %s", d)
+	}
+	s, warnings, err := parseSQL(filePath, string(content), d)
+	for _, warning := range warnings {
+		logger.Warn("%v", warning)
+	}
+	return s, err
+}
+
+// parseSQL parses the statements of SQL source read from path. Constraints
+// that cannot be generated are returned as warnings.
+func parseSQL(path, src string, d dialect.Dialect) (schema.Schema, []error, error) {
+	tokens, err := lexSQL(path, src, d)
+	if err != nil {
+		return schema.Schema{}, nil, err
+	}
 
 	var s schema.Schema
-	var relationships []schema.Relationship
-	scanner := bufio.NewScanner(file)
-	
-	var currentTable *schema.Table
-	var inTableDefinition bool
-	
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "--") {
-			continue // Skip empty lines and comments
-		}
-
-		// Check for CREATE TABLE statement
-		if strings.HasPrefix(strings.ToUpper(line), "CREATE TABLE") {
-			tableName := extractTableName(line)
-			if tableName != "" {
-				currentTable = &schema.Table{
-					Name:   tableName,
-					Fields: []schema.Field{},
-				}
-				inTableDefinition = true
+	var warnings []error
+	var pending []pendingReference
+	p := &sqlParser{
+		path:          path,
+		src:           src,
+		tokens:        tokens,
+		dialect:       d,
+		enumTypes:     make(map[string][]interface{}),
+		relationships: &s.Relationships,
+		pending:       &pending,
+		warnings:      &warnings,
+	}
+	for p.peek().kind != sqlEOF {
+		if err := p.parseStatement(&s); err != nil {
+			return schema.Schema{}, nil, err
+		}
+	}
+	p.resolveReferences(&s)
+	return s, warnings, nil
+}
+
+// sqlParser reads statements from the tokens of an SQL file. Definitions
+// inside CREATE TABLE are parsed by sub-parsers over their own tokens, which
+// share the enum types and relationships found so far.
+type sqlParser struct {
+	path   string
+	src    string
+	tokens []sqlToken
+	pos    int
+
+	dialect       dialect.Dialect
+	enumTypes     map[string][]interface{} // lower-case type name -> values
+	relationships *[]schema.Relationship
+	pending       *[]pendingReference
+	warnings      *[]error
+}
+
+// pendingReference is a foreign key declared without a column list, such as
+// user_id INT REFERENCES users, which references the primary key of a table
+// that may only be complete at the end of the file
+type pendingReference struct {
+	table   string
+	fields  []string
+	toTable string
+	tok     sqlToken // the referenced table name, for warnings
+}
+
+func (p *sqlParser) peek() sqlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *sqlParser) next() sqlToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != sqlEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword reports whether the next tokens are the keywords, consuming them if so
+func (p *sqlParser) keyword(words ...string) bool {
+	for i, word := range words {
+		if p.pos+i >= len(p.tokens) || !p.tokens[p.pos+i].is(word) {
+			return false
+		}
+	}
+	p.pos += len(words)
+	return true
+}
+
+// symbol reports whether the next token is the symbol, consuming it if so
+func (p *sqlParser) symbol(s string) bool {
+	if p.peek().isSymbol(s) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *sqlParser) errorf(tok sqlToken, format string, args ...interface{}) error {
+	return syntaxError(p.path, p.src, tok.start, format, args...)
+}
+
+// warnf records a warning at a token
+func (p *sqlParser) warnf(tok sqlToken, format string, args ...interface{}) {
+	*p.warnings = append(*p.warnings, p.errorf(tok, format, args...))
+}
+
+// sub returns a parser over some of the tokens
+func (p *sqlParser) sub(tokens []sqlToken) *sqlParser {
+	end := p.peek().start
+	if len(tokens) > 0 {
+		end = tokens[len(tokens)-1].end
+	}
+	q := *p
+	q.tokens = append(tokens[:len(tokens):len(tokens)], sqlToken{kind: sqlEOF, start: end, end: end})
+	q.pos = 0
+	return &q
+}
+
+// skipStatement skips the tokens up to the end of the statement
+func (p *sqlParser) skipStatement() {
+	_ = p.statement()
+}
+
+// statement reads the tokens up to the end of the statement and returns
+// them without the semicolon. Without a semicolon, a CREATE or a SQL Server
+// GO ends the statement too.
+func (p *sqlParser) statement() []sqlToken {
+	depth, start := 0, p.pos
+	for {
+		if depth <= 0 && ((p.pos > start && p.peek().is("CREATE")) || p.atGo()) {
+			return p.tokens[start:p.pos]
+		}
+		switch tok := p.next(); {
+		case tok.kind == sqlEOF:
+			return p.tokens[start:p.pos]
+		case tok.isSymbol("("):
+			depth++
+		case tok.isSymbol(")"):
+			depth--
+		case tok.isSymbol(";") && depth <= 0:
+			return p.tokens[start : p.pos-1]
+		}
+	}
+}
+
+// atGo reports whether the next token is a SQL Server GO, which separates
+// batches of statements on a line of its own
+func (p *sqlParser) atGo() bool {
+	tok := p.peek()
+	if !tok.is("GO") {
+		return false
+	}
+	lineStart := strings.LastIndexByte(p.src[:tok.start], '\n') + 1
+	return strings.TrimSpace(p.src[lineStart:tok.start]) == ""
+}
+
+// skipParens skips a parenthesized group starting at the next token
+func (p *sqlParser) skipParens() {
+	_, _ = p.parenBody()
+}
+
+// parenBody reads a parenthesized group and returns the tokens inside it
+func (p *sqlParser) parenBody() ([]sqlToken, error) {
+	open := p.peek()
+	if !p.symbol("(") {
+		return nil, p.errorf(open, "expected \"(\", found %s", open)
+	}
+	start, depth := p.pos, 1
+	for {
+		tok := p.next()
+		switch {
+		case tok.kind == sqlEOF:
+			return nil, p.errorf(open, "missing \")\" for this \"(\"")
+		case tok.isSymbol("("):
+			depth++
+		case tok.isSymbol(")"):
+			if depth--; depth == 0 {
+				return p.tokens[start : p.pos-1], nil
 			}
-			continue
 		}
+	}
+}
+
+// name reads a possibly schema-qualified name, such as public.users or
+// [dbo].[orders], and returns its last part
+func (p *sqlParser) name(what string) (string, error) {
+	tok := p.next()
+	if !tok.isName() {
+		return "", p.errorf(tok, "expected %s name, found %s", what, tok)
+	}
+	name := tok.text
+	for p.peek().isSymbol(".") {
+		p.next()
+		if tok = p.next(); !tok.isName() {
+			return "", p.errorf(tok, "expected %s name after \".\", found %s", what, tok)
+		}
+		name = tok.text
+	}
+	return name, nil
+}
 
-		// Check for end of table definition
-		if strings.Contains(line, ");") {
-			if currentTable != nil {
-				s.Tables = append(s.Tables, *currentTable)
-				currentTable = nil
+// columnList reads a parenthesized list of column names. Index options such
+// as ASC, DESC or a prefix length are skipped.
+func (p *sqlParser) columnList() ([]string, error) {
+	body, err := p.parenBody()
+	if err != nil {
+		return nil, err
+	}
+	var columns []string
+	for _, item := range splitTokens(body) {
+		if len(item) == 0 || !item[0].isName() {
+			tok := p.peek()
+			if len(item) > 0 {
+				tok = item[0]
 			}
-			inTableDefinition = false
+			return nil, p.errorf(tok, "expected column name, found %s", tok)
+		}
+		columns = append(columns, item[0].text)
+	}
+	return columns, nil
+}
+
+// splitTokens splits tokens at the commas outside parentheses
+func splitTokens(tokens []sqlToken) [][]sqlToken {
+	var items [][]sqlToken
+	depth, start := 0, 0
+	for i, tok := range tokens {
+		switch {
+		case tok.isSymbol("("):
+			depth++
+		case tok.isSymbol(")"):
+			depth--
+		case tok.isSymbol(",") && depth == 0:
+			items = append(items, tokens[start:i])
+			start = i + 1
+		}
+	}
+	if len(tokens) > 0 {
+		items = append(items, tokens[start:])
+	}
+	return items
+}
+
+// text returns the source text of some tokens with runs of whitespace collapsed
+func (p *sqlParser) text(tokens []sqlToken) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+	return strings.Join(strings.Fields(p.src[tokens[0].start:tokens[len(tokens)-1].end]), " ")
+}
+
+// parseStatement parses one statement, skipping those that do not describe tables
+func (p *sqlParser) parseStatement(s *schema.Schema) error {
+	if p.symbol(";") {
+		return nil
+	}
+	if p.atGo() {
+		p.next()
+		return nil
+	}
+	if p.keyword("CREATE") {
+		p.keyword("OR", "REPLACE")
+		for p.keyword("TEMP") || p.keyword("TEMPORARY") || p.keyword("UNLOGGED") || p.keyword("GLOBAL") || p.keyword("LOCAL") {
+		}
+		switch {
+		case p.keyword("TABLE"):
+			return p.parseCreateTable(s)
+		case p.keyword("TYPE"):
+			return p.parseCreateType()
+		case p.keyword("UNIQUE"):
+			return p.parseCreateUniqueIndex(s)
+		}
+	}
+	if p.keyword("ALTER", "TABLE") {
+		return p.parseAlterTable(s)
+	}
+	p.skipStatement()
+	return nil
+}
+
+// findTable returns the table with a name, matching its case first
+func findTable(s *schema.Schema, name string) *schema.Table {
+	for i := range s.Tables {
+		if s.Tables[i].Name == name {
+			return &s.Tables[i]
+		}
+	}
+	for i := range s.Tables {
+		if strings.EqualFold(s.Tables[i].Name, name) {
+			return &s.Tables[i]
+		}
+	}
+	return nil
+}
+
+// parseAlterTable applies the keys, foreign keys, checks and columns that an
+// ALTER TABLE ... ADD statement adds to a table created earlier in the file,
+// as schema dumps declare them once every table exists. Other changes, and
+// tables the file does not create, are skipped.
+func (p *sqlParser) parseAlterTable(s *schema.Schema) error {
+	p.keyword("IF", "EXISTS")
+	p.keyword("ONLY")
+	name, err := p.name("table")
+	if err != nil {
+		return err
+	}
+	actions := splitTokens(p.statement())
+	table := findTable(s, name)
+	if table == nil {
+		return nil
+	}
+
+	var checks [][]sqlToken
+	for _, action := range actions {
+		q := p.sub(action)
+		_ = q.keyword("WITH", "CHECK") || q.keyword("WITH", "NOCHECK")
+		if q.keyword("ALTER") {
+			q.alterColumnDefault(table)
 			continue
 		}
+		if !q.keyword("ADD") {
+			continue
+		}
+		if q.addsDefault() {
+			q.addDefault(table)
+			continue
+		}
+		q.keyword("COLUMN")
+		q.keyword("IF", "NOT", "EXISTS")
+		defChecks, err := q.parseTableDefinition(table)
+		if err != nil {
+			return err
+		}
+		checks = append(checks, defChecks...)
+	}
+	for _, check := range checks {
+		p.applyCheck(table, check)
+	}
+	applyTableKeys(table, p.relationships)
+	return nil
+}
 
-		// Parse field definitions within table
-		if inTableDefinition && currentTable != nil {
-			field := parseFieldDefinitionWithConstraints(line, currentTable.Name, &relationships)
-			if field.Name != "" {
-				currentTable.Fields = append(currentTable.Fields, field)
-			}
+// addsDefault reports whether an ALTER TABLE ... ADD action is a SQL Server
+// [CONSTRAINT name] DEFAULT value FOR column
+func (p *sqlParser) addsDefault() bool {
+	i := p.pos
+	if p.tokens[i].is("CONSTRAINT") {
+		i += 2
+	}
+	return i < len(p.tokens) && p.tokens[i].is("DEFAULT")
+}
+
+// addDefault applies a SQL Server [CONSTRAINT name] DEFAULT value FOR column
+func (p *sqlParser) addDefault(table *schema.Table) {
+	if p.keyword("CONSTRAINT") {
+		p.next()
+	}
+	p.keyword("DEFAULT")
+	action := p.tokens[p.pos : len(p.tokens)-1]
+	i := topLevel(action, func(tok sqlToken) bool { return tok.is("FOR") })
+	if i < 0 || i+1 >= len(action) {
+		return
+	}
+	if field := findField(table, action[i+1].text); field != nil {
+		p.applyDefault(field, action[:i])
+	}
+}
+
+// alterColumnDefault applies an ALTER [COLUMN] name SET DEFAULT value or DROP
+// DEFAULT action, as pg_dump writes the nextval() of serial columns, and the
+// ADD GENERATED ... AS IDENTITY of identity columns
+func (p *sqlParser) alterColumnDefault(table *schema.Table) {
+	p.keyword("COLUMN")
+	field := findField(table, p.next().text)
+	switch {
+	case field == nil:
+	case p.keyword("SET", "DEFAULT"):
+		p.applyDefault(field, p.tokens[p.pos:len(p.tokens)-1])
+	case p.keyword("DROP", "DEFAULT"):
+		field.Default = nil
+	case p.keyword("ADD", "GENERATED"):
+		field.AutoIncrement = true
+	}
+}
+
+// parseCreateUniqueIndex applies a CREATE UNIQUE INDEX statement to its
+// table. Indexes on expressions such as lower(email) are skipped, and so are
+// partial indexes with a WHERE clause, with a warning.
+func (p *sqlParser) parseCreateUniqueIndex(s *schema.Schema) error {
+	_ = p.keyword("CLUSTERED") || p.keyword("NONCLUSTERED")
+	if !p.keyword("INDEX") {
+		p.skipStatement()
+		return nil
+	}
+	p.keyword("CONCURRENTLY")
+	p.keyword("IF", "NOT", "EXISTS")
+	if !p.peek().is("ON") {
+		if _, err := p.name("index"); err != nil {
+			return err
 		}
 	}
+	if p.keyword("USING") {
+		p.next() // MySQL USING BTREE before ON
+	}
+	if !p.keyword("ON") {
+		return p.errorf(p.peek(), "expected ON, found %s", p.peek())
+	}
+	p.keyword("ONLY")
+	name, err := p.name("table")
+	if err != nil {
+		return err
+	}
+	if p.keyword("USING") {
+		p.next() // Postgres index method, e.g. USING btree
+	}
+	body, err := p.parenBody()
+	if err != nil {
+		return err
+	}
+	// A partial index only makes the rows matching its WHERE clause unique
+	rest := p.statement()
+	if i := topLevel(rest, func(tok sqlToken) bool { return tok.is("WHERE") }); i >= 0 {
+		p.warnf(rest[i], "ignoring partial unique index on %s: it only applies to rows matching %s", name, p.text(rest[i+1:]))
+		return nil
+	}
 
-	if err := scanner.Err(); err != nil {
-		return schema.Schema{}, err
+	var columns []string
+	for _, item := range splitTokens(body) {
+		// A column may have a prefix length, email(10), but not be an expression
+		if len(item) == 0 || !item[0].isName() || (len(item) > 2 && item[1].isSymbol("(") && item[2].kind != sqlNumber) {
+			return nil
+		}
+		columns = append(columns, item[0].text)
 	}
+	if table := findTable(s, name); table != nil {
+		table.UniqueKeys = append(table.UniqueKeys, columns)
+		applyTableKeys(table, p.relationships)
+	}
+	return nil
+}
+
+// parseCreateType records the values of a CREATE TYPE name AS ENUM (...) statement
+func (p *sqlParser) parseCreateType() error {
+	name, err := p.name("type")
+	if err != nil {
+		return err
+	}
+	if p.keyword("AS", "ENUM") {
+		body, err := p.parenBody()
+		if err != nil {
+			return err
+		}
+		if values, ok := tokenValues(body); ok {
+			p.enumTypes[strings.ToLower(name)] = values
+		}
+	}
+	p.skipStatement()
+	return nil
+}
 
-	// Add parsed relationships to schema
-	s.Relationships = relationships
+// parseCreateTable parses a CREATE TABLE statement and adds the table to the schema
+func (p *sqlParser) parseCreateTable(s *schema.Schema) error {
+	p.keyword("IF", "NOT", "EXISTS")
+	name, err := p.name("table")
+	if err != nil {
+		return err
+	}
+	if !p.peek().isSymbol("(") {
+		// CREATE TABLE ... AS SELECT and CREATE TABLE ... LIKE define no columns
+		p.skipStatement()
+		return nil
+	}
+	open := p.peek()
+	body, err := p.parenBody()
+	if err != nil {
+		return p.errorf(open, "CREATE TABLE %s is missing its closing \")\"", name)
+	}
+	if err := p.skipTableOptions(name); err != nil {
+		return err
+	}
+
+	table := schema.Table{Name: name, Fields: []schema.Field{}}
+	var checks [][]sqlToken
+	for _, def := range splitTokens(body) {
+		if len(def) == 0 {
+			return p.errorf(open, "empty column definition in table %s", name)
+		}
+		q := p.sub(def)
+		defChecks, err := q.parseTableDefinition(&table)
+		if err != nil {
+			return err
+		}
+		checks = append(checks, defChecks...)
+	}
+	for _, check := range checks {
+		p.applyCheck(&table, check)
+	}
+	applyTableKeys(&table, p.relationships)
+	s.Tables = append(s.Tables, table)
+	return nil
+}
 
-	return s, nil
+// skipTableOptions skips the options after the columns of a table, such as
+// ENGINE=InnoDB, up to the end of the statement. A statement that starts
+// without a semicolon before it ends the options too.
+func (p *sqlParser) skipTableOptions(table string) error {
+	for {
+		switch tok := p.peek(); {
+		case tok.kind == sqlEOF, tok.is("CREATE"), tok.is("ALTER"):
+			return nil
+		case p.symbol(";"):
+			return nil
+		case tok.isSymbol(")"):
+			return p.errorf(tok, "unexpected \")\" after the columns of table %s", table)
+		case tok.isSymbol("("):
+			p.skipParens()
+		default:
+			p.next()
+		}
+	}
 }
 
-// extractTableName extracts the table name from a CREATE TABLE statement.
-func extractTableName(line string) string {
-	// Regex to match CREATE TABLE table_name
-	re := regexp.MustCompile(`CREATE\s+TABLE\s+(\w+)`)
-	matches := re.FindStringSubmatch(strings.ToUpper(line))
-	if len(matches) > 1 {
-		// Return the original case from the line
-		originalRe := regexp.MustCompile(`CREATE\s+TABLE\s+(\w+)`)
-		originalMatches := originalRe.FindStringSubmatch(line)
-		if len(originalMatches) > 1 {
-			return originalMatches[1]
+// parseTableDefinition parses a column or a table-level key of a CREATE
+// TABLE statement. CHECK constraints, of the table or of a column, are
+// returned to be applied once every column is known.
+func (p *sqlParser) parseTableDefinition(table *schema.Table) ([][]sqlToken, error) {
+	named := p.keyword("CONSTRAINT")
+	if named {
+		if _, err := p.name("constraint"); err != nil {
+			return nil, err
 		}
 	}
-	return ""
+
+	switch {
+	case p.keyword("PRIMARY", "KEY"):
+		_ = p.keyword("CLUSTERED") || p.keyword("NONCLUSTERED")
+		columns, err := p.columnList()
+		if err != nil {
+			return nil, err
+		}
+		table.PrimaryKey = columns
+	case p.keyword("UNIQUE"):
+		_ = p.keyword("KEY") || p.keyword("INDEX")
+		_ = p.keyword("CLUSTERED") || p.keyword("NONCLUSTERED")
+		if p.peek().isName() {
+			p.next() // index name
+		}
+		columns, err := p.columnList()
+		if err != nil {
+			return nil, err
+		}
+		table.UniqueKeys = append(table.UniqueKeys, columns)
+	case p.keyword("FOREIGN", "KEY"):
+		if p.peek().isName() {
+			p.next() // index name
+		}
+		fk, err := p.foreignKey(table.Name)
+		if err != nil {
+			return nil, err
+		}
+		if fk != nil {
+			table.ForeignKeys = append(table.ForeignKeys, *fk)
+		}
+	case p.keyword("CHECK"):
+		body, err := p.parenBody()
+		if err != nil {
+			return nil, err
+		}
+		return [][]sqlToken{body}, nil
+	case p.keyword("EXCLUDE"):
+		// Postgres exclusion constraints are not generated
+		p.skipStatement()
+	case named:
+		return nil, p.errorf(p.peek(), "expected PRIMARY KEY, UNIQUE, FOREIGN KEY or CHECK, found %s", p.peek())
+	case p.isIndex():
+		// Plain indexes do not constrain the data
+	default:
+		field, checks, err := p.parseColumn(table.Name)
+		if err != nil {
+			return nil, err
+		}
+		table.Fields = append(table.Fields, field)
+		return checks, nil
+	}
+	return nil, nil
 }
 
-// parseFieldDefinitionWithConstraints parses a field definition line and extracts constraints and relationships
-func parseFieldDefinitionWithConstraints(line, tableName string, relationships *[]schema.Relationship) schema.Field {
-	// Clean up the line
-	line = strings.TrimSpace(line)
-	line = strings.TrimSuffix(line, ",")
-	
-	// Skip empty lines and various SQL constraint/index definitions
-	upperLine := strings.ToUpper(line)
-	if line == "" || 
-		strings.HasPrefix(upperLine, "PRIMARY KEY") || 
-		strings.HasPrefix(upperLine, "CONSTRAINT") ||
-		strings.HasPrefix(upperLine, "INDEX") ||
-		strings.HasPrefix(upperLine, "UNIQUE(") ||
-		strings.HasPrefix(upperLine, "FOREIGN KEY") ||
-		strings.HasPrefix(upperLine, "KEY") {
-		return schema.Field{}
+// foreignKey reads the columns of a foreign key and the table and columns it
+// references. A foreign key without referenced columns is left pending until
+// the primary key of its table is known, and nil is returned.
+func (p *sqlParser) foreignKey(tableName string) (*schema.ForeignKey, error) {
+	columns, err := p.columnList()
+	if err != nil {
+		return nil, err
+	}
+	if !p.keyword("REFERENCES") {
+		return nil, p.errorf(p.peek(), "expected REFERENCES, found %s", p.peek())
 	}
+	tok := p.peek()
+	toTable, err := p.name("table")
+	if err != nil {
+		return nil, err
+	}
+	if !p.peek().isSymbol("(") {
+		*p.pending = append(*p.pending, pendingReference{table: tableName, fields: columns, toTable: toTable, tok: tok})
+		return nil, nil
+	}
+	toColumns, err := p.columnList()
+	if err != nil {
+		return nil, err
+	}
+	return &schema.ForeignKey{Fields: columns, ToTable: toTable, ToFields: toColumns}, nil
+}
+
+// isIndex reports whether a definition is a MySQL INDEX, KEY, FULLTEXT or
+// SPATIAL index, e.g. KEY idx_email (email), rather than a column named key
+func (p *sqlParser) isIndex() bool {
+	tok := p.peek()
+	if tok.is("FULLTEXT") || tok.is("SPATIAL") {
+		return true
+	}
+	if !tok.is("INDEX") && !tok.is("KEY") {
+		return false
+	}
+	i := p.pos + 1
+	if p.tokens[i].isName() {
+		i++
+	}
+	// An index lists columns; a column type such as VARCHAR(10) has a length
+	return p.tokens[i].isSymbol("(") && !p.tokens[i+1].isSymbol(")") && p.tokens[i+1].kind != sqlNumber
+}
 
-	parts := strings.Fields(line)
-	if len(parts) < 2 {
-		return schema.Field{}
+// columnConstraints are the keywords that end the type of a column definition
+var columnConstraints = map[string]bool{
+	"NOT": true, "NULL": true, "PRIMARY": true, "UNIQUE": true, "REFERENCES": true,
+	"CHECK": true, "DEFAULT": true, "GENERATED": true, "AS": true, "AUTO_INCREMENT": true,
+	"AUTOINCREMENT": true, "IDENTITY": true, "CONSTRAINT": true, "COLLATE": true,
+	"COMMENT": true, "ON": true, "CHARSET": true,
+}
+
+// atColumnConstraint reports whether the next token starts a column constraint
+func (p *sqlParser) atColumnConstraint() bool {
+	tok := p.peek()
+	if tok.kind != sqlWord {
+		return false
+	}
+	if tok.is("CHARACTER") {
+		return p.tokens[p.pos+1].is("SET")
 	}
+	return columnConstraints[strings.ToUpper(tok.text)]
+}
 
+// parseColumn parses a column definition: its name, its type and its
+// constraints. Its CHECK constraints are returned to be applied with those
+// of the table.
+func (p *sqlParser) parseColumn(tableName string) (schema.Field, [][]sqlToken, error) {
+	nameTok := p.next()
+	if !nameTok.isName() {
+		return schema.Field{}, nil, p.errorf(nameTok, "expected column name, found %s", nameTok)
+	}
 	field := schema.Field{
-		Name:        parts[0],
-		Type:        mapSQLType(parts[1]),
+		Name:        nameTok.text,
 		Required:    false,
 		Constraints: &schema.Constraint{},
 	}
 
-	// Parse constraints from the line (upperLine already defined above)
-	
-	// Check for NOT NULL
-	if strings.Contains(upperLine, "NOT NULL") {
-		field.Required = true
-	}
-
-	// Check for CHECK constraints with min/max values
-	checkRe := regexp.MustCompile(`CHECK\s*\(\s*(\w+)\s*>=\s*(\d+)\s*AND\s*(\w+)\s*<=\s*(\d+)\s*\)`)
-	if matches := checkRe.FindStringSubmatch(upperLine); len(matches) == 5 {
-		if min, err := strconv.Atoi(matches[2]); err == nil {
-			field.Constraints.MinValue = &min
-		}
-		if max, err := strconv.Atoi(matches[4]); err == nil {
-			field.Constraints.MaxValue = &max
+	typeStart := p.pos
+	for p.peek().kind != sqlEOF && !p.atColumnConstraint() {
+		if p.peek().isSymbol("(") {
+			p.skipParens()
+		} else {
+			p.next()
 		}
 	}
+	typeTokens := p.tokens[typeStart:p.pos]
+	sqlType := dialect.ParseType(p.text(typeTokens))
+	field.Type = mapSQLType(sqlType, p.dialect)
+	p.applyColumnType(&field, sqlType, typeTokens)
 
-	// Check for REFERENCES (foreign key)
-	refRe := regexp.MustCompile(`REFERENCES\s+(\w+)\s*\(\s*(\w+)\s*\)`)
-	if matches := refRe.FindStringSubmatch(upperLine); len(matches) == 3 {
-		field.Constraints.References = &schema.Reference{
-			Table: matches[1],
-			Field: matches[2],
+	var checks [][]sqlToken
+	for p.peek().kind != sqlEOF {
+		switch {
+		case p.keyword("NOT", "NULL"):
+			field.Required = true
+		case p.keyword("PRIMARY", "KEY"):
+			field.PrimaryKey = true
+			field.Required = true
+		case p.keyword("UNIQUE"):
+			field.Unique = true
+			p.keyword("KEY")
+		case p.keyword("AUTO_INCREMENT") || p.keyword("AUTOINCREMENT") || p.keyword("IDENTITY"):
+			field.AutoIncrement = true
+		case p.keyword("REFERENCES"):
+			if err := p.columnReference(&field, tableName); err != nil {
+				return schema.Field{}, nil, err
+			}
+		case p.keyword("CHECK"):
+			body, err := p.parenBody()
+			if err != nil {
+				return schema.Field{}, nil, err
+			}
+			checks = append(checks, body)
+		case p.keyword("GENERATED"):
+			_ = p.keyword("ALWAYS") || p.keyword("BY", "DEFAULT")
+			if !p.keyword("AS") {
+				return schema.Field{}, nil, p.errorf(p.peek(), "expected AS after GENERATED, found %s", p.peek())
+			}
+			if p.keyword("IDENTITY") {
+				field.AutoIncrement = true
+				continue
+			}
+			fallthrough
+		case p.keyword("AS"):
+			// Generated columns: GENERATED ALWAYS AS (expr) [STORED] and MySQL AS (expr)
+			open := p.peek()
+			body, err := p.parenBody()
+			if err != nil {
+				return schema.Field{}, nil, err
+			}
+			if len(body) == 0 {
+				return schema.Field{}, nil, p.errorf(open, "empty expression for generated column %s", field.Name)
+			}
+			field.Expression = p.src[body[0].start:body[len(body)-1].end]
+		case p.keyword("DEFAULT"):
+			p.applyDefault(&field, p.defaultExpression())
+		case p.peek().isSymbol("("):
+			p.skipParens()
+		default:
+			p.next() // Collations, comments and other options
 		}
-		
-		// Add to relationships
-		*relationships = append(*relationships, schema.Relationship{
-			Type:      "foreign_key",
-			FromTable: tableName,
-			FromField: field.Name,
-			ToTable:   matches[1],
-			ToField:   matches[2],
-			Cardinality: "many:1",
-		})
 	}
 
+	p.applyTypeRange(&field, sqlType)
+
 	// If no constraints were set, remove the empty constraints object
-	if field.Constraints.References == nil && field.Constraints.MinValue == nil && field.Constraints.MaxValue == nil {
+	if field.Constraints.References == nil && field.Constraints.MinValue == nil && field.Constraints.MaxValue == nil && field.Constraints.Enum == nil {
 		field.Constraints = nil
 	}
+	return field, checks, nil
+}
 
-	return field
+// defaultFunctions are the SQL functions whose defaults generate UUIDs
+var defaultFunctions = map[string]string{
+	"gen_random_uuid": "uuid", "uuid_generate_v4": "uuid", "uuid_generate_v1": "uuid",
+	"uuid": "uuid", "newid": "uuid", "newsequentialid": "uuid",
 }
 
-// mapSQLType maps SQL types to internal types.
-func mapSQLType(sqlType string) string {
-	sqlType = strings.ToUpper(sqlType)
-	
+// defaultExpression reads the expression of a DEFAULT, which ends at the
+// next column constraint
+func (p *sqlParser) defaultExpression() []sqlToken {
+	start := p.pos
+	for p.peek().kind != sqlEOF && (p.pos == start || !p.atColumnConstraint()) {
+		if p.peek().isSymbol("(") {
+			p.skipParens()
+		} else {
+			p.next()
+		}
+	}
+	return p.tokens[start:p.pos]
+}
+
+// applyDefault sets the DEFAULT of a column: a literal, or now(), CURRENT_DATE
+// or a UUID function, which generate values. nextval() makes the column
+// auto-increment, as pg_dump declares serial columns. Other expressions are
+// reported as warnings.
+func (p *sqlParser) applyDefault(field *schema.Field, tokens []sqlToken) {
+	field.Default = nil
+	tokens = stripCasts(tokens)
+	switch {
+	case len(tokens) == 0 || (len(tokens) == 1 && tokens[0].is("NULL")):
+		return
+	case len(tokens) == 1 && (tokens[0].is("TRUE") || tokens[0].is("FALSE")):
+		field.Default = &schema.Default{Value: tokens[0].is("TRUE")}
+		return
+	case len(tokens) > 1 && tokens[0].kind == sqlWord && tokens[1].isSymbol("("):
+		name := strings.ToLower(tokens[0].text)
+		if name == "nextval" {
+			field.AutoIncrement = true
+			return
+		}
+		if function := defaultFunctions[name]; function != "" {
+			field.Default = &schema.Default{Function: function}
+			return
+		}
+	}
+
+	value, err := p.operand(&schema.Table{}, tokens)
 	switch {
-	case strings.Contains(sqlType, "SERIAL"):
-		return "int"
-	case strings.Contains(sqlType, "INTEGER") || strings.Contains(sqlType, "INT"):
-		return "int"
-	case strings.Contains(sqlType, "VARCHAR") || strings.Contains(sqlType, "TEXT"):
-		return "string"
-	case strings.Contains(sqlType, "DECIMAL") || strings.Contains(sqlType, "NUMERIC") || strings.Contains(sqlType, "FLOAT"):
-		return "float"
-	case strings.Contains(sqlType, "BOOLEAN") || strings.Contains(sqlType, "BOOL"):
-		return "boolean"
-	case strings.Contains(sqlType, "DATE"):
-		return "date"
-	case strings.Contains(sqlType, "TIMESTAMP") || strings.Contains(sqlType, "DATETIME"):
-		return "timestamp"
+	case err == nil && value.now != "":
+		field.Default = &schema.Default{Function: value.now}
+	case err == nil && value.value != nil:
+		// Booleans stored as numbers, e.g. TINYINT(1) DEFAULT 0
+		if n, ok := value.value.(int); ok && field.Type == "boolean" {
+			value.value = n != 0
+		}
+		field.Default = &schema.Default{Value: value.value}
 	default:
-		return "string"
+		p.warnf(tokens[0], "ignoring DEFAULT %s of column %s: not a value or a known function", p.text(tokens), field.Name)
+	}
+}
+
+// applyColumnType sets what the declared type of a column says beyond its
+// internal type: enum values, auto-increment, precision, scale and length
+func (p *sqlParser) applyColumnType(field *schema.Field, sqlType dialect.Type, typeTokens []sqlToken) {
+	if len(typeTokens) == 0 || sqlType.Array {
+		return
+	}
+
+	// Enum types: MySQL ENUM('a', 'b') columns and Postgres CREATE TYPE ... AS ENUM
+	typeName := typeTokens[0].text
+	for i := 1; i+1 < len(typeTokens) && typeTokens[i].isSymbol(".") && typeTokens[i+1].isName(); i += 2 {
+		typeName = typeTokens[i+1].text
+	}
+	if typeTokens[0].is("ENUM") && len(typeTokens) > 2 && typeTokens[1].isSymbol("(") {
+		if values, ok := tokenValues(typeTokens[2 : len(typeTokens)-1]); ok {
+			field.Type = "string"
+			field.Constraints.Enum = &schema.Enum{Values: values}
+		}
+	}
+	if values, exists := p.enumTypes[strings.ToLower(typeName)]; exists {
+		field.Type = "string"
+		field.Constraints.Enum = &schema.Enum{Values: values}
+	}
+
+	// Auto-increment column types
+	if serialTypes[sqlType.Name] {
+		field.AutoIncrement = true
+	}
+
+	// DECIMAL(p, s) and NUMERIC(p, s) precision and scale
+	if decimalTypes[sqlType.Name] && len(sqlType.Args) > 0 && len(sqlType.Args) <= 2 {
+		if precision, err := strconv.Atoi(sqlType.Args[0]); err == nil {
+			field.Precision = &precision
+			scale := 0
+			if len(sqlType.Args) == 2 {
+				scale, _ = strconv.Atoi(sqlType.Args[1])
+			}
+			field.Scale = &scale
+		}
 	}
-}
\ No newline at end of file
+
+	// VARCHAR(n) and CHAR(n) lengths; CHAR values are padded to n.
+	// NVARCHAR(MAX) has no length.
+	if fixed, ok := charTypes[sqlType.Name]; ok && len(sqlType.Args) == 1 {
+		if length, err := strconv.Atoi(sqlType.Args[0]); err == nil && length > 0 {
+			field.MaxLength = &length
+			field.FixedLength = fixed
+		}
+	}
+}
+
+// applyTypeRange bounds the values of numeric columns to what their type
+// holds: unsigned numbers are never negative, and TINYINT holds one byte.
+// CHECK constraints, applied once the table is read, replace these bounds.
+// Auto-increment columns keep counting from 1.
+func (p *sqlParser) applyTypeRange(field *schema.Field, sqlType dialect.Type) {
+	if field.AutoIncrement || sqlType.Array || (field.Type != "int" && field.Type != "float") {
+		return
+	}
+	if sqlType.Unsigned {
+		min := 0.0
+		field.Constraints.MinValue = &min
+	}
+	if sqlType.Name == "TINYINT" {
+		max := 127.0
+		if sqlType.Unsigned || p.dialect == dialect.SQLServer {
+			max = 255
+		}
+		field.Constraints.MaxValue = &max
+	}
+}
+
+// columnReference reads an inline REFERENCES table (column) and records the
+// foreign key on the field and in the relationships. Without a column, the
+// primary key of the table is referenced once the whole file is read.
+func (p *sqlParser) columnReference(field *schema.Field, tableName string) error {
+	tok := p.peek()
+	toTable, err := p.name("table")
+	if err != nil {
+		return err
+	}
+	if !p.peek().isSymbol("(") {
+		*p.pending = append(*p.pending, pendingReference{table: tableName, fields: []string{field.Name}, toTable: toTable, tok: tok})
+		return nil
+	}
+	columns, err := p.columnList()
+	if err != nil {
+		return err
+	}
+	field.Constraints.References = &schema.Reference{
+		Table: toTable,
+		Field: columns[0],
+	}
+	*p.relationships = append(*p.relationships, schema.Relationship{
+		Type:        "foreign_key",
+		FromTable:   tableName,
+		FromField:   field.Name,
+		ToTable:     toTable,
+		ToField:     columns[0],
+		Cardinality: "many:1",
+	})
+	return nil
+}
+
+// tokenValues reads a comma-separated list of SQL literals, e.g. 'a', 'it”s', 3.
+// Quoted values stay strings, numbers become int or float64.
+func tokenValues(tokens []sqlToken) ([]interface{}, bool) {
+	var values []interface{}
+	for _, item := range splitTokens(tokens) {
+		negative := len(item) == 2 && item[0].isSymbol("-")
+		if negative {
+			item = item[1:]
+		}
+		if len(item) != 1 {
+			return nil, false
+		}
+		switch tok := item[0]; {
+		case tok.kind == sqlString && !negative:
+			values = append(values, tok.text)
+		case tok.kind == sqlNumber && negative:
+			values = append(values, sqlLiteral("-"+tok.text))
+		case tok.kind == sqlNumber:
+			values = append(values, sqlLiteral(tok.text))
+		default:
+			return nil, false
+		}
+	}
+	return values, len(values) > 0
+}
+
+// sqlLiteral converts an unquoted SQL literal to an int or float64 when it is numeric
+func sqlLiteral(token string) interface{} {
+	if n, err := strconv.Atoi(token); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(token, 64); err == nil {
+		return f
+	}
+	return token
+}
+
+// resolveReferences points the foreign keys declared without a column list
+// at the primary key of the table they reference, now that ALTER TABLE
+// statements have added every key. Those that cannot be resolved are
+// reported as warnings and ignored.
+func (p *sqlParser) resolveReferences(s *schema.Schema) {
+	for _, ref := range *p.pending {
+		table := findTable(s, ref.table)
+		if table == nil {
+			continue
+		}
+		to := findTable(s, ref.toTable)
+		if to == nil {
+			p.warnf(ref.tok, "ignoring foreign key (%s) of %s: table %s is not created in this file",
+				strings.Join(ref.fields, ", "), ref.table, ref.toTable)
+			continue
+		}
+		key := primaryKey(to)
+		if len(key) != len(ref.fields) {
+			want := "a single-column primary key"
+			if len(ref.fields) > 1 {
+				want = "a primary key of " + strconv.Itoa(len(ref.fields)) + " columns"
+			}
+			p.warnf(ref.tok, "ignoring foreign key (%s) of %s: REFERENCES %s has no column list and %s does not have %s",
+				strings.Join(ref.fields, ", "), ref.table, ref.toTable, to.Name, want)
+			continue
+		}
+		table.ForeignKeys = append(table.ForeignKeys, schema.ForeignKey{Fields: ref.fields, ToTable: ref.toTable, ToFields: key})
+		applyTableKeys(table, p.relationships)
+	}
+}
+
+// primaryKey returns the primary key columns of a table
+func primaryKey(table *schema.Table) []string {
+	if len(table.PrimaryKey) > 0 {
+		return table.PrimaryKey
+	}
+	var key []string
+	for _, field := range table.Fields {
+		if field.PrimaryKey {
+			key = append(key, field.Name)
+		}
+	}
+	return key
+}
+
+// applyTableKeys moves single-column table-level keys onto their fields, so
+// that "PRIMARY KEY (id)" means the same as an inline "id INT PRIMARY KEY".
+// Composite keys stay on the table, and composite primary key columns are NOT NULL.
+func applyTableKeys(table *schema.Table, relationships *[]schema.Relationship) {
+	fieldIndex := func(name string) int {
+		for i, field := range table.Fields {
+			if field.Name == name {
+				return i
+			}
+		}
+		return -1
+	}
+
+	if len(table.PrimaryKey) == 1 {
+		if i := fieldIndex(table.PrimaryKey[0]); i >= 0 {
+			table.Fields[i].PrimaryKey = true
+			table.Fields[i].Required = true
+			table.PrimaryKey = nil
+		}
+	}
+	for _, name := range table.PrimaryKey {
+		if i := fieldIndex(name); i >= 0 {
+			table.Fields[i].Required = true
+		}
+	}
+
+	var uniqueKeys [][]string
+	for _, key := range table.UniqueKeys {
+		if i := fieldIndex(key[0]); len(key) == 1 && i >= 0 {
+			table.Fields[i].Unique = true
+			continue
+		}
+		uniqueKeys = append(uniqueKeys, key)
+	}
+	table.UniqueKeys = uniqueKeys
+
+	var foreignKeys []schema.ForeignKey
+	for _, fk := range table.ForeignKeys {
+		i := -1
+		if len(fk.Fields) == 1 && len(fk.ToFields) == 1 {
+			i = fieldIndex(fk.Fields[0])
+		}
+		if i < 0 {
+			foreignKeys = append(foreignKeys, fk)
+			continue
+		}
+
+		constraints := schema.Constraint{}
+		if table.Fields[i].Constraints != nil {
+			constraints = *table.Fields[i].Constraints
+		}
+		constraints.References = &schema.Reference{Table: fk.ToTable, Field: fk.ToFields[0]}
+		table.Fields[i].Constraints = &constraints
+
+		*relationships = append(*relationships, schema.Relationship{
+			Type:        "foreign_key",
+			FromTable:   table.Name,
+			FromField:   fk.Fields[0],
+			ToTable:     fk.ToTable,
+			ToField:     fk.ToFields[0],
+			Cardinality: "many:1",
+		})
+	}
+	table.ForeignKeys = foreignKeys
+}
+
+// mapSQLType maps an SQL column type to an internal type. Types the dialect
+// does not know are looked up in the other dialects, and are strings if none
+// knows them.
+func mapSQLType(sqlType dialect.Type, d dialect.Dialect) string {
+	if internal := d.Map(sqlType); internal != "" {
+		return internal
+	}
+	if internal := dialect.Generic.Map(sqlType); internal != "" {
+		return internal
+	}
+	if sqlType.Array {
+		return "string[]"
+	}
+	return "string"
+}
@@ -1,21 +1,51 @@
 package schema
 
+import "encoding/json"
+
 type Schema struct {
     Tables []Table `json:"tables,omitempty"`
     Fields []Field `json:"fields,omitempty"` // For backward compatibility with simple schemas
     Relationships []Relationship `json:"relationships,omitempty"` // New: Define relationships
+    NullRatio *float64 `json:"null_ratio,omitempty"` // Default share of nulls in columns that are not required
+    DefaultRatio *float64 `json:"default_ratio,omitempty"` // Default share of rows given the column default: 1 always (default), 0 never
 }
 
 type Table struct {
-    Name   string  `json:"name"`
-    Fields []Field `json:"fields"`
+    Name        string       `json:"name"`
+    Fields      []Field      `json:"fields"`
+    Rows        *int         `json:"rows,omitempty"`         // Row count for this table, overrides the global row count
+    PrimaryKey  []string     `json:"primary_key,omitempty"`  // Composite primary key columns
+    UniqueKeys  [][]string   `json:"unique_keys,omitempty"`  // Column sets whose combined values are unique
+    ForeignKeys []ForeignKey `json:"foreign_keys,omitempty"` // Multi-column foreign keys
+}
+
+// ForeignKey references a whole row of another table through several columns,
+// e.g. (order_id, line_no) -> order_lines(order_id, line_no)
+type ForeignKey struct {
+    Fields   []string `json:"fields"`
+    ToTable  string   `json:"to_table"`
+    ToFields []string `json:"to_fields"`
 }
 
 type Field struct {
-    Name        string      `json:"name"`
-    Type        string      `json:"type"`
-    Required    bool        `json:"required"`
-    Constraints *Constraint `json:"constraints,omitempty"` // New: Field-level constraints
+    Name          string      `json:"name"`
+    Type          string      `json:"type"`
+    Required      bool        `json:"required"`
+    PrimaryKey    bool        `json:"primary_key,omitempty"`    // Values are unique and never null
+    Unique        bool        `json:"unique,omitempty"`         // Values are unique
+    AutoIncrement bool        `json:"auto_increment,omitempty"` // Sequential ids (SERIAL, AUTO_INCREMENT, IDENTITY)
+    NullRatio     *float64    `json:"null_ratio,omitempty"`     // Share of null values, overrides the schema null_ratio
+    Precision     *int        `json:"precision,omitempty"`      // Total number of digits, e.g. 10 for DECIMAL(10,2)
+    Scale         *int        `json:"scale,omitempty"`          // Digits after the decimal point, e.g. 2 for DECIMAL(10,2)
+    MinLength     *int        `json:"min_length,omitempty"`     // Minimum length of string values
+    MaxLength     *int        `json:"max_length,omitempty"`     // Maximum length of string values, e.g. 100 for VARCHAR(100)
+    FixedLength   bool        `json:"fixed_length,omitempty"`   // Pad string values to max_length with spaces, as CHAR(n) does
+    Format        string      `json:"format,omitempty"`         // Date output: date, datetime, rfc3339, unix, unix_ms or a Go layout
+    Timezone      string      `json:"timezone,omitempty"`       // IANA time zone of dates, e.g. Europe/Paris (default UTC)
+    Expression    string      `json:"expression,omitempty"`     // Computed value, e.g. quantity * unit_price (type "computed" or any other type)
+    Default       *Default    `json:"default,omitempty"`        // Column default, e.g. 'active' or now()
+    DefaultRatio  *float64    `json:"default_ratio,omitempty"`  // Share of rows given the default, overrides the schema default_ratio
+    Constraints   *Constraint `json:"constraints,omitempty"`    // New: Field-level constraints
 }
 
 // New: Relationship constraints between tables/fields
@@ -26,16 +56,153 @@ type Relationship struct {
     ToTable      string `json:"to_table"`  
     ToField      string `json:"to_field"`
     Cardinality  string `json:"cardinality,omitempty"` // "1:1", "1:many", "many:many"
+    MinCount     *int   `json:"min_count,omitempty"`   // Minimum from_table rows per to_table row
+    MaxCount     *int   `json:"max_count,omitempty"`   // Maximum from_table rows per to_table row
+
+    // many_to_many relationships link from_field and to_field through a junction table
+    Through          string `json:"through,omitempty"`            // Junction table (default from_table_to_table)
+    ThroughFromField string `json:"through_from_field,omitempty"` // Junction column for from_field (default e.g. user_id)
+    ThroughToField   string `json:"through_to_field,omitempty"`   // Junction column for to_field (default e.g. role_id)
+    MinPerFrom       *int   `json:"min_per_from,omitempty"`       // Minimum links per from_table row (default 1)
+    MaxPerFrom       *int   `json:"max_per_from,omitempty"`       // Maximum links per from_table row (default 3)
+    MinPerTo         *int   `json:"min_per_to,omitempty"`         // Minimum links per to_table row (default 0)
+    MaxPerTo         *int   `json:"max_per_to,omitempty"`         // Maximum links per to_table row (default unlimited)
 }
 
 // New: Field-level constraints
 type Constraint struct {
     References   *Reference `json:"references,omitempty"`   // Foreign key reference
-    DependsOn    string     `json:"depends_on,omitempty"`   // Field dependency
+    DependsOn    *Dependency `json:"depends_on,omitempty"`  // Value derived from another column of the row
+    When         []Rule     `json:"when,omitempty"`         // Rules on other columns of the row; the first that matches applies
     Pattern      string     `json:"pattern,omitempty"`      // Regex pattern
-    MinValue     *int       `json:"min_value,omitempty"`    // Minimum value
-    MaxValue     *int       `json:"max_value,omitempty"`    // Maximum value
+    MinValue     *float64   `json:"min_value,omitempty"`    // Minimum value
+    MaxValue     *float64   `json:"max_value,omitempty"`    // Maximum value
     UniqueCount  *int       `json:"unique_count,omitempty"` // Number of unique values
+    Hierarchy    *Hierarchy `json:"hierarchy,omitempty"`    // Tree shape for self-referencing foreign keys
+    Enum         *Enum      `json:"enum,omitempty"`         // Allowed values
+    Distribution *Distribution `json:"distribution,omitempty"` // Shape of numeric values (default uniform)
+    MinDate      string     `json:"min_date,omitempty"`     // Earliest date: 2024-01-31, a timestamp, now, today or an offset such as -90d
+    MaxDate      string     `json:"max_date,omitempty"`     // Latest date, in the same forms as min_date
+}
+
+// Distribution shapes the values of a numeric field. Values are kept within
+// min_value and max_value when those are set.
+type Distribution struct {
+    Type    string   `json:"type"`              // normal, lognormal, exponential, poisson, zipf or histogram
+    Mean    float64  `json:"mean,omitempty"`    // normal, exponential, poisson; mean of the logarithm for lognormal
+    StdDev  float64  `json:"stddev,omitempty"`  // normal; standard deviation of the logarithm for lognormal
+    S       float64  `json:"s,omitempty"`       // zipf exponent (default 1.1), over [min_value, max_value]
+    Buckets []Bucket `json:"buckets,omitempty"` // histogram
+}
+
+// Bucket is a histogram range [Min, Max] picked with probability proportional to Weight
+type Bucket struct {
+    Min    float64 `json:"min"`
+    Max    float64 `json:"max"`
+    Weight float64 `json:"weight"`
+}
+
+// Enum restricts a field to a list of values, optionally weighted. In JSON it
+// is either a list of values or an object with "values" and "weights".
+type Enum struct {
+    Values  []interface{} `json:"values"`
+    Weights []float64     `json:"weights,omitempty"` // Relative weight of each value (default equal)
+}
+
+// UnmarshalJSON accepts both ["a", "b"] and {"values": ["a", "b"], "weights": [1, 3]}
+func (e *Enum) UnmarshalJSON(data []byte) error {
+    var values []interface{}
+    if err := json.Unmarshal(data, &values); err == nil {
+        e.Values = values
+        return nil
+    }
+    type plain Enum
+    return json.Unmarshal(data, (*plain)(e))
+}
+
+// Default is the value a column takes in rows that do not set it: a fixed
+// value, or a function generating one. In JSON it is either the value itself
+// or an object with "value" or "function".
+type Default struct {
+    Value    interface{} `json:"value,omitempty"`
+    Function string      `json:"function,omitempty"` // now (timestamps up to now), today (dates up to today) or uuid
+}
+
+// UnmarshalJSON accepts both "active" and {"function": "now"}
+func (d *Default) UnmarshalJSON(data []byte) error {
+    var keys map[string]json.RawMessage
+    if err := json.Unmarshal(data, &keys); err == nil && len(keys) > 0 {
+        plain := true
+        for key := range keys {
+            plain = plain && (key == "value" || key == "function")
+        }
+        if plain {
+            type object Default
+            return json.Unmarshal(data, (*object)(d))
+        }
+    }
+    return json.Unmarshal(data, &d.Value)
+}
+
+// Dependency derives a value from another column of the same row, e.g. an
+// end_date 1 to 180 days after start_date, or with Parent from a column of
+// the row a foreign key points to. In JSON it is either the column name alone
+// or an object.
+type Dependency struct {
+    Field     string `json:"field"`                // Column the value is derived from
+    Parent    string `json:"parent,omitempty"`     // Foreign key column of this row; field is then a column of the referenced row
+    Operator  string `json:"operator,omitempty"`   // after (default), on_or_after, before or on_or_before
+    MinOffset Offset `json:"min_offset,omitempty"` // Smallest distance from the column: 1d, 2h, 1mo for dates, a number otherwise
+    MaxOffset Offset `json:"max_offset,omitempty"` // Largest distance from the column
+}
+
+// UnmarshalJSON accepts both "start_date" and {"field": "start_date", ...}
+func (d *Dependency) UnmarshalJSON(data []byte) error {
+    var field string
+    if err := json.Unmarshal(data, &field); err == nil {
+        d.Field = field
+        return nil
+    }
+    type plain Dependency
+    return json.Unmarshal(data, (*plain)(d))
+}
+
+// Rule changes how a field is generated in the rows matching a condition on
+// other columns, e.g. a null shipped_at unless status is shipped. A rule with
+// no action keeps the field's own generator.
+type Rule struct {
+    If          string      `json:"if,omitempty"`          // Condition, e.g. status IN ('shipped', 'delivered'); empty matches every row
+    Null        bool        `json:"null,omitempty"`        // The value is null
+    Value       interface{} `json:"value,omitempty"`       // The value is fixed
+    Type        string      `json:"type,omitempty"`        // Generate the value as this type instead, e.g. price
+    Expression  string      `json:"expression,omitempty"`  // Compute the value from the row
+    Constraints *Constraint `json:"constraints,omitempty"` // Constraints replacing the field's own, key by key
+}
+
+// Offset is a distance between two values: a duration such as 90d for dates
+// and timestamps, or a number. JSON numbers are accepted as well as strings.
+type Offset string
+
+// UnmarshalJSON accepts both "1d" and 5
+func (o *Offset) UnmarshalJSON(data []byte) error {
+    var number json.Number
+    if err := json.Unmarshal(data, &number); err == nil {
+        *o = Offset(number)
+        return nil
+    }
+    var s string
+    if err := json.Unmarshal(data, &s); err != nil {
+        return err
+    }
+    *o = Offset(s)
+    return nil
+}
+
+// Hierarchy controls the tree generated for a self-referencing foreign key
+// such as employees.manager_id -> employees.id
+type Hierarchy struct {
+    RootFraction float64 `json:"root_fraction,omitempty"` // Share of rows without a parent (default 0.1)
+    MaxDepth     int     `json:"max_depth,omitempty"`     // Maximum tree depth, 0 = unlimited
 }
 
 // New: Reference constraint for foreign keys
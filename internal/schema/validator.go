@@ -1,12 +1,27 @@
 package schema
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"go-fake/internal/expr"
+	"go-fake/pkg/faker"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
 
 func ValidateSchema(schema Schema) error {
 	// Check if schema has either tables or fields
 	if len(schema.Tables) == 0 && len(schema.Fields) == 0 {
 		return errors.New("schema must have at least one table or field")
 	}
+	if err := validateRatio("schema", "null_ratio", schema.NullRatio); err != nil {
+		return err
+	}
+	if err := validateRatio("schema", "default_ratio", schema.DefaultRatio); err != nil {
+		return err
+	}
 
 	// Validate tables if present
 	if len(schema.Tables) > 0 {
@@ -20,6 +35,15 @@ func ValidateSchema(schema Schema) error {
 			if err := validateFields(table.Fields); err != nil {
 				return err
 			}
+			if err := validateTableKeys(table, schema.Tables); err != nil {
+				return err
+			}
+			if err := validateParentDependencies(table, schema); err != nil {
+				return err
+			}
+			if err := validateAggregates(table, schema); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -28,6 +52,12 @@ func ValidateSchema(schema Schema) error {
 		if err := validateFields(schema.Fields); err != nil {
 			return err
 		}
+		if err := validateParentDependencies(Table{Fields: schema.Fields}, schema); err != nil {
+			return err
+		}
+		if err := validateAggregates(Table{Fields: schema.Fields}, schema); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -47,6 +77,550 @@ func validateFields(fields []Field) error {
 		if field.Type == "" {
 			return errors.New("field type cannot be empty")
 		}
+		if err := validateField(field); err != nil {
+			return err
+		}
+		if err := validateRules(field); err != nil {
+			return err
+		}
+	}
+	return validateDependencies(fields)
+}
+
+// validateField checks the value settings and constraints of one field
+func validateField(field Field) error {
+	if err := validateRatio("field "+field.Name, "null_ratio", field.NullRatio); err != nil {
+		return err
+	}
+	if err := validateRatio("field "+field.Name, "default_ratio", field.DefaultRatio); err != nil {
+		return err
+	}
+	if err := validateDefault(field.Default); err != nil {
+		return fmt.Errorf("field %s: %v", field.Name, err)
+	}
+	if err := validateNumericFormat(field); err != nil {
+		return fmt.Errorf("field %s: %v", field.Name, err)
+	}
+	if err := validateLength(field); err != nil {
+		return fmt.Errorf("field %s: %v", field.Name, err)
+	}
+	if err := validateDates(field); err != nil {
+		return fmt.Errorf("field %s: %v", field.Name, err)
+	}
+	if field.Constraints != nil && field.Constraints.Enum != nil {
+		if err := validateEnum(*field.Constraints.Enum); err != nil {
+			return fmt.Errorf("field %s: %v", field.Name, err)
+		}
+	}
+	if field.Constraints != nil && field.Constraints.Distribution != nil {
+		if err := validateDistribution(*field.Constraints.Distribution, integerTypes[strings.ToLower(field.Type)]); err != nil {
+			return fmt.Errorf("field %s: %v", field.Name, err)
+		}
+	}
+	if field.Constraints != nil && field.Constraints.Pattern != "" {
+		if _, err := faker.CompilePattern(field.Constraints.Pattern); err != nil {
+			return fmt.Errorf("field %s: %v", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateRules checks that when rules have a valid condition and at most one
+// action, and that the constraints they set are valid for the field
+func validateRules(field Field) error {
+	if field.Constraints == nil {
+		return nil
+	}
+	for i, rule := range field.Constraints.When {
+		actions := 0
+		for _, set := range []bool{rule.Null, rule.Value != nil, rule.Expression != "", rule.Type != "" || rule.Constraints != nil} {
+			if set {
+				actions++
+			}
+		}
+		if actions > 1 {
+			return fmt.Errorf("field %s: when rule %d: set only one of null, value, expression, or type and constraints", field.Name, i+1)
+		}
+		for _, src := range []string{rule.If, rule.Expression} {
+			if src == "" {
+				continue
+			}
+			e, err := expr.Parse(src)
+			if err != nil {
+				return fmt.Errorf("field %s: when rule %d: %v", field.Name, i+1, err)
+			}
+			if len(e.Aggregates()) > 0 {
+				return fmt.Errorf("field %s: when rule %d: aggregates are only allowed in the expression of a field", field.Name, i+1)
+			}
+		}
+		if c := rule.Constraints; c != nil {
+			if c.When != nil || c.Hierarchy != nil || c.References != nil {
+				return fmt.Errorf("field %s: when rule %d: constraints cannot set when, hierarchy or references", field.Name, i+1)
+			}
+			override := field
+			override.Constraints = c
+			if rule.Type != "" {
+				override.Type = rule.Type
+			}
+			if err := validateField(override); err != nil {
+				return fmt.Errorf("when rule %d of %v", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// fieldDependencies returns the depends_on settings of a field and of its when rules
+func fieldDependencies(field Field) []*Dependency {
+	if field.Constraints == nil {
+		return nil
+	}
+	var deps []*Dependency
+	if field.Constraints.DependsOn != nil {
+		deps = append(deps, field.Constraints.DependsOn)
+	}
+	for _, rule := range field.Constraints.When {
+		if rule.Constraints != nil && rule.Constraints.DependsOn != nil {
+			deps = append(deps, rule.Constraints.DependsOn)
+		}
+	}
+	return deps
+}
+
+// fieldExpressions returns the expression of a field and the conditions and
+// expressions of its when rules
+func fieldExpressions(field Field) []string {
+	var sources []string
+	if field.Expression != "" {
+		sources = append(sources, field.Expression)
+	}
+	if field.Constraints != nil {
+		for _, rule := range field.Constraints.When {
+			for _, src := range []string{rule.If, rule.Expression} {
+				if src != "" {
+					sources = append(sources, src)
+				}
+			}
+		}
+	}
+	return sources
+}
+
+// validateDependencies checks the depends_on settings and the expressions of
+// fields and their when rules, and that the columns they read form no cycle
+func validateDependencies(fields []Field) error {
+	dependsOn := make(map[string][]string)
+	for _, field := range fields {
+		if strings.EqualFold(field.Type, "computed") && field.Expression == "" {
+			return fmt.Errorf("field %s: computed fields need an expression", field.Name)
+		}
+		for _, src := range fieldExpressions(field) {
+			e, err := expr.Parse(src)
+			if err != nil {
+				return fmt.Errorf("field %s: %v", field.Name, err)
+			}
+			for _, column := range e.Columns() {
+				if column == field.Name {
+					return fmt.Errorf("field %s: expression %q cannot read the field itself", field.Name, src)
+				}
+				if !hasField(fields, column) {
+					return fmt.Errorf("field %s: expression %q reads unknown column %q", field.Name, src, column)
+				}
+			}
+			dependsOn[field.Name] = append(dependsOn[field.Name], e.Columns()...)
+		}
+		for _, dep := range fieldDependencies(field) {
+			local, err := validateDependency(fields, field, dep)
+			if err != nil {
+				return err
+			}
+			dependsOn[field.Name] = append(dependsOn[field.Name], local)
+		}
+	}
+
+	state := make(map[string]int) // 1 on the path, 2 done
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case 1:
+			for i, column := range path {
+				if column == name {
+					return fmt.Errorf("dependency cycle: %s", strings.Join(append(path[i:], name), " -> "))
+				}
+			}
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		path = append(path, name)
+		for _, next := range dependsOn[name] {
+			if err := visit(next); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = 2
+		return nil
+	}
+	for _, field := range fields {
+		if err := visit(field.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateAggregates checks that the aggregates of computed fields read an
+// existing column of a child table with a foreign key to this table
+func validateAggregates(table Table, schema Schema) error {
+	for _, field := range table.Fields {
+		if field.Expression == "" {
+			continue
+		}
+		e, err := expr.Parse(field.Expression)
+		if err != nil {
+			continue // reported by validateFields
+		}
+		for _, a := range e.Aggregates() {
+			if table.Name == "" {
+				return fmt.Errorf("field %s: aggregates of %s need a schema with tables", field.Name, a.Table)
+			}
+			var child *Table
+			for i := range schema.Tables {
+				if schema.Tables[i].Name == a.Table {
+					child = &schema.Tables[i]
+				}
+			}
+			if child == nil {
+				return fmt.Errorf("field %s: expression aggregates unknown table %s", field.Name, a.Table)
+			}
+			if !hasField(child.Fields, a.Column) {
+				return fmt.Errorf("field %s: table %s has no column %s", field.Name, a.Table, a.Column)
+			}
+			if !referencesTable(*child, table.Name, schema.Relationships) {
+				return fmt.Errorf("field %s: table %s has no foreign key to %s", field.Name, a.Table, table.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// referencesTable reports whether a column of the child table is a foreign
+// key to the parent table, declared on the column or in the relationships
+func referencesTable(child Table, parent string, relationships []Relationship) bool {
+	for _, field := range child.Fields {
+		if field.Constraints != nil && field.Constraints.References != nil && field.Constraints.References.Table == parent {
+			return true
+		}
+	}
+	for _, rel := range relationships {
+		if rel.FromTable == child.Name && rel.ToTable == parent {
+			return true
+		}
+	}
+	return false
+}
+
+// validateDependency checks that a depends_on setting of a field names
+// another column of the same fields, with a known operator and valid offsets,
+// and returns the column of the row it is ordered after
+func validateDependency(fields []Field, field Field, dep *Dependency) (string, error) {
+	// A parent dependency is ordered after its foreign key column
+	local := dep.Field
+	if dep.Parent != "" {
+		local = dep.Parent
+		if dep.Field == "" {
+			return "", fmt.Errorf("field %s: depends_on with a parent must name a field of the parent row", field.Name)
+		}
+	}
+	if local == field.Name {
+		return "", fmt.Errorf("field %s: depends_on cannot name the field itself", field.Name)
+	}
+	if !hasField(fields, local) {
+		return "", fmt.Errorf("field %s: depends_on names unknown column %q", field.Name, local)
+	}
+	switch strings.ToLower(dep.Operator) {
+	case "", "after", ">", "on_or_after", ">=", "before", "<", "on_or_before", "<=":
+	default:
+		return "", fmt.Errorf("field %s: unknown depends_on operator %q; use after, on_or_after, before or on_or_before", field.Name, dep.Operator)
+	}
+	for _, offset := range []Offset{dep.MinOffset, dep.MaxOffset} {
+		if err := validateOffset(offset); err != nil {
+			return "", fmt.Errorf("field %s: %v", field.Name, err)
+		}
+	}
+	return local, nil
+}
+
+// validateParentDependencies checks that a depends_on parent is a foreign key,
+// declared on the column or in the relationships, whose table has the field
+func validateParentDependencies(table Table, schema Schema) error {
+	for _, field := range table.Fields {
+		for _, dep := range fieldDependencies(field) {
+			if dep.Parent == "" {
+				continue
+			}
+			if err := validateParentDependency(table, schema, field, dep); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateParentDependency checks one depends_on setting with a parent
+func validateParentDependency(table Table, schema Schema, field Field, dep *Dependency) error {
+	var ref *Reference
+	for _, column := range table.Fields {
+		if column.Name == dep.Parent && column.Constraints != nil && column.Constraints.References != nil {
+			ref = column.Constraints.References
+		}
+	}
+	for _, rel := range schema.Relationships {
+		if ref == nil && rel.FromTable == table.Name && rel.FromField == dep.Parent {
+			ref = &Reference{Table: rel.ToTable, Field: rel.ToField}
+		}
+	}
+	if ref == nil {
+		return fmt.Errorf("field %s: depends_on parent %s is not a foreign key", field.Name, dep.Parent)
+	}
+	for _, parent := range schema.Tables {
+		if parent.Name == ref.Table && !hasField(parent.Fields, dep.Field) {
+			return fmt.Errorf("field %s: depends_on parent table %s has no column %s", field.Name, ref.Table, dep.Field)
+		}
+	}
+	return nil
+}
+
+// validateOffset accepts an empty offset, a number or a duration such as 90d
+func validateOffset(offset Offset) error {
+	if offset == "" {
+		return nil
+	}
+	if _, err := strconv.ParseFloat(string(offset), 64); err == nil {
+		return nil
+	}
+	if _, err := faker.ShiftTime(time.Time{}, string(offset), false); err != nil {
+		return err
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// hasField reports whether a column with the name exists
+func hasField(fields []Field, name string) bool {
+	for _, field := range fields {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTableKeys checks that composite keys name existing columns and that
+// composite foreign keys match the columns of the table they reference
+func validateTableKeys(table Table, tables []Table) error {
+	keys := [][]string{table.PrimaryKey}
+	keys = append(keys, table.UniqueKeys...)
+	for _, key := range keys {
+		if err := checkColumns(table, key); err != nil {
+			return err
+		}
+	}
+
+	for _, fk := range table.ForeignKeys {
+		if err := checkColumns(table, fk.Fields); err != nil {
+			return err
+		}
+		if len(fk.Fields) == 0 || len(fk.Fields) != len(fk.ToFields) {
+			return fmt.Errorf("table %s: foreign key (%s) must list as many columns as it references in %s (%s)",
+				table.Name, strings.Join(fk.Fields, ", "), fk.ToTable, strings.Join(fk.ToFields, ", "))
+		}
+		for _, parent := range tables {
+			if parent.Name == fk.ToTable {
+				if err := checkColumns(parent, fk.ToFields); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkColumns reports the first name that is not a column of the table
+func checkColumns(table Table, names []string) error {
+	for _, name := range names {
+		if !hasField(table.Fields, name) {
+			return fmt.Errorf("table %s has no column %s", table.Name, name)
+		}
+	}
+	return nil
+}
+
+// validateRatio checks that a null_ratio or default_ratio is a share between 0 and 1
+func validateRatio(owner, key string, ratio *float64) error {
+	if ratio != nil && (*ratio < 0 || *ratio > 1) {
+		return fmt.Errorf("%s: %s must be between 0 and 1, got %g", owner, key, *ratio)
+	}
+	return nil
+}
+
+// defaultFunctions are the functions a column default may generate values with
+var defaultFunctions = map[string]bool{"now": true, "today": true, "uuid": true}
+
+// validateDefault checks that a default has either a value or a known function
+func validateDefault(d *Default) error {
+	switch {
+	case d == nil:
+		return nil
+	case d.Function != "" && d.Value != nil:
+		return errors.New("default cannot have both a value and a function")
+	case d.Function != "" && !defaultFunctions[d.Function]:
+		return fmt.Errorf("unknown default function %q; use now, today or uuid", d.Function)
+	case d.Function == "" && d.Value == nil:
+		return errors.New("default needs a value or a function")
+	}
+	return nil
+}
+
+// validateNumericFormat checks precision, scale and the min/max value range
+func validateNumericFormat(field Field) error {
+	if field.Precision != nil && *field.Precision < 1 {
+		return fmt.Errorf("precision must be at least 1, got %d", *field.Precision)
+	}
+	if field.Scale != nil {
+		if *field.Scale < 0 {
+			return fmt.Errorf("scale cannot be negative, got %d", *field.Scale)
+		}
+		if field.Precision != nil && *field.Scale > *field.Precision {
+			return fmt.Errorf("scale %d cannot exceed precision %d", *field.Scale, *field.Precision)
+		}
+	}
+	if c := field.Constraints; c != nil && c.MinValue != nil && c.MaxValue != nil {
+		if *c.MinValue > *c.MaxValue {
+			return fmt.Errorf("min_value %g is greater than max_value %g", *c.MinValue, *c.MaxValue)
+		}
+		if integerTypes[strings.ToLower(field.Type)] && math.Ceil(*c.MinValue) > math.Floor(*c.MaxValue) {
+			return fmt.Errorf("no whole number between min_value %g and max_value %g", *c.MinValue, *c.MaxValue)
+		}
+	}
+	return nil
+}
+
+// integerTypes lists the field types whose values are whole numbers
+var integerTypes = map[string]bool{"int": true, "integer": true, "bigint": true, "smallint": true, "serial": true, "age": true}
+
+// validateLength checks the string length limits of a field
+func validateLength(field Field) error {
+	if field.MinLength != nil && *field.MinLength < 0 {
+		return fmt.Errorf("min_length cannot be negative, got %d", *field.MinLength)
+	}
+	if field.MaxLength != nil && *field.MaxLength < 1 {
+		return fmt.Errorf("max_length must be at least 1, got %d", *field.MaxLength)
+	}
+	if field.MinLength != nil && field.MaxLength != nil && *field.MinLength > *field.MaxLength {
+		return fmt.Errorf("min_length %d is greater than max_length %d", *field.MinLength, *field.MaxLength)
+	}
+	if field.FixedLength && field.MaxLength == nil {
+		return errors.New("fixed_length requires max_length")
+	}
+	return nil
+}
+
+// validateDates checks the date bounds, time zone and format of a field
+func validateDates(field Field) error {
+	loc := time.UTC
+	if field.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(field.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", field.Timezone)
+		}
+	}
+	if err := faker.CheckTimeLayout(field.Format); err != nil {
+		return err
+	}
+
+	c := field.Constraints
+	if c == nil {
+		return nil
+	}
+	var min, max time.Time
+	var err error
+	if c.MinDate != "" {
+		if min, err = faker.ParseTimeBound(c.MinDate, faker.ReferenceTime(), loc); err != nil {
+			return fmt.Errorf("min_date: %v", err)
+		}
+	}
+	if c.MaxDate != "" {
+		if max, err = faker.ParseTimeBound(c.MaxDate, faker.ReferenceTime(), loc); err != nil {
+			return fmt.Errorf("max_date: %v", err)
+		}
+	}
+	if c.MinDate != "" && c.MaxDate != "" && min.After(max) {
+		return fmt.Errorf("min_date %s is after max_date %s", c.MinDate, c.MaxDate)
+	}
+	return nil
+}
+
+// validateEnum checks that an enum has values and one non-negative weight per value
+func validateEnum(enum Enum) error {
+	if len(enum.Values) == 0 {
+		return errors.New("enum must list at least one value")
+	}
+	if len(enum.Weights) == 0 {
+		return nil
+	}
+	if len(enum.Weights) != len(enum.Values) {
+		return fmt.Errorf("enum has %d values but %d weights", len(enum.Values), len(enum.Weights))
+	}
+	total := 0.0
+	for _, weight := range enum.Weights {
+		if weight < 0 {
+			return fmt.Errorf("enum weights cannot be negative, got %g", weight)
+		}
+		total += weight
+	}
+	if total == 0 {
+		return errors.New("enum weights cannot all be zero")
+	}
+	return nil
+}
+
+// validateDistribution checks the parameters of a numeric distribution.
+// Histogram buckets of integer fields must contain a whole number.
+func validateDistribution(d Distribution, integer bool) error {
+	switch strings.ToLower(d.Type) {
+	case "normal", "lognormal", "log-normal", "log_normal":
+		if d.StdDev <= 0 {
+			return fmt.Errorf("%s distribution needs a positive stddev", d.Type)
+		}
+	case "exponential", "poisson":
+		if d.Mean <= 0 {
+			return fmt.Errorf("%s distribution needs a positive mean", d.Type)
+		}
+	case "zipf":
+		if d.S < 0 {
+			return fmt.Errorf("zipf exponent s cannot be negative, got %g", d.S)
+		}
+	case "histogram":
+		if len(d.Buckets) == 0 {
+			return errors.New("histogram distribution needs at least one bucket")
+		}
+		total := 0.0
+		for _, bucket := range d.Buckets {
+			if bucket.Max < bucket.Min || bucket.Weight < 0 {
+				return fmt.Errorf("invalid histogram bucket %+v", bucket)
+			}
+			if integer && math.Ceil(bucket.Min) > math.Floor(bucket.Max) {
+				return fmt.Errorf("histogram bucket %+v contains no whole number", bucket)
+			}
+			total += bucket.Weight
+		}
+		if total == 0 {
+			return errors.New("histogram bucket weights cannot all be zero")
+		}
+	default:
+		return fmt.Errorf("unknown distribution type %q", d.Type)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package faker
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // time zones work on systems without a zoneinfo database
+)
+
+// Default layouts of generated dates and timestamps
+const (
+	DateLayout     = "2006-01-02"
+	DateTimeLayout = "2006-01-02 15:04:05"
+)
+
+// Default lower bounds of generated dates and timestamps; the upper bound is the reference time
+var (
+	DefaultDateStart     = time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+	DefaultDateTimeStart = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// absoluteLayouts are the layouts accepted for absolute date bounds
+var absoluteLayouts = []string{time.RFC3339Nano, DateTimeLayout, "2006-01-02T15:04:05", DateLayout}
+
+// relativeDateRe matches offsets such as -90d, +1y or now-6mo
+var relativeDateRe = regexp.MustCompile(`^(now|today)?\s*([+-])\s*(\d+)\s*(s|m|h|d|w|mo|y)$`)
+
+// offsetRe matches unsigned durations such as 90d or 2h; 0 needs no unit
+var offsetRe = regexp.MustCompile(`^(?:(\d+)\s*(s|m|h|d|w|mo|y)|0)$`)
+
+// ParseTimeBound parses a date bound: an absolute date or timestamp
+// (2024-01-31, 2024-01-31 12:00:00, RFC 3339), "now", "today", or an offset
+// from now such as -90d, +1y or today-2w. Units are s, m (minutes), h, d, w,
+// mo (months) and y. Offsets are relative to ref; dates without a zone are
+// read in loc.
+func ParseTimeBound(expr string, ref time.Time, loc *time.Location) (time.Time, error) {
+	now := ref.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	s := strings.TrimSpace(expr)
+	switch lower := strings.ToLower(s); lower {
+	case "now":
+		return now, nil
+	case "today":
+		return today, nil
+	default:
+		if m := relativeDateRe.FindStringSubmatch(lower); m != nil {
+			base := now
+			if m[1] == "today" {
+				base = today
+			}
+			n, err := strconv.Atoi(m[3])
+			if err != nil {
+				break
+			}
+			if m[2] == "-" {
+				n = -n
+			}
+			return addOffset(base, n, m[4]), nil
+		}
+	}
+
+	for _, layout := range absoluteLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q: use a date such as 2024-01-31, a timestamp, now, today or an offset such as -90d or +1y", expr)
+}
+
+// addOffset moves t by n units
+func addOffset(t time.Time, n int, unit string) time.Time {
+	switch unit {
+	case "s":
+		return t.Add(time.Duration(n) * time.Second)
+	case "m":
+		return t.Add(time.Duration(n) * time.Minute)
+	case "h":
+		return t.Add(time.Duration(n) * time.Hour)
+	case "d":
+		return t.AddDate(0, 0, n)
+	case "w":
+		return t.AddDate(0, 0, 7*n)
+	case "mo":
+		return t.AddDate(0, n, 0)
+	}
+	return t.AddDate(n, 0, 0)
+}
+
+// ShiftTime moves t forward, or backward when back is set, by a duration
+// such as 90d, 2h or 1y (units as in ParseTimeBound)
+func ShiftTime(t time.Time, offset string, back bool) (time.Time, error) {
+	m := offsetRe.FindStringSubmatch(strings.ToLower(strings.TrimSpace(offset)))
+	if m == nil {
+		return time.Time{}, fmt.Errorf("invalid offset %q: use a number and a unit such as 1d, 2h or 6mo", offset)
+	}
+	if m[1] == "" {
+		return t, nil
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid offset %q: %v", offset, err)
+	}
+	if back {
+		n = -n
+	}
+	return addOffset(t, n, m[2]), nil
+}
+
+// ParseTime reads back a value written by FormatTime with the same layout
+func ParseTime(value interface{}, layout string, loc *time.Location) (time.Time, bool) {
+	switch v := value.(type) {
+	case int64:
+		if strings.EqualFold(layout, "unix_ms") {
+			return time.UnixMilli(v).In(loc), true
+		}
+		return time.Unix(v, 0).In(loc), true
+	case string:
+		goLayout := layout
+		switch strings.ToLower(layout) {
+		case "date":
+			goLayout = DateLayout
+		case "", "datetime":
+			goLayout = DateTimeLayout
+		case "rfc3339":
+			goLayout = time.RFC3339
+		}
+		t, err := time.ParseInLocation(goLayout, v, loc)
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
+
+// CheckTimeLayout reports layouts that would be written literally, such as
+// YYYY-MM-DD instead of the Go reference layout 2006-01-02
+func CheckTimeLayout(layout string) error {
+	switch strings.ToLower(layout) {
+	case "", "date", "datetime", "rfc3339", "unix", "unix_ms":
+		return nil
+	}
+	if time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC).Format(layout) == layout {
+		return fmt.Errorf("date format %q has no date or time elements; use a Go layout such as 2006-01-02 15:04", layout)
+	}
+	return nil
+}
+
+// FormatTime renders a time with a layout: date, datetime, rfc3339, unix
+// (epoch seconds), unix_ms (epoch milliseconds) or a Go layout. Epoch
+// layouts return an int64, the others a string.
+func FormatTime(t time.Time, layout string) interface{} {
+	switch strings.ToLower(layout) {
+	case "date":
+		return t.Format(DateLayout)
+	case "", "datetime":
+		return t.Format(DateTimeLayout)
+	case "rfc3339":
+		return t.Format(time.RFC3339)
+	case "unix":
+		return t.Unix()
+	case "unix_ms":
+		return t.UnixMilli()
+	}
+	return t.Format(layout)
+}
+
+// GenerateTimeBetween returns a random instant in [start, end), to the second
+func GenerateTimeBetween(r *rand.Rand, start, end time.Time) time.Time {
+	delta := end.Unix() - start.Unix()
+	if delta <= 0 {
+		return start
+	}
+	return time.Unix(start.Unix()+r.Int64N(delta), 0).In(start.Location())
+}
+
+// GenerateDateBetween returns a random calendar day from start to end
+// inclusive, at midnight in the location of start
+func GenerateDateBetween(r *rand.Rand, start, end time.Time) time.Time {
+	loc := start.Location()
+	end = end.In(loc)
+	first := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	last := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(last.Sub(first).Hours() / 24)
+	if days > 0 {
+		first = first.AddDate(0, 0, r.IntN(days+1))
+	}
+	return time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,199 @@
+package faker
+
+import (
+	"math/rand/v2"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPatternGenerate(t *testing.T) {
+	tests := []struct {
+		pattern string
+		check   func(string) bool
+	}{
+		{`^[A-Z]{3}-\d{4}$`, nil},
+		{`[a-f0-9]{8}`, nil},
+		{`(foo|bar)_[xyz]?`, nil},
+		{`\w+@example\.com`, nil},
+		{`[^,]{5}`, func(s string) bool { return len(s) == 5 && !strings.Contains(s, ",") }},
+		{`(?i)abc`, func(s string) bool { return strings.EqualFold(s, "abc") }},
+		{`a{2,}`, func(s string) bool { return len(s) >= 2 && len(s) <= 2+PatternRepeatLimit }},
+		{`x*`, func(s string) bool { return len(s) <= PatternRepeatLimit }},
+		{`.{10}`, func(s string) bool {
+			for _, c := range s {
+				if c < ' ' || c > '~' {
+					return false
+				}
+			}
+			return len(s) == 10
+		}},
+	}
+
+	r := rand.New(rand.NewPCG(1, 2))
+	for _, tt := range tests {
+		p, err := CompilePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("CompilePattern(%q) error = %v", tt.pattern, err)
+		}
+		if p.String() != tt.pattern {
+			t.Errorf("String() = %q, want %q", p.String(), tt.pattern)
+		}
+		re := regexp.MustCompile(`^(?:` + tt.pattern + `)$`)
+		for i := 0; i < 50; i++ {
+			value := p.Generate(r)
+			if !re.MatchString(value) {
+				t.Errorf("pattern %q generated %q, which does not match", tt.pattern, value)
+			}
+			if tt.check != nil && !tt.check(value) {
+				t.Errorf("pattern %q generated unexpected value %q", tt.pattern, value)
+			}
+		}
+	}
+}
+
+func TestPatternIsReproducible(t *testing.T) {
+	p, err := CompilePattern(`[A-Z]{2}\d{6}`)
+	if err != nil {
+		t.Fatalf("CompilePattern() error = %v", err)
+	}
+	first := p.Generate(rand.New(rand.NewPCG(7, 7)))
+	second := p.Generate(rand.New(rand.NewPCG(7, 7)))
+	if first != second {
+		t.Errorf("same seed generated %q and %q", first, second)
+	}
+}
+
+func TestCompilePatternRejectsUnsupportedSyntax(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{`[a-`, "invalid pattern"},
+		{`\bword\b`, "word boundaries"},
+		{`a^b`, "^ or \\A must be at the start"},
+		{`a$b`, "$ or \\z must be at the end"},
+		{`(^a)+`, "^ or \\A must be at the start"},
+		{`[^\x00-\x{10FFFF}]`, "empty character class"},
+	}
+	for _, tt := range tests {
+		_, err := CompilePattern(tt.pattern)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("CompilePattern(%q) error = %v, want %q", tt.pattern, err, tt.want)
+		}
+	}
+
+	// Anchors are accepted at the ends, also inside groups and alternations
+	for _, pattern := range []string{`^abc$`, `(^a|^b)c`, `\Afoo\z`, `^(ab)?$`} {
+		if _, err := CompilePattern(pattern); err != nil {
+			t.Errorf("CompilePattern(%q) unexpected error: %v", pattern, err)
+		}
+	}
+}
+
+func TestParseTimeBound(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+	ref := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+	today := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		loc  *time.Location
+		want time.Time
+	}{
+		{"now", time.UTC, ref},
+		{"today", time.UTC, today},
+		{"TODAY", time.UTC, today},
+		{"-90d", time.UTC, ref.AddDate(0, 0, -90)},
+		{"+2w", time.UTC, ref.AddDate(0, 0, 14)},
+		{"now+1y", time.UTC, ref.AddDate(1, 0, 0)},
+		{"now - 6mo", time.UTC, ref.AddDate(0, -6, 0)},
+		{"today-2d", time.UTC, today.AddDate(0, 0, -2)},
+		{"-30m", time.UTC, ref.Add(-30 * time.Minute)},
+		{"+5s", time.UTC, ref.Add(5 * time.Second)},
+		{"2024-01-31", time.UTC, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{"2024-01-31 12:00:00", time.UTC, time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)},
+		{"2024-01-31T12:00:00Z", paris, time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)},
+		{"2024-01-31", paris, time.Date(2024, 1, 31, 0, 0, 0, 0, paris)},
+		{"today", paris, time.Date(2024, 3, 15, 0, 0, 0, 0, paris)},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimeBound(tt.expr, ref, tt.loc)
+		if err != nil {
+			t.Errorf("ParseTimeBound(%q) error = %v", tt.expr, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseTimeBound(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"", "yesterday", "-90", "90x", "now+", "31/01/2024"} {
+		if _, err := ParseTimeBound(expr, ref, time.UTC); err == nil {
+			t.Errorf("ParseTimeBound(%q) expected error", expr)
+		}
+	}
+}
+
+func TestShiftTime(t *testing.T) {
+	start := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		offset string
+		back   bool
+		want   time.Time
+	}{
+		{"1d", false, start.AddDate(0, 0, 1)},
+		{"2h", true, start.Add(-2 * time.Hour)},
+		{"1mo", false, start.AddDate(0, 1, 0)},
+		{"0", false, start},
+	}
+	for _, tt := range tests {
+		got, err := ShiftTime(start, tt.offset, tt.back)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ShiftTime(%q, %v) = %v, %v, want %v", tt.offset, tt.back, got, err, tt.want)
+		}
+	}
+	if _, err := ShiftTime(start, "-1d", false); err == nil {
+		t.Error("ShiftTime() expected error for a signed offset")
+	}
+}
+
+func TestFormatAndParseTime(t *testing.T) {
+	value := time.Date(2024, 1, 31, 12, 34, 56, 0, time.UTC)
+	for _, layout := range []string{"", "date", "datetime", "rfc3339", "unix", "unix_ms", "02/01/2006 15:04"} {
+		got, ok := ParseTime(FormatTime(value, layout), layout, time.UTC)
+		want := value
+		if layout == "date" {
+			want = time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+		} else if layout == "02/01/2006 15:04" {
+			want = value.Truncate(time.Minute)
+		}
+		if !ok || !got.Equal(want) {
+			t.Errorf("layout %q: ParseTime(FormatTime()) = %v, %v, want %v", layout, got, ok, want)
+		}
+	}
+
+	if err := CheckTimeLayout("YYYY-MM-DD"); err == nil {
+		t.Error("CheckTimeLayout() expected error for a layout without Go elements")
+	}
+}
+
+func TestGenerateDateBetween(t *testing.T) {
+	r := rand.New(rand.NewPCG(3, 4))
+	start := time.Date(2024, 1, 1, 15, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 50; i++ {
+		day := GenerateDateBetween(r, start, end)
+		if day.Hour() != 0 || day.Before(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || day.After(time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("GenerateDateBetween() = %v, outside 2024-01-01..2024-01-03", day)
+		}
+		ts := GenerateTimeBetween(r, start, end)
+		if ts.Before(start) || !ts.Before(end) {
+			t.Errorf("GenerateTimeBetween() = %v, outside [%v, %v)", ts, start, end)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package faker
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// PatternRepeatLimit is the number of extra repetitions generated for an
+// unbounded quantifier: a* yields 0-8 a's and a{2,} yields 2-10.
+const PatternRepeatLimit = 8
+
+// printable is the character range used for ".", negated classes and other
+// classes that reach beyond printable ASCII
+var printable = []rune{' ', '~'}
+
+// Pattern generates strings that match a regular expression
+type Pattern struct {
+	expr string
+	re   *syntax.Regexp
+}
+
+// CompilePattern parses a regular expression (Go RE2 syntax) for generation.
+// Patterns that cannot be generated are rejected: word boundaries, and
+// anchors that are not at the start or end of the pattern.
+func CompilePattern(expr string) (*Pattern, error) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %v", expr, err)
+	}
+	if err := checkGeneratable(re, true, true); err != nil {
+		return nil, fmt.Errorf("pattern %q cannot be generated: %v", expr, err)
+	}
+	return &Pattern{expr: expr, re: re}, nil
+}
+
+// String returns the source of the pattern
+func (p *Pattern) String() string {
+	return p.expr
+}
+
+// Generate returns a random string matching the pattern
+func (p *Pattern) Generate(r *rand.Rand) string {
+	var b strings.Builder
+	generatePattern(r, p.re, &b)
+	return b.String()
+}
+
+// checkGeneratable walks the expression and reports constructs that no
+// generated string can satisfy. atStart and atEnd tell whether the node can
+// be at the very start or end of the generated string.
+func checkGeneratable(re *syntax.Regexp, atStart, atEnd bool) error {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return fmt.Errorf("it matches nothing")
+	case syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return fmt.Errorf(`word boundaries (\b, \B) are not supported`)
+	case syntax.OpBeginLine, syntax.OpBeginText:
+		if !atStart {
+			return fmt.Errorf("^ or \\A must be at the start of the pattern")
+		}
+	case syntax.OpEndLine, syntax.OpEndText:
+		if !atEnd {
+			return fmt.Errorf("$ or \\z must be at the end of the pattern")
+		}
+	case syntax.OpConcat:
+		for i, sub := range re.Sub {
+			start := atStart && allEmptyWidth(re.Sub[:i])
+			end := atEnd && allEmptyWidth(re.Sub[i+1:])
+			if err := checkGeneratable(sub, start, end); err != nil {
+				return err
+			}
+		}
+	case syntax.OpAlternate, syntax.OpCapture:
+		for _, sub := range re.Sub {
+			if err := checkGeneratable(sub, atStart, atEnd); err != nil {
+				return err
+			}
+		}
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		// A repeated anchor is only valid if the repetition can happen once
+		once := re.Op == syntax.OpQuest || (re.Op == syntax.OpRepeat && re.Max == 1)
+		if err := checkGeneratable(re.Sub[0], atStart && once, atEnd && once); err != nil {
+			return err
+		}
+	case syntax.OpCharClass:
+		if len(re.Rune) == 0 {
+			return fmt.Errorf("it contains an empty character class")
+		}
+	}
+	return nil
+}
+
+// allEmptyWidth reports whether every expression only matches anchors
+func allEmptyWidth(subs []*syntax.Regexp) bool {
+	for _, sub := range subs {
+		switch sub.Op {
+		case syntax.OpBeginLine, syntax.OpBeginText, syntax.OpEndLine, syntax.OpEndText, syntax.OpEmptyMatch:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// generatePattern appends a random match of re to b
+func generatePattern(r *rand.Rand, re *syntax.Regexp, b *strings.Builder) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, c := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 && r.IntN(2) == 0 {
+				c = unicode.SimpleFold(c)
+			}
+			b.WriteRune(c)
+		}
+	case syntax.OpCharClass:
+		b.WriteRune(pickRune(r, re.Rune))
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		b.WriteRune(pickRune(r, printable))
+	case syntax.OpCapture:
+		generatePattern(r, re.Sub[0], b)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			generatePattern(r, sub, b)
+		}
+	case syntax.OpAlternate:
+		generatePattern(r, re.Sub[r.IntN(len(re.Sub))], b)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		min, max := repeatRange(re)
+		for n := min + r.IntN(max-min+1); n > 0; n-- {
+			generatePattern(r, re.Sub[0], b)
+		}
+	}
+	// Anchors and empty matches generate nothing
+}
+
+// repeatRange returns the number of repetitions to choose from for a quantifier
+func repeatRange(re *syntax.Regexp) (int, int) {
+	switch re.Op {
+	case syntax.OpStar:
+		return 0, PatternRepeatLimit
+	case syntax.OpPlus:
+		return 1, 1 + PatternRepeatLimit
+	case syntax.OpQuest:
+		return 0, 1
+	}
+	if re.Max < 0 {
+		return re.Min, re.Min + PatternRepeatLimit
+	}
+	return re.Min, re.Max
+}
+
+// pickRune picks a character from a class given as pairs of inclusive ranges.
+// Classes that reach beyond printable ASCII are narrowed to it when possible,
+// so negated classes such as [^,] produce readable text.
+func pickRune(r *rand.Rand, ranges []rune) rune {
+	if narrowed := intersectRanges(ranges, printable); len(narrowed) > 0 {
+		ranges = narrowed
+	}
+
+	total := 0
+	for i := 0; i+1 < len(ranges); i += 2 {
+		total += int(ranges[i+1]-ranges[i]) + 1
+	}
+	n := r.IntN(total)
+	for i := 0; i+1 < len(ranges); i += 2 {
+		size := int(ranges[i+1]-ranges[i]) + 1
+		if n < size {
+			return ranges[i] + rune(n)
+		}
+		n -= size
+	}
+	return ranges[0]
+}
+
+// intersectRanges intersects a class with a single range
+func intersectRanges(ranges, bounds []rune) []rune {
+	var result []rune
+	for i := 0; i+1 < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		if lo < bounds[0] {
+			lo = bounds[0]
+		}
+		if hi > bounds[1] {
+			hi = bounds[1]
+		}
+		if lo <= hi {
+			result = append(result, lo, hi)
+		}
+	}
+	return result
+}
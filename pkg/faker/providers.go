@@ -31,90 +31,96 @@ var textSamples = []string{
 	"To be, or not to be, that is the question.",
 }
 
-func GenerateName() string {
-	firstName := firstNames[rand.IntN(len(firstNames))]
-	lastName := lastNames[rand.IntN(len(lastNames))]
+// referenceTime is the upper bound for generated dates and timestamps.
+var referenceTime = time.Now()
+
+// SetReferenceTime pins the upper bound used by GenerateDate and GenerateDateTime.
+// Seeded runs pin it to a fixed instant so their output does not drift with the clock.
+func SetReferenceTime(t time.Time) {
+	referenceTime = t
+}
+
+// ReferenceTime returns the current upper bound for generated dates and timestamps.
+func ReferenceTime() time.Time {
+	return referenceTime
+}
+
+func GenerateName(r *rand.Rand) string {
+	firstName := firstNames[r.IntN(len(firstNames))]
+	lastName := lastNames[r.IntN(len(lastNames))]
 	return firstName + " " + lastName
 }
 
-func GenerateFirstName() string {
-	return firstNames[rand.IntN(len(firstNames))]
+func GenerateFirstName(r *rand.Rand) string {
+	return firstNames[r.IntN(len(firstNames))]
 }
 
-func GenerateLastName() string {
-	return lastNames[rand.IntN(len(lastNames))]
+func GenerateLastName(r *rand.Rand) string {
+	return lastNames[r.IntN(len(lastNames))]
 }
 
-func GenerateEmail() string {
-	name := GenerateName()
-	domain := domains[rand.IntN(len(domains))]
+func GenerateEmail(r *rand.Rand) string {
+	name := GenerateName(r)
+	domain := domains[r.IntN(len(domains))]
 	return formatEmail(name, domain)
 }
 
-func GeneratePhone() string {
-	return fmt.Sprintf("(%03d) %03d-%04d", rand.IntN(900)+100, rand.IntN(900)+100, rand.IntN(10000))
+func GeneratePhone(r *rand.Rand) string {
+	return fmt.Sprintf("(%03d) %03d-%04d", r.IntN(900)+100, r.IntN(900)+100, r.IntN(10000))
 }
 
-func GenerateCompany() string {
-	return companies[rand.IntN(len(companies))]
+func GenerateCompany(r *rand.Rand) string {
+	return companies[r.IntN(len(companies))]
 }
 
-func GenerateAddress() string {
-	number := rand.IntN(9999) + 1
-	street := streetNames[rand.IntN(len(streetNames))]
+func GenerateAddress(r *rand.Rand) string {
+	number := r.IntN(9999) + 1
+	street := streetNames[r.IntN(len(streetNames))]
 	return fmt.Sprintf("%d %s", number, street)
 }
 
-func GenerateCity() string {
-	return cities[rand.IntN(len(cities))]
+func GenerateCity(r *rand.Rand) string {
+	return cities[r.IntN(len(cities))]
 }
 
-func GenerateState() string {
-	return states[rand.IntN(len(states))]
+func GenerateState(r *rand.Rand) string {
+	return states[r.IntN(len(states))]
 }
 
-func GenerateZipCode() string {
-	return fmt.Sprintf("%05d", rand.IntN(100000))
+func GenerateZipCode(r *rand.Rand) string {
+	return fmt.Sprintf("%05d", r.IntN(100000))
 }
 
-func GenerateDate() string {
-	start := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
-	end := time.Now()
-	delta := end.Unix() - start.Unix()
-	randomTime := start.Unix() + rand.Int64N(delta)
-	return time.Unix(randomTime, 0).Format("2006-01-02")
+func GenerateDate(r *rand.Rand) string {
+	return GenerateTimeBetween(r, DefaultDateStart, referenceTime).UTC().Format(DateLayout)
 }
 
-func GenerateDateTime() string {
-	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
-	end := time.Now()
-	delta := end.Unix() - start.Unix()
-	randomTime := start.Unix() + rand.Int64N(delta)
-	return time.Unix(randomTime, 0).Format("2006-01-02 15:04:05")
+func GenerateDateTime(r *rand.Rand) string {
+	return GenerateTimeBetween(r, DefaultDateTimeStart, referenceTime).UTC().Format(DateTimeLayout)
 }
 
-func GenerateBool() string {
-	if rand.IntN(2) == 0 {
+func GenerateBool(r *rand.Rand) string {
+	if r.IntN(2) == 0 {
 		return "false"
 	}
 	return "true"
 }
 
-func GenerateFloat() string {
-	return fmt.Sprintf("%.2f", rand.Float64()*1000)
+func GenerateFloat(r *rand.Rand) string {
+	return fmt.Sprintf("%.2f", r.Float64()*1000)
 }
 
-func GeneratePrice() string {
-	return fmt.Sprintf("%.2f", rand.Float64()*500+10)
+func GeneratePrice(r *rand.Rand) string {
+	return fmt.Sprintf("%.2f", r.Float64()*500+10)
 }
 
-func GenerateUUID() string {
+func GenerateUUID(r *rand.Rand) string {
 	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x",
-		rand.Uint32(),
-		rand.Uint32()&0xffff,
-		rand.Uint32()&0xffff,
-		rand.Uint32()&0xffff,
-		rand.Uint64()&0xffffffffffff)
+		r.Uint32(),
+		r.Uint32()&0xffff,
+		r.Uint32()&0xffff,
+		r.Uint32()&0xffff,
+		r.Uint64()&0xffffffffffff)
 }
 
 func formatEmail(name, domain string) string {
@@ -123,106 +129,106 @@ func formatEmail(name, domain string) string {
 
 // New faker functions for intelligent field detection
 
-func GenerateCountry() string {
-	return countries[rand.IntN(len(countries))]
+func GenerateCountry(r *rand.Rand) string {
+	return countries[r.IntN(len(countries))]
 }
 
-func GenerateText() string {
-	return textSamples[rand.IntN(len(textSamples))]
+func GenerateText(r *rand.Rand) string {
+	return textSamples[r.IntN(len(textSamples))]
 }
 
-func GenerateURL() string {
+func GenerateURL(r *rand.Rand) string {
 	domains := []string{"https://example.com", "https://test.org", "https://demo.net", "https://sample.io"}
 	paths := []string{"/home", "/about", "/products", "/services", "/contact", "/blog", "/support"}
-	domain := domains[rand.IntN(len(domains))]
-	path := paths[rand.IntN(len(paths))]
+	domain := domains[r.IntN(len(domains))]
+	path := paths[r.IntN(len(paths))]
 	return domain + path
 }
 
-func GenerateImageURL() string {
+func GenerateImageURL(r *rand.Rand) string {
 	sizes := []string{"300x200", "400x300", "500x400", "600x400", "800x600"}
 	categories := []string{"nature", "city", "people", "technology", "abstract"}
-	size := sizes[rand.IntN(len(sizes))]
-	category := categories[rand.IntN(len(categories))]
+	size := sizes[r.IntN(len(sizes))]
+	category := categories[r.IntN(len(categories))]
 	return fmt.Sprintf("https://picsum.photos/%s?category=%s", size, category)
 }
 
-func GenerateGender() string {
-	return genders[rand.IntN(len(genders))]
+func GenerateGender(r *rand.Rand) string {
+	return genders[r.IntN(len(genders))]
 }
 
-func GenerateCategory() string {
-	return categories[rand.IntN(len(categories))]
+func GenerateCategory(r *rand.Rand) string {
+	return categories[r.IntN(len(categories))]
 }
 
-func GenerateJobTitle() string {
-	return jobTitles[rand.IntN(len(jobTitles))]
+func GenerateJobTitle(r *rand.Rand) string {
+	return jobTitles[r.IntN(len(jobTitles))]
 }
 
-func GenerateDepartment() string {
-	return departments[rand.IntN(len(departments))]
+func GenerateDepartment(r *rand.Rand) string {
+	return departments[r.IntN(len(departments))]
 }
 
-func GenerateSkill() string {
-	return skills[rand.IntN(len(skills))]
+func GenerateSkill(r *rand.Rand) string {
+	return skills[r.IntN(len(skills))]
 }
 
-func GenerateColor() string {
-	return colors[rand.IntN(len(colors))]
+func GenerateColor(r *rand.Rand) string {
+	return colors[r.IntN(len(colors))]
 }
 
-func GenerateProductName() string {
-	return productNames[rand.IntN(len(productNames))]
+func GenerateProductName(r *rand.Rand) string {
+	return productNames[r.IntN(len(productNames))]
 }
 
-func GenerateBrandName() string {
-	return brandNames[rand.IntN(len(brandNames))]
+func GenerateBrandName(r *rand.Rand) string {
+	return brandNames[r.IntN(len(brandNames))]
 }
 
 // Username generation
 var usernameWords = []string{"cool", "fast", "smart", "super", "mega", "ultra", "pro", "elite", "master", "legend"}
 var usernameEndings = []string{"123", "456", "789", "2024", "x", "pro", "dev", "code", "tech"}
 
-func GenerateUsername() string {
-	word := usernameWords[rand.IntN(len(usernameWords))]
-	name := strings.ToLower(firstNames[rand.IntN(len(firstNames))])
-	ending := usernameEndings[rand.IntN(len(usernameEndings))]
+func GenerateUsername(r *rand.Rand) string {
+	word := usernameWords[r.IntN(len(usernameWords))]
+	name := strings.ToLower(firstNames[r.IntN(len(firstNames))])
+	ending := usernameEndings[r.IntN(len(usernameEndings))]
 	return word + name + ending
 }
 
 // Password generation
-func GeneratePassword() string {
+func GeneratePassword(r *rand.Rand) string {
 	chars := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*"
-	length := 8 + rand.IntN(8) // 8-15 characters
+	length := 8 + r.IntN(8) // 8-15 characters
 	password := make([]byte, length)
 	for i := range password {
-		password[i] = chars[rand.IntN(len(chars))]
+		password[i] = chars[r.IntN(len(chars))]
 	}
 	return string(password)
 }
 
 // IP Address generation
-func GenerateIPAddress() string {
+func GenerateIPAddress(r *rand.Rand) string {
 	return fmt.Sprintf("%d.%d.%d.%d", 
-		rand.IntN(256), rand.IntN(256), rand.IntN(256), rand.IntN(256))
+		r.IntN(256), r.IntN(256), r.IntN(256), r.IntN(256))
 }
 
 // MAC Address generation
-func GenerateMACAddress() string {
+func GenerateMACAddress(r *rand.Rand) string {
 	mac := make([]string, 6)
 	for i := range mac {
-		mac[i] = fmt.Sprintf("%02x", rand.IntN(256))
+		mac[i] = fmt.Sprintf("%02x", r.IntN(256))
 	}
 	return strings.Join(mac, ":")
 }
 
 // Credit Card generation (fake numbers)
-func GenerateCreditCard() string {
+func GenerateCreditCard(r *rand.Rand) string {
 	// Generate a fake 16-digit credit card number
 	digits := make([]string, 16)
 	digits[0] = "4" // Visa starts with 4
 	for i := 1; i < 16; i++ {
-		digits[i] = fmt.Sprintf("%d", rand.IntN(10))
+		digits[i] = fmt.Sprintf("%d", r.IntN(10))
 	}
 	return strings.Join([]string{
 		strings.Join(digits[0:4], ""),
@@ -233,55 +239,55 @@ func GenerateCreditCard() string {
 }
 
 // Bank Account generation
-func GenerateBankAccount() string {
-	return fmt.Sprintf("%09d", rand.IntN(1000000000))
+func GenerateBankAccount(r *rand.Rand) string {
+	return fmt.Sprintf("%09d", r.IntN(1000000000))
 }
 
 // SSN generation (fake format)
-func GenerateSSN() string {
+func GenerateSSN(r *rand.Rand) string {
 	return fmt.Sprintf("%03d-%02d-%04d", 
-		rand.IntN(900)+100, rand.IntN(100), rand.IntN(10000))
+		r.IntN(900)+100, r.IntN(100), r.IntN(10000))
 }
 
 // License number generation
-func GenerateLicense() string {
+func GenerateLicense(r *rand.Rand) string {
 	letters := "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
 	license := ""
 	for i := 0; i < 2; i++ {
-		license += string(letters[rand.IntN(len(letters))])
+		license += string(letters[r.IntN(len(letters))])
 	}
-	license += fmt.Sprintf("%06d", rand.IntN(1000000))
+	license += fmt.Sprintf("%06d", r.IntN(1000000))
 	return license
 }
 
 // Version generation
 var versionFormats = []string{"%d.%d.%d", "%d.%d", "%d.%d.%d-beta", "%d.%d.%d-alpha"}
 
-func GenerateVersion() string {
-	format := versionFormats[rand.IntN(len(versionFormats))]
-	major := rand.IntN(10) + 1
-	minor := rand.IntN(20)
-	patch := rand.IntN(50)
+func GenerateVersion(r *rand.Rand) string {
+	format := versionFormats[r.IntN(len(versionFormats))]
+	major := r.IntN(10) + 1
+	minor := r.IntN(20)
+	patch := r.IntN(50)
 	return fmt.Sprintf(format, major, minor, patch)
 }
 
 // Status generation
 var statuses = []string{"Active", "Inactive", "Pending", "Approved", "Rejected", "In Progress", "Completed", "Cancelled", "On Hold", "Review"}
 
-func GenerateStatus() string {
-	return statuses[rand.IntN(len(statuses))]
+func GenerateStatus(r *rand.Rand) string {
+	return statuses[r.IntN(len(statuses))]
 }
 
 // Priority generation
 var priorities = []string{"Low", "Medium", "High", "Critical", "Urgent", "Normal", "Minor", "Major"}
 
-func GeneratePriority() string {
-	return priorities[rand.IntN(len(priorities))]
+func GeneratePriority(r *rand.Rand) string {
+	return priorities[r.IntN(len(priorities))]
 }
 
 // Duration generation (in hours)
-func GenerateDuration() string {
-	hours := rand.IntN(168) + 1 // 1-168 hours (1 week)
+func GenerateDuration(r *rand.Rand) string {
+	hours := r.IntN(168) + 1 // 1-168 hours (1 week)
 	if hours < 24 {
 		return fmt.Sprintf("%d hours", hours)
 	}
@@ -297,43 +303,83 @@ func GenerateDuration() string {
 var fileExtensions = []string{".txt", ".pdf", ".doc", ".docx", ".jpg", ".png", ".mp4", ".mp3", ".zip", ".csv"}
 var fileWords = []string{"document", "report", "image", "photo", "video", "data", "backup", "file", "archive", "presentation"}
 
-func GenerateFilename() string {
-	word := fileWords[rand.IntN(len(fileWords))]
-	number := rand.IntN(999) + 1
-	ext := fileExtensions[rand.IntN(len(fileExtensions))]
+func GenerateFilename(r *rand.Rand) string {
+	word := fileWords[r.IntN(len(fileWords))]
+	number := r.IntN(999) + 1
+	ext := fileExtensions[r.IntN(len(fileExtensions))]
 	return fmt.Sprintf("%s_%03d%s", word, number, ext)
 }
 
 // Hashtag generation
 var hashtagWords = []string{"tech", "design", "coding", "startup", "innovation", "digital", "future", "ai", "data", "cloud"}
 
-func GenerateHashtag() string {
-	word1 := hashtagWords[rand.IntN(len(hashtagWords))]
-	word2 := hashtagWords[rand.IntN(len(hashtagWords))]
+func GenerateHashtag(r *rand.Rand) string {
+	word1 := hashtagWords[r.IntN(len(hashtagWords))]
+	word2 := hashtagWords[r.IntN(len(hashtagWords))]
 	return fmt.Sprintf("#%s%s", word1, word2)
 }
 
 // Longitude generation (-180 to 180)
-func GenerateLongitude() float64 {
-	return (rand.Float64() * 360) - 180
+func GenerateLongitude(r *rand.Rand) float64 {
+	return (r.Float64() * 360) - 180
 }
 
 // Latitude generation (-90 to 90)
-func GenerateLatitude() float64 {
-	return (rand.Float64() * 180) - 90
+func GenerateLatitude(r *rand.Rand) float64 {
+	return (r.Float64() * 180) - 90
 }
 
 // Temperature generation (in Celsius, -50 to 50)
-func GenerateTemperature() float64 {
-	return (rand.Float64() * 100) - 50
+func GenerateTemperature(r *rand.Rand) float64 {
+	return (r.Float64() * 100) - 50
 }
 
 // Weight generation (in kg, 0.1 to 200)
-func GenerateWeight() float64 {
-	return rand.Float64()*199.9 + 0.1
+func GenerateWeight(r *rand.Rand) float64 {
+	return r.Float64()*199.9 + 0.1
 }
 
 // Height generation (in cm, 50 to 250)
-func GenerateHeight() float64 {
-	return rand.Float64()*200 + 50
-}
\ No newline at end of file
+func GenerateHeight(r *rand.Rand) float64 {
+	return r.Float64()*200 + 50
+}
+
+// Time of day generation (HH:MM:SS)
+func GenerateTime(r *rand.Rand) string {
+	return fmt.Sprintf("%02d:%02d:%02d", r.IntN(24), r.IntN(60), r.IntN(60))
+}
+
+// Point generation, as Postgres writes points: (x,y)
+func GeneratePoint(r *rand.Rand) string {
+	return fmt.Sprintf("(%.6f,%.6f)", GenerateLongitude(r), GenerateLatitude(r))
+}
+
+// Binary generation: 4 to 16 random bytes written as hex
+func GenerateBinary(r *rand.Rand) string {
+	data := make([]byte, 4+r.IntN(13))
+	for i := range data {
+		data[i] = byte(r.IntN(256))
+	}
+	return fmt.Sprintf("%x", data)
+}
+
+// JSON document generation: a small object with an id, a name and a status
+func GenerateJSON(r *rand.Rand) string {
+	return fmt.Sprintf(`{"id": %d, "name": %q, "status": %q}`,
+		r.IntN(1000)+1, GenerateName(r), GenerateStatus(r))
+}
+
+// FormatArray writes values as a Postgres array literal, e.g. {1,2} or
+// {"New York",Chicago}. Elements that are empty or hold spaces, quotes,
+// commas or braces are quoted.
+func FormatArray(values []interface{}) string {
+	elements := make([]string, len(values))
+	for i, value := range values {
+		element := fmt.Sprint(value)
+		if element == "" || strings.ContainsAny(element, " \t,{}\"\\") || strings.EqualFold(element, "NULL") {
+			element = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(element) + `"`
+		}
+		elements[i] = element
+	}
+	return "{" + strings.Join(elements, ",") + "}"
+}
@@ -11,6 +11,7 @@ type Level int
 
 const (
 	LevelError Level = iota
+	LevelWarn
 	LevelInfo
 	LevelDebug
 )
@@ -48,6 +49,8 @@ func (l *Logger) log(level Level, format string, args ...interface{}) {
 	switch level {
 	case LevelError:
 		levelStr = "ERROR"
+	case LevelWarn:
+		levelStr = "WARN"
 	case LevelInfo:
 		levelStr = "INFO"
 	case LevelDebug:
@@ -65,6 +68,12 @@ func Error(format string, args ...interface{}) {
 	}
 }
 
+func Warn(format string, args ...interface{}) {
+	if globalLogger != nil {
+		globalLogger.log(LevelWarn, format, args...)
+	}
+}
+
 func Info(format string, args ...interface{}) {
 	if globalLogger != nil {
 		globalLogger.log(LevelInfo, format, args...)